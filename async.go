@@ -0,0 +1,159 @@
+package twoface
+
+import (
+	"context"
+	"fmt"
+	"runtime/debug"
+)
+
+/*
+PanicError is the error a Future fails with when the function that produces its value panics.
+
+Example:
+
+	_, err := Async(ctx, func(ctx context.Context) (int, error) { panic("boom") }).Result()
+
+	var panicErr *PanicError
+	fmt.Println(errors.As(err, &panicErr)) // true
+*/
+type PanicError struct {
+	Value any
+	Stack []byte
+}
+
+/*
+Error returns the value that was passed to panic.
+*/
+func (err *PanicError) Error() string {
+	return fmt.Sprintf("panic: %v", err.Value)
+}
+
+/*
+Unwrap returns the value that was passed to panic, if that value is an error.
+*/
+func (err *PanicError) Unwrap() error {
+	if wrapped, ok := err.Value.(error); ok {
+		return wrapped
+	}
+	return nil
+}
+
+/*
+Async runs the function on a new goroutine, and returns a Future for its outcome.
+
+Example:
+
+	f := Async(ctx, func(ctx context.Context) (int, error) {
+	    return 42, nil
+	})
+	result, err := f.Result()
+*/
+func Async[T any](ctx context.Context, fn func(context.Context) (T, error)) *Future[T] {
+	return AsyncOn(ctx, GoExecutor, fn)
+}
+
+/*
+AsyncOn runs the function on the given Executor, and returns a Future for its outcome.
+When the Executor is a Pool, the function runs on one of its workers, and the Future
+fails if the pool is shut down before that happens. The function is not called at all
+if the context is already done by the time it would start, and the context it receives
+is cancelled when the Future is.
+
+Example:
+
+	pool := NewPool(ctx, 4)
+	f := AsyncOn(ctx, pool, func(ctx context.Context) (int, error) {
+	    return 42, nil
+	})
+*/
+func AsyncOn[T any](ctx context.Context, executor Executor, fn func(context.Context) (T, error)) *Future[T] {
+	promise, future := NewPromiseWithContext[T](ctx)
+	job := asyncJob[T]{ctx: promise.Context(), fn: fn, promise: promise}
+
+	if pool, ok := executor.(submitter); ok {
+		pool.Submit(job)
+		return future
+	}
+
+	executor.Execute(func() { job.Do() })
+	return future
+}
+
+/*
+Lazy returns a Future for the outcome of the function, which only starts running on
+a new goroutine once the Future is first waited on, through Result, Await or Done,
+or through a Future that was derived from it.
+
+Example:
+
+	f := Lazy(ctx, func(ctx context.Context) (int, error) {
+	    return expensiveComputation(ctx)
+	})
+	result, err := f.Result() // the computation starts here
+*/
+func Lazy[T any](ctx context.Context, fn func(context.Context) (T, error)) *Future[T] {
+	promise, future := NewPromiseWithContext[T](ctx)
+
+	future.start = func() {
+		go func() { promise.Set(call(promise.Context(), fn)) }()
+	}
+
+	return future
+}
+
+/*
+submitter is implemented by executors that accept a Job directly, like Pool.
+*/
+type submitter interface {
+	Submit(Job)
+}
+
+/*
+asyncJob is the Job that runs the function of AsyncOn and completes its Future.
+*/
+type asyncJob[T any] struct {
+	ctx     context.Context
+	fn      func(context.Context) (T, error)
+	promise *Promise[T]
+}
+
+func (job asyncJob[T]) Do() Result[any, error] {
+	result, err := call(job.ctx, job.fn)
+	job.promise.Set(result, err)
+
+	if err != nil {
+		return Err[any](err)
+	}
+	return Ok[any, error](result)
+}
+
+/*
+Cancelled lets the Pool skip the job when its Future was completed, which can only
+happen through Future.Cancel, before a worker picked it up.
+*/
+func (job asyncJob[T]) Cancelled() bool {
+	return job.promise.future.IsDone()
+}
+
+func (job asyncJob[T]) discard(err error) {
+	var zero T
+	job.promise.Set(zero, err)
+}
+
+/*
+call runs the function unless the context is already done, turning a panic into a PanicError.
+*/
+func call[T any](ctx context.Context, fn func(context.Context) (T, error)) (result T, err error) {
+	if err := ctx.Err(); err != nil {
+		return result, err
+	}
+
+	defer func() {
+		if value := recover(); value != nil {
+			var zero T
+			result, err = zero, &PanicError{Value: value, Stack: debug.Stack()}
+		}
+	}()
+
+	return fn(ctx)
+}
@@ -0,0 +1,163 @@
+package twoface
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/smartystreets/goconvey/convey"
+)
+
+func TestAsync(t *testing.T) {
+	convey.Convey("Async", t, func() {
+		ctx := context.Background()
+
+		convey.Convey("Should resolve with the value of the function", func() {
+			result, err := Async(ctx, func(ctx context.Context) (int, error) { return 42, nil }).Result()
+			convey.So(result, convey.ShouldEqual, 42)
+			convey.So(err, convey.ShouldBeNil)
+		})
+
+		convey.Convey("Should resolve with the error of the function", func() {
+			_, err := Async(ctx, func(ctx context.Context) (int, error) { return 0, errDummy }).Result()
+			convey.So(err, convey.ShouldEqual, errDummy)
+		})
+
+		convey.Convey("Should recover a panic into a PanicError", func() {
+			_, err := Async(ctx, func(ctx context.Context) (int, error) { panic(errDummy) }).Result()
+
+			var panicErr *PanicError
+			convey.So(errors.As(err, &panicErr), convey.ShouldBeTrue)
+			convey.So(panicErr.Stack, convey.ShouldNotBeEmpty)
+			convey.So(errors.Is(err, errDummy), convey.ShouldBeTrue)
+		})
+
+		convey.Convey("Should not call the function when the context is done", func() {
+			ctx, cancel := context.WithCancel(ctx)
+			cancel()
+			called := false
+			_, err := Async(ctx, func(ctx context.Context) (int, error) {
+				called = true
+				return 42, nil
+			}).Result()
+			convey.So(err, convey.ShouldEqual, context.Canceled)
+			convey.So(called, convey.ShouldBeFalse)
+		})
+
+		convey.Convey("Should run on the workers of a Pool", func() {
+			pool := NewPool(ctx, 2)
+			var count atomic.Int64
+
+			futures := make([]*Future[int64], 10)
+			for i := range futures {
+				futures[i] = AsyncOn(ctx, pool, func(ctx context.Context) (int64, error) {
+					return count.Add(1), nil
+				})
+			}
+
+			values, err := All(ctx, futures...).Result()
+			convey.So(err, convey.ShouldBeNil)
+			convey.So(values, convey.ShouldHaveLength, 10)
+
+			pool.Shutdown()
+			convey.So(count.Load(), convey.ShouldEqual, 10)
+		})
+
+		convey.Convey("Should fail when the Pool is shut down", func() {
+			pool := NewPool(ctx, 1)
+			pool.Shutdown()
+			_, err := AsyncOn(ctx, pool, func(ctx context.Context) (int, error) { return 42, nil }).Result()
+			convey.So(err, convey.ShouldEqual, context.Canceled)
+		})
+
+		convey.Convey("Should cancel the context of the function", func() {
+			started := make(chan struct{})
+			f := Async(ctx, func(ctx context.Context) (int, error) {
+				close(started)
+				<-ctx.Done()
+				return 0, ctx.Err()
+			})
+			<-started
+			f.Cancel()
+			_, err := f.Result()
+			convey.So(err, convey.ShouldEqual, context.Canceled)
+		})
+
+		convey.Convey("Should skip queued jobs whose Future was cancelled", func() {
+			pool := NewPool(ctx, 1)
+			release := make(chan struct{})
+			blocker := AsyncOn(ctx, pool, func(ctx context.Context) (int, error) {
+				<-release
+				return 0, nil
+			})
+
+			called := false
+			skipped := AsyncOn(ctx, pool, func(ctx context.Context) (int, error) {
+				called = true
+				return 42, nil
+			})
+			skipped.Cancel()
+			close(release)
+
+			_, err := blocker.Result()
+			convey.So(err, convey.ShouldBeNil)
+			pool.Shutdown()
+
+			_, err = skipped.Result()
+			convey.So(err, convey.ShouldEqual, context.Canceled)
+			convey.So(called, convey.ShouldBeFalse)
+		})
+
+		convey.Convey("Should only start a Lazy Future once it is waited on", func() {
+			var calls atomic.Int64
+			f := Lazy(ctx, func(ctx context.Context) (int, error) {
+				calls.Add(1)
+				return 42, nil
+			})
+
+			time.Sleep(10 * time.Millisecond)
+			convey.So(calls.Load(), convey.ShouldEqual, 0)
+			convey.So(f.IsDone(), convey.ShouldBeFalse)
+
+			result, err := f.Result()
+			convey.So(result, convey.ShouldEqual, 42)
+			convey.So(err, convey.ShouldBeNil)
+
+			f.Result()
+			convey.So(calls.Load(), convey.ShouldEqual, 1)
+		})
+
+		convey.Convey("Should start a Lazy Future through derived futures and combinators", func() {
+			lazy := func(value int) *Future[int] {
+				return Lazy(ctx, func(ctx context.Context) (int, error) { return value, nil })
+			}
+
+			result, err := ThenApply(lazy(21), func(v int) (int, error) { return v * 2, nil }).Result()
+			convey.So(result, convey.ShouldEqual, 42)
+			convey.So(err, convey.ShouldBeNil)
+
+			values, err := All(ctx, lazy(1), lazy(2)).Result()
+			convey.So(values, convey.ShouldResemble, []int{1, 2})
+			convey.So(err, convey.ShouldBeNil)
+		})
+
+		convey.Convey("Should run Future callbacks on a Pool", func() {
+			pool := NewPool(ctx, 1)
+			p, f := NewPromise[int]()
+			ch := make(chan int)
+			f.WithExecutor(pool).Then(func(result int) { ch <- result })
+			p.Set(42, nil)
+			convey.So(<-ch, convey.ShouldEqual, 42)
+			pool.Shutdown()
+		})
+	})
+}
+
+func BenchmarkAsync(b *testing.B) {
+	ctx := context.Background()
+	for i := 0; i < b.N; i++ {
+		Async(ctx, func(ctx context.Context) (int, error) { return 42, nil }).Result()
+	}
+}
@@ -0,0 +1,321 @@
+package twoface
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+/*
+CacheConfig configures a Cache. The zero value is a valid configuration, which keeps
+values forever, does not cache errors, and does not refresh ahead.
+*/
+type CacheConfig struct {
+	// TTL is how long a loaded value is kept. Zero means forever.
+	TTL time.Duration
+	// NegativeTTL is how long a failed load is kept. Zero means failures are not cached.
+	NegativeTTL time.Duration
+	// RefreshAhead is how long before a value expires it is reloaded in the background,
+	// while the stale value is still served. Zero means values are only loaded on a miss.
+	RefreshAhead time.Duration
+	// MaxSize limits the number of keys, evicting the least recently used. Zero means unbounded.
+	MaxSize int
+
+	clock Clock
+}
+
+/*
+CacheStats is a snapshot of the counters of a Cache.
+*/
+type CacheStats struct {
+	Hits       int64
+	Misses     int64
+	Loads      int64
+	LoadErrors int64
+	Refreshes  int64
+	Evictions  int64
+}
+
+/*
+HitRate returns the fraction of requests that were served from the cache.
+
+Example:
+
+	fmt.Printf("%.2f\n", cache.Stats().HitRate())
+*/
+func (stats CacheStats) HitRate() float64 {
+	total := stats.Hits + stats.Misses
+	if total == 0 {
+		return 0
+	}
+	return float64(stats.Hits) / float64(total)
+}
+
+/*
+Cache is an asynchronous loading cache. Values are loaded by the loader function on the
+given Executor, typically a Pool, and callers receive a Future for them. Concurrent
+callers for a key that is still loading share the same Future.
+
+Example:
+
+	cache := NewCache(ctx, pool, fetchUser, CacheConfig{
+	    TTL:          time.Minute,
+	    RefreshAhead: 10 * time.Second,
+	    MaxSize:      10000,
+	})
+	user, err := cache.Get("alice").Result()
+*/
+type Cache[K comparable, V any] struct {
+	mu       sync.Mutex
+	ctx      context.Context
+	executor Executor
+	loader   func(context.Context, K) (V, error)
+	config   CacheConfig
+	entries  map[K]*list.Element
+	order    *list.List
+
+	hits       atomic.Int64
+	misses     atomic.Int64
+	loads      atomic.Int64
+	loadErrors atomic.Int64
+	refreshes  atomic.Int64
+	evictions  atomic.Int64
+}
+
+type cacheEntry[K comparable, V any] struct {
+	key        K
+	future     *Future[V]
+	expires    time.Time
+	refreshing bool
+}
+
+/*
+NewCache creates an empty Cache that loads values with the loader function, on the
+given Executor. The Executor has to run loads asynchronously, like a Pool or GoExecutor
+do, because they are started while the cache is locked.
+
+Example:
+
+	cache := NewCache(ctx, pool, func(ctx context.Context, id string) (User, error) {
+	    return fetchUser(ctx, id)
+	}, CacheConfig{TTL: time.Minute})
+*/
+func NewCache[K comparable, V any](
+	ctx context.Context,
+	executor Executor,
+	loader func(context.Context, K) (V, error),
+	config CacheConfig,
+) *Cache[K, V] {
+	if config.clock == nil {
+		config.clock = SystemClock
+	}
+
+	return &Cache[K, V]{
+		ctx:      ctx,
+		executor: executor,
+		loader:   loader,
+		config:   config,
+		entries:  make(map[K]*list.Element),
+		order:    list.New(),
+	}
+}
+
+/*
+Get returns a Future for the value of the key. A fresh or still loading value is a hit.
+A missing, expired or failed value is a miss, and starts a new load. A value that is
+about to expire is still a hit, but also starts a reload in the background.
+
+Example:
+
+	user, err := cache.Get("alice").Result()
+*/
+func (cache *Cache[K, V]) Get(key K) *Future[V] {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+
+	if element, ok := cache.entries[key]; ok {
+		entry := element.Value.(*cacheEntry[K, V])
+
+		if cache.valid(entry) {
+			cache.hits.Add(1)
+			cache.order.MoveToFront(element)
+			cache.refreshAhead(entry)
+			return entry.future
+		}
+
+		cache.remove(element)
+	}
+
+	cache.misses.Add(1)
+
+	entry := &cacheEntry[K, V]{key: key}
+	cache.entries[key] = cache.order.PushFront(entry)
+	entry.future = cache.load(entry, false)
+
+	for cache.config.MaxSize > 0 && cache.order.Len() > cache.config.MaxSize {
+		cache.remove(cache.order.Back())
+		cache.evictions.Add(1)
+	}
+
+	return entry.future
+}
+
+/*
+Invalidate removes the key, so the next call to Get loads it again. Callers that
+already hold a Future for the old value are not affected.
+
+Example:
+
+	cache.Invalidate("alice")
+*/
+func (cache *Cache[K, V]) Invalidate(key K) {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+
+	if element, ok := cache.entries[key]; ok {
+		cache.remove(element)
+	}
+}
+
+/*
+Len returns the number of keys in the cache, including ones that expired but were not
+requested since.
+
+Example:
+
+	fmt.Println(cache.Len())
+*/
+func (cache *Cache[K, V]) Len() int {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+	return cache.order.Len()
+}
+
+/*
+Stats returns a snapshot of the counters of the cache.
+
+Example:
+
+	stats := cache.Stats()
+	fmt.Println(stats.Hits, stats.Misses)
+*/
+func (cache *Cache[K, V]) Stats() CacheStats {
+	return CacheStats{
+		Hits:       cache.hits.Load(),
+		Misses:     cache.misses.Load(),
+		Loads:      cache.loads.Load(),
+		LoadErrors: cache.loadErrors.Load(),
+		Refreshes:  cache.refreshes.Load(),
+		Evictions:  cache.evictions.Load(),
+	}
+}
+
+/*
+load starts loading the value of an entry. The bookkeeping is done before the Future
+completes, so anyone who sees the Future completed also sees its expiry. A refresh only
+replaces the Future of the entry when it succeeds, so a failed refresh keeps serving the
+stale value until it expires.
+*/
+func (cache *Cache[K, V]) load(entry *cacheEntry[K, V], refresh bool) *Future[V] {
+	cache.loads.Add(1)
+
+	var future *Future[V]
+
+	future = AsyncOn(cache.ctx, cache.executor, func(ctx context.Context) (V, error) {
+		value, err := call(ctx, func(ctx context.Context) (V, error) {
+			return cache.loader(ctx, entry.key)
+		})
+
+		cache.mu.Lock()
+		defer cache.mu.Unlock()
+
+		if err != nil {
+			cache.loadErrors.Add(1)
+		}
+
+		if refresh {
+			entry.refreshing = false
+
+			if err == nil && cache.contains(entry) {
+				entry.future = future
+				entry.expires = cache.expiry(cache.config.TTL)
+			}
+
+			return value, err
+		}
+
+		switch {
+		case err == nil:
+			entry.expires = cache.expiry(cache.config.TTL)
+		case !errors.Is(err, context.Canceled):
+			entry.expires = cache.expiry(cache.config.NegativeTTL)
+		}
+
+		return value, err
+	})
+
+	return future
+}
+
+/*
+refreshAhead starts a background reload for a loaded value that is about to expire.
+*/
+func (cache *Cache[K, V]) refreshAhead(entry *cacheEntry[K, V]) {
+	if cache.config.RefreshAhead <= 0 || entry.refreshing || entry.expires.IsZero() {
+		return
+	}
+
+	if !entry.future.IsDone() || entry.future.err != nil {
+		return
+	}
+
+	if cache.config.clock.Now().Before(entry.expires.Add(-cache.config.RefreshAhead)) {
+		return
+	}
+
+	entry.refreshing = true
+	cache.refreshes.Add(1)
+	cache.load(entry, true)
+}
+
+/*
+valid returns true if the entry can still be served. Failures are only served while
+they are negatively cached.
+*/
+func (cache *Cache[K, V]) valid(entry *cacheEntry[K, V]) bool {
+	if !entry.future.IsDone() {
+		return true
+	}
+
+	now := cache.config.clock.Now()
+
+	if entry.future.err != nil {
+		return cache.config.NegativeTTL > 0 && !entry.expires.IsZero() && now.Before(entry.expires)
+	}
+
+	return entry.expires.IsZero() || now.Before(entry.expires)
+}
+
+/*
+expiry returns the moment something with the given TTL expires, where the zero time
+means it never does.
+*/
+func (cache *Cache[K, V]) expiry(ttl time.Duration) time.Time {
+	if ttl <= 0 {
+		return time.Time{}
+	}
+	return cache.config.clock.Now().Add(ttl)
+}
+
+func (cache *Cache[K, V]) contains(entry *cacheEntry[K, V]) bool {
+	element, ok := cache.entries[entry.key]
+	return ok && element.Value == entry
+}
+
+func (cache *Cache[K, V]) remove(element *list.Element) {
+	delete(cache.entries, element.Value.(*cacheEntry[K, V]).key)
+	cache.order.Remove(element)
+}
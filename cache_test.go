@@ -0,0 +1,148 @@
+package twoface
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/smartystreets/goconvey/convey"
+)
+
+func TestCache(t *testing.T) {
+	convey.Convey("Cache", t, func() {
+		ctx := context.Background()
+		pool := NewPool(ctx, 2)
+		clock := &testClock{now: time.Now()}
+
+		var loads atomic.Int64
+		var failing atomic.Bool
+		loader := func(ctx context.Context, key string) (int64, error) {
+			if failing.Load() {
+				return 0, errDummy
+			}
+			return loads.Add(1), nil
+		}
+
+		newCache := func(config CacheConfig) *Cache[string, int64] {
+			config.clock = clock
+			return NewCache(ctx, pool, loader, config)
+		}
+
+		convey.Convey("Should load a value once and serve it from the cache", func() {
+			cache := newCache(CacheConfig{})
+			first, err := cache.Get("a").Result()
+			convey.So(err, convey.ShouldBeNil)
+			second, _ := cache.Get("a").Result()
+			convey.So(second, convey.ShouldEqual, first)
+
+			stats := cache.Stats()
+			convey.So(stats.Hits, convey.ShouldEqual, 1)
+			convey.So(stats.Misses, convey.ShouldEqual, 1)
+			convey.So(stats.Loads, convey.ShouldEqual, 1)
+			convey.So(stats.HitRate(), convey.ShouldEqual, 0.5)
+		})
+
+		convey.Convey("Should reload a value once it expired", func() {
+			cache := newCache(CacheConfig{TTL: time.Minute})
+			first, _ := cache.Get("a").Result()
+			clock.Advance(2 * time.Minute)
+			second, _ := cache.Get("a").Result()
+			convey.So(second, convey.ShouldNotEqual, first)
+			convey.So(cache.Stats().Misses, convey.ShouldEqual, 2)
+		})
+
+		convey.Convey("Should not cache failures without a NegativeTTL", func() {
+			cache := newCache(CacheConfig{})
+			failing.Store(true)
+			_, err := cache.Get("a").Result()
+			convey.So(err, convey.ShouldEqual, errDummy)
+
+			failing.Store(false)
+			_, err = cache.Get("a").Result()
+			convey.So(err, convey.ShouldBeNil)
+			convey.So(cache.Stats().LoadErrors, convey.ShouldEqual, 1)
+		})
+
+		convey.Convey("Should cache failures for the NegativeTTL", func() {
+			cache := newCache(CacheConfig{NegativeTTL: time.Minute})
+			failing.Store(true)
+			cache.Get("a").Result()
+
+			failing.Store(false)
+			_, err := cache.Get("a").Result()
+			convey.So(err, convey.ShouldEqual, errDummy)
+
+			clock.Advance(2 * time.Minute)
+			_, err = cache.Get("a").Result()
+			convey.So(err, convey.ShouldBeNil)
+		})
+
+		convey.Convey("Should refresh ahead while serving the stale value", func() {
+			cache := newCache(CacheConfig{TTL: time.Minute, RefreshAhead: 10 * time.Second})
+			first, _ := cache.Get("a").Result()
+
+			clock.Advance(55 * time.Second)
+			stale, _ := cache.Get("a").Result()
+			convey.So(stale, convey.ShouldEqual, first)
+
+			deadline := time.Now().Add(time.Second)
+			refreshed := first
+			for refreshed == first && time.Now().Before(deadline) {
+				refreshed, _ = cache.Get("a").Result()
+			}
+			convey.So(refreshed, convey.ShouldEqual, first+1)
+
+			stats := cache.Stats()
+			convey.So(stats.Refreshes, convey.ShouldEqual, 1)
+			convey.So(stats.Misses, convey.ShouldEqual, 1)
+
+			clock.Advance(55 * time.Second)
+			again, _ := cache.Get("a").Result()
+			convey.So(again, convey.ShouldEqual, refreshed)
+		})
+
+		convey.Convey("Should keep the stale value when a refresh fails", func() {
+			cache := newCache(CacheConfig{TTL: time.Minute, RefreshAhead: 10 * time.Second})
+			first, _ := cache.Get("a").Result()
+
+			failing.Store(true)
+			clock.Advance(55 * time.Second)
+			cache.Get("a").Result()
+
+			deadline := time.Now().Add(time.Second)
+			for cache.Stats().LoadErrors == 0 && time.Now().Before(deadline) {
+				time.Sleep(time.Millisecond)
+			}
+
+			stale, err := cache.Get("a").Result()
+			convey.So(err, convey.ShouldBeNil)
+			convey.So(stale, convey.ShouldEqual, first)
+		})
+
+		convey.Convey("Should evict the least recently used keys", func() {
+			cache := newCache(CacheConfig{MaxSize: 2})
+			cache.Get("a").Result()
+			cache.Get("b").Result()
+			cache.Get("a").Result()
+			cache.Get("c").Result()
+			convey.So(cache.Len(), convey.ShouldEqual, 2)
+			convey.So(cache.Stats().Evictions, convey.ShouldEqual, 1)
+
+			cache.Get("a").Result()
+			convey.So(cache.Stats().Misses, convey.ShouldEqual, 3)
+		})
+
+		convey.Convey("Should Invalidate a key", func() {
+			cache := newCache(CacheConfig{})
+			first, _ := cache.Get("a").Result()
+			cache.Invalidate("a")
+			second, _ := cache.Get("a").Result()
+			convey.So(second, convey.ShouldNotEqual, first)
+		})
+
+		convey.Reset(func() {
+			pool.Shutdown()
+		})
+	})
+}
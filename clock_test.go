@@ -0,0 +1,106 @@
+package twoface
+
+import (
+	"sync"
+	"time"
+)
+
+// testClock is a Clock that only moves when it is advanced, so tests that depend on time
+// do not have to wait for it.
+type testClock struct {
+	mu     sync.Mutex
+	now    time.Time
+	timers []*testTimer
+}
+
+// testTimer fires once the testClock is advanced past its time.
+type testTimer struct {
+	clock *testClock
+	at    time.Time
+	ch    chan time.Time
+}
+
+func (clock *testClock) Now() time.Time {
+	clock.mu.Lock()
+	defer clock.mu.Unlock()
+	return clock.now
+}
+
+func (clock *testClock) Advance(d time.Duration) {
+	clock.mu.Lock()
+	defer clock.mu.Unlock()
+	clock.now = clock.now.Add(d)
+
+	pending := clock.timers[:0]
+	for _, timer := range clock.timers {
+		if timer.at.After(clock.now) {
+			pending = append(pending, timer)
+			continue
+		}
+		timer.fire()
+	}
+	clock.timers = pending
+}
+
+func (clock *testClock) After(d time.Duration) <-chan time.Time {
+	return clock.NewTimer(d).C()
+}
+
+func (clock *testClock) NewTimer(d time.Duration) Timer {
+	timer := &testTimer{clock: clock, ch: make(chan time.Time, 1)}
+	timer.Reset(d)
+	return timer
+}
+
+// pending returns the number of timers that did not fire and were not stopped.
+func (clock *testClock) pending() int {
+	clock.mu.Lock()
+	defer clock.mu.Unlock()
+	return len(clock.timers)
+}
+
+func (timer *testTimer) C() <-chan time.Time {
+	return timer.ch
+}
+
+func (timer *testTimer) Stop() bool {
+	timer.clock.mu.Lock()
+	defer timer.clock.mu.Unlock()
+	return timer.remove()
+}
+
+func (timer *testTimer) Reset(d time.Duration) bool {
+	clock := timer.clock
+	clock.mu.Lock()
+	defer clock.mu.Unlock()
+
+	active := timer.remove()
+	timer.at = clock.now.Add(d)
+
+	if d <= 0 {
+		timer.fire()
+	} else {
+		clock.timers = append(clock.timers, timer)
+	}
+
+	return active
+}
+
+// remove takes the timer off the clock, and reports whether it was on it.
+func (timer *testTimer) remove() bool {
+	for idx, pending := range timer.clock.timers {
+		if pending == timer {
+			timer.clock.timers = append(timer.clock.timers[:idx], timer.clock.timers[idx+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// fire sends the time like a time.Timer does, so it is dropped when the last one was not received.
+func (timer *testTimer) fire() {
+	select {
+	case timer.ch <- timer.clock.now:
+	default:
+	}
+}
@@ -0,0 +1,207 @@
+package twoface
+
+import (
+	"context"
+	"errors"
+)
+
+/*
+All waits for all futures to complete successfully, and resolves with their values
+in the same order as the inputs. It fails fast, resolving with the first error that
+any of the futures produces, or with the context error if the context is done first.
+
+Example:
+
+	all := All(ctx, fetch("a"), fetch("b"))
+	values, err := all.Result()
+*/
+func All[T any](ctx context.Context, futures ...*Future[T]) *Future[[]T] {
+	promise, future := NewPromiseWithContext[[]T](ctx)
+	future.onCancel(dependOnAll(futures))
+
+	go func() {
+		ctx := promise.Context()
+
+		values := make([]T, len(futures))
+		completed := watch(ctx, futures)
+
+		for range futures {
+			select {
+			case idx := <-completed:
+				if err := futures[idx].err; err != nil {
+					promise.Set(nil, err)
+					return
+				}
+				values[idx] = futures[idx].result
+			case <-ctx.Done():
+				promise.Set(nil, ctx.Err())
+				return
+			}
+		}
+
+		promise.Set(values, nil)
+	}()
+
+	return future
+}
+
+/*
+AllSettled waits for all futures to complete, and resolves with a Result for each of
+them, in the same order as the inputs. It never fails, if the context is done before
+all futures have completed, the ones that are still pending settle with the context error.
+
+Example:
+
+	settled, _ := AllSettled(ctx, fetch("a"), fetch("b")).Result()
+	for _, result := range settled {
+	    fmt.Println(result.IsOk())
+	}
+*/
+func AllSettled[T any](ctx context.Context, futures ...*Future[T]) *Future[[]Result[T, error]] {
+	promise, future := NewPromiseWithContext[[]Result[T, error]](ctx)
+	future.onCancel(dependOnAll(futures))
+
+	go func() {
+		ctx := promise.Context()
+
+		results := make([]Result[T, error], len(futures))
+		completed := watch(ctx, futures)
+
+		for range futures {
+			select {
+			case idx := <-completed:
+				results[idx] = futures[idx].settled()
+			case <-ctx.Done():
+				for idx := range results {
+					if !results[idx].IsOk() && !results[idx].IsErr() {
+						results[idx] = Err[T](ctx.Err())
+					}
+				}
+				promise.Set(results, nil)
+				return
+			}
+		}
+
+		promise.Set(results, nil)
+	}()
+
+	return future
+}
+
+/*
+Any resolves with the value of the first future that completes successfully. If all
+of them fail, it resolves with all of their errors joined together, in the same order
+as the inputs.
+
+Example:
+
+	fastest, err := Any(ctx, fetchFrom("primary"), fetchFrom("replica")).Result()
+*/
+func Any[T any](ctx context.Context, futures ...*Future[T]) *Future[T] {
+	promise, future := NewPromiseWithContext[T](ctx)
+	future.onCancel(dependOnAll(futures))
+
+	if len(futures) == 0 {
+		var zero T
+		promise.Set(zero, errors.New("called `Any` without any futures"))
+		return future
+	}
+
+	go func() {
+		ctx := promise.Context()
+
+		errs := make([]error, len(futures))
+		completed := watch(ctx, futures)
+
+		for range futures {
+			select {
+			case idx := <-completed:
+				if futures[idx].err == nil {
+					promise.Set(futures[idx].result, nil)
+					return
+				}
+				errs[idx] = futures[idx].err
+			case <-ctx.Done():
+				var zero T
+				promise.Set(zero, ctx.Err())
+				return
+			}
+		}
+
+		var zero T
+		promise.Set(zero, errors.Join(errs...))
+	}()
+
+	return future
+}
+
+/*
+Race resolves with the outcome of the first future that completes, whether that is a
+value or an error.
+
+Example:
+
+	first, err := Race(ctx, fetch("a"), timeout(time.Second)).Result()
+*/
+func Race[T any](ctx context.Context, futures ...*Future[T]) *Future[T] {
+	promise, future := NewPromiseWithContext[T](ctx)
+	future.onCancel(dependOnAll(futures))
+
+	if len(futures) == 0 {
+		var zero T
+		promise.Set(zero, errors.New("called `Race` without any futures"))
+		return future
+	}
+
+	go func() {
+		ctx := promise.Context()
+
+		select {
+		case idx := <-watch(ctx, futures):
+			promise.Set(futures[idx].result, futures[idx].err)
+		case <-ctx.Done():
+			var zero T
+			promise.Set(zero, ctx.Err())
+		}
+	}()
+
+	return future
+}
+
+/*
+dependOnAll registers a dependent on every one of the futures, and returns the function
+that cancels them on its behalf.
+*/
+func dependOnAll[T any](futures []*Future[T]) func() {
+	releases := make([]func(), len(futures))
+	for idx, f := range futures {
+		releases[idx] = f.depend()
+	}
+
+	return func() {
+		for _, release := range releases {
+			release()
+		}
+	}
+}
+
+/*
+watch reports the index of each future as it completes. The channel is buffered so
+none of the watching goroutines ever block, and they all exit once the context is done,
+which for the combinators is as soon as their own Future is completed or cancelled.
+*/
+func watch[T any](ctx context.Context, futures []*Future[T]) <-chan int {
+	completed := make(chan int, len(futures))
+
+	for idx, f := range futures {
+		go func() {
+			select {
+			case <-f.Done():
+				completed <- idx
+			case <-ctx.Done():
+			}
+		}()
+	}
+
+	return completed
+}
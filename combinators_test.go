@@ -0,0 +1,131 @@
+package twoface
+
+import (
+	"context"
+	"errors"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/smartystreets/goconvey/convey"
+)
+
+func resolved[T any](value T, err error) *Future[T] {
+	p, f := NewPromise[T]()
+	p.Set(value, err)
+	return f
+}
+
+func pending[T any]() *Future[T] {
+	_, f := NewPromise[T]()
+	return f
+}
+
+func TestCombinators(t *testing.T) {
+	convey.Convey("Future combinators", t, func() {
+		ctx := context.Background()
+
+		convey.Convey("All should resolve with all values in order", func() {
+			p, slow := NewPromise[int]()
+			all := All(ctx, slow, resolved(2, nil), resolved(3, nil))
+			p.Set(1, nil)
+			values, err := all.Result()
+			convey.So(err, convey.ShouldBeNil)
+			convey.So(values, convey.ShouldResemble, []int{1, 2, 3})
+		})
+
+		convey.Convey("All should fail fast", func() {
+			values, err := All(ctx, pending[int](), resolved(0, errDummy)).Result()
+			convey.So(err, convey.ShouldEqual, errDummy)
+			convey.So(values, convey.ShouldBeNil)
+		})
+
+		convey.Convey("All should resolve with no futures", func() {
+			values, err := All[int](ctx).Result()
+			convey.So(err, convey.ShouldBeNil)
+			convey.So(values, convey.ShouldBeEmpty)
+		})
+
+		convey.Convey("AllSettled should collect every outcome", func() {
+			settled, err := AllSettled(ctx, resolved(1, nil), resolved(0, errDummy)).Result()
+			convey.So(err, convey.ShouldBeNil)
+			convey.So(settled[0].Unwrap(), convey.ShouldEqual, 1)
+			convey.So(settled[1].UnwrapErr(), convey.ShouldEqual, errDummy)
+		})
+
+		convey.Convey("AllSettled should settle pending futures with the context error", func() {
+			ctx, cancel := context.WithCancel(ctx)
+			all := AllSettled(ctx, resolved(1, nil), pending[int]())
+			time.Sleep(10 * time.Millisecond)
+			cancel()
+			settled, err := all.Result()
+			convey.So(err, convey.ShouldBeNil)
+			convey.So(settled[0].Unwrap(), convey.ShouldEqual, 1)
+			convey.So(settled[1].UnwrapErr(), convey.ShouldEqual, context.Canceled)
+		})
+
+		convey.Convey("Any should resolve with the first success", func() {
+			value, err := Any(ctx, resolved(0, errDummy), pending[int](), resolved(42, nil)).Result()
+			convey.So(err, convey.ShouldBeNil)
+			convey.So(value, convey.ShouldEqual, 42)
+		})
+
+		convey.Convey("Any should join the errors when all futures fail", func() {
+			other := errors.New("other error")
+			_, err := Any(ctx, resolved(0, errDummy), resolved(0, other)).Result()
+			convey.So(errors.Is(err, errDummy), convey.ShouldBeTrue)
+			convey.So(errors.Is(err, other), convey.ShouldBeTrue)
+		})
+
+		convey.Convey("Race should resolve with the first completion", func() {
+			_, err := Race(ctx, pending[int](), resolved(0, errDummy)).Result()
+			convey.So(err, convey.ShouldEqual, errDummy)
+
+			value, err := Race(ctx, pending[int](), resolved(42, nil)).Result()
+			convey.So(err, convey.ShouldBeNil)
+			convey.So(value, convey.ShouldEqual, 42)
+		})
+
+		convey.Convey("Any and Race should fail without futures", func() {
+			_, err := Any[int](ctx).Result()
+			convey.So(err, convey.ShouldNotBeNil)
+			_, err = Race[int](ctx).Result()
+			convey.So(err, convey.ShouldNotBeNil)
+		})
+
+		convey.Convey("Should not leak goroutines once the context is cancelled", func() {
+			before := runtime.NumGoroutine()
+			ctx, cancel := context.WithCancel(ctx)
+
+			futures := []*Future[int]{pending[int](), pending[int](), pending[int]()}
+			combined := []func() error{
+				func() error { _, err := All(ctx, futures...).Result(); return err },
+				func() error { _, err := Any(ctx, futures...).Result(); return err },
+				func() error { _, err := Race(ctx, futures...).Result(); return err },
+			}
+
+			errs := make(chan error, len(combined))
+			for _, wait := range combined {
+				go func() { errs <- wait() }()
+			}
+
+			cancel()
+			for range combined {
+				convey.So(<-errs, convey.ShouldEqual, context.Canceled)
+			}
+
+			deadline := time.Now().Add(time.Second)
+			for runtime.NumGoroutine() > before && time.Now().Before(deadline) {
+				time.Sleep(time.Millisecond)
+			}
+			convey.So(runtime.NumGoroutine(), convey.ShouldBeLessThanOrEqualTo, before)
+		})
+	})
+}
+
+func BenchmarkAll(b *testing.B) {
+	ctx := context.Background()
+	for i := 0; i < b.N; i++ {
+		All(ctx, resolved(1, nil), resolved(2, nil), resolved(3, nil)).Result()
+	}
+}
@@ -0,0 +1,591 @@
+package twoface
+
+import (
+	"math/rand/v2"
+	"sort"
+	"sync"
+	"time"
+)
+
+/*
+Clock is the source of time of a Cron, which can be replaced to test schedules without
+having to wait for them.
+*/
+type Clock interface {
+	Now() time.Time
+	After(d time.Duration) <-chan time.Time
+	NewTimer(d time.Duration) Timer
+}
+
+/*
+Timer is a timer of a Clock, like a time.Timer, which can be stopped and reset, so a
+loop that waits over and over does not need a new timer every time.
+*/
+type Timer interface {
+	C() <-chan time.Time
+	Stop() bool
+	Reset(d time.Duration) bool
+}
+
+/*
+SystemClock is the Clock that follows the time of the system.
+*/
+var SystemClock Clock = systemClock{}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
+
+func (systemClock) After(d time.Duration) <-chan time.Time {
+	return time.After(d)
+}
+
+func (systemClock) NewTimer(d time.Duration) Timer {
+	return systemTimer{time.NewTimer(d)}
+}
+
+type systemTimer struct {
+	*time.Timer
+}
+
+func (timer systemTimer) C() <-chan time.Time {
+	return timer.Timer.C
+}
+
+/*
+OverlapPolicy decides what happens when a job is due while its previous run is still
+in progress.
+*/
+type OverlapPolicy int
+
+const (
+	// OverlapAllow starts the new run regardless.
+	OverlapAllow OverlapPolicy = iota
+	// OverlapSkip skips the new run.
+	OverlapSkip
+	// OverlapQueue starts the new run once the previous one has finished.
+	OverlapQueue
+)
+
+/*
+CatchUpPolicy decides what happens with the runs that were missed while a Cron was
+stopped, or could not keep up.
+*/
+type CatchUpPolicy int
+
+const (
+	// CatchUpNone skips the missed runs, and continues with the next one.
+	CatchUpNone CatchUpPolicy = iota
+	// CatchUpOnce runs a job once for all of its missed runs.
+	CatchUpOnce
+	// CatchUpAll runs a job for every missed run, up to maxCatchUp runs.
+	CatchUpAll
+)
+
+/*
+maxCatchUp bounds the number of missed runs that CatchUpAll makes up for at once, so a
+short interval after a long pause does not flood the Pool.
+*/
+const maxCatchUp = 1000
+
+/*
+CronOption configures a Cron.
+*/
+type CronOption func(*Cron)
+
+/*
+WithCronClock sets the Clock of a Cron. It defaults to SystemClock.
+*/
+func WithCronClock(clock Clock) CronOption {
+	return func(cron *Cron) {
+		cron.clock = clock
+	}
+}
+
+/*
+WithCronLocation sets the time zone of the expressions that do not have one of their own.
+It defaults to the local time zone.
+*/
+func WithCronLocation(location *time.Location) CronOption {
+	return func(cron *Cron) {
+		cron.location = location
+	}
+}
+
+/*
+WithCatchUp sets how the runs that were missed are made up for. It defaults to CatchUpNone.
+*/
+func WithCatchUp(policy CatchUpPolicy) CronOption {
+	return func(cron *Cron) {
+		cron.catchUp = policy
+	}
+}
+
+/*
+CronEntryOption configures a single job of a Cron.
+*/
+type CronEntryOption func(*cronEntry)
+
+/*
+WithOverlap sets what happens when the job is due while it is still running. It defaults
+to OverlapAllow.
+*/
+func WithOverlap(policy OverlapPolicy) CronEntryOption {
+	return func(entry *cronEntry) {
+		entry.overlap = policy
+	}
+}
+
+/*
+WithJitter delays every run by a random duration up to the given one, to spread out jobs
+that are scheduled for the same time.
+*/
+func WithJitter(jitter time.Duration) CronEntryOption {
+	return func(entry *cronEntry) {
+		entry.jitter = jitter
+	}
+}
+
+/*
+CronEntry is a snapshot of a job of a Cron.
+*/
+type CronEntry struct {
+	ID      int
+	Spec    string
+	Next    time.Time
+	Prev    time.Time
+	Runs    int
+	Skipped int
+	Running int
+}
+
+/*
+Cron submits jobs to a Pool on a recurring schedule. It does not run anything until it
+is started, and can be stopped and started again, after which the runs it missed are
+made up for according to its CatchUpPolicy.
+
+Example:
+
+	cron := NewCron(pool, WithCronLocation(time.UTC))
+	cron.Add("@every 5m", cleanup, WithOverlap(OverlapSkip))
+	cron.Add("@daily", report, WithJitter(time.Minute))
+	cron.Start()
+	defer cron.Stop()
+*/
+type Cron struct {
+	pool     *Pool
+	clock    Clock
+	location *time.Location
+	catchUp  CatchUpPolicy
+	mu       sync.Mutex
+	entries  map[int]*cronEntry
+	nextID   int
+	started  bool
+	running  bool
+	wake     chan struct{}
+	stop     chan struct{}
+	stopped  chan struct{}
+}
+
+/*
+NewCron creates a Cron that submits its jobs to the pool.
+
+Example:
+
+	cron := NewCron(pool)
+*/
+func NewCron(pool *Pool, options ...CronOption) *Cron {
+	cron := &Cron{
+		pool:     pool,
+		clock:    SystemClock,
+		location: time.Local,
+		entries:  make(map[int]*cronEntry),
+		wake:     make(chan struct{}, 1),
+	}
+
+	for _, option := range options {
+		option(cron)
+	}
+
+	return cron
+}
+
+/*
+Add schedules the job according to the cron expression, as understood by ParseCron, and
+returns the ID of the entry.
+
+Example:
+
+	id, err := cron.Add("0 9 * * MON-FRI", standup)
+*/
+func (cron *Cron) Add(spec string, job Job, options ...CronEntryOption) (int, error) {
+	schedule, err := ParseCron(spec)
+	if err != nil {
+		return 0, err
+	}
+
+	return cron.addEntry(spec, schedule, job, options), nil
+}
+
+/*
+AddSchedule schedules the job according to a CronSchedule of its own.
+
+Example:
+
+	id := cron.AddSchedule(MustParseCron("@hourly"), sync)
+*/
+func (cron *Cron) AddSchedule(schedule CronSchedule, job Job, options ...CronEntryOption) int {
+	return cron.addEntry("", schedule, job, options)
+}
+
+func (cron *Cron) addEntry(spec string, schedule CronSchedule, job Job, options []CronEntryOption) int {
+	entry := &cronEntry{spec: spec, schedule: schedule, job: job}
+
+	for _, option := range options {
+		option(entry)
+	}
+
+	cron.mu.Lock()
+	cron.nextID++
+	entry.id = cron.nextID
+	cron.reschedule(entry, cron.clock.Now())
+	cron.entries[entry.id] = entry
+	cron.mu.Unlock()
+
+	cron.notify()
+	return entry.id
+}
+
+/*
+Remove stops scheduling the entry. Runs that were already submitted are not affected.
+It returns false when there is no entry with the ID.
+
+Example:
+
+	cron.Remove(id)
+*/
+func (cron *Cron) Remove(id int) bool {
+	cron.mu.Lock()
+	defer cron.mu.Unlock()
+
+	_, ok := cron.entries[id]
+	delete(cron.entries, id)
+	return ok
+}
+
+/*
+Entries returns a snapshot of all entries, ordered by ID.
+
+Example:
+
+	for _, entry := range cron.Entries() {
+	    fmt.Println(entry.Spec, entry.Next)
+	}
+*/
+func (cron *Cron) Entries() []CronEntry {
+	cron.mu.Lock()
+	defer cron.mu.Unlock()
+
+	entries := make([]CronEntry, 0, len(cron.entries))
+	for _, entry := range cron.entries {
+		entries = append(entries, entry.snapshot())
+	}
+
+	sort.Slice(entries, func(i, j int) bool { return entries[i].ID < entries[j].ID })
+	return entries
+}
+
+/*
+Entry returns a snapshot of the entry with the ID, if there is one.
+
+Example:
+
+	next := cron.Entry(id).Expect("no such entry").Next
+*/
+func (cron *Cron) Entry(id int) Option[CronEntry] {
+	cron.mu.Lock()
+	defer cron.mu.Unlock()
+
+	entry, ok := cron.entries[id]
+	if !ok {
+		return None[CronEntry]()
+	}
+
+	return Some(entry.snapshot())
+}
+
+/*
+Start starts submitting jobs. The first time, and with CatchUpNone, the runs that are
+in the past are skipped. Otherwise the runs that were missed while the Cron was stopped
+are made up for.
+
+Example:
+
+	cron.Start()
+*/
+func (cron *Cron) Start() {
+	cron.mu.Lock()
+	defer cron.mu.Unlock()
+
+	if cron.running {
+		return
+	}
+
+	now := cron.clock.Now()
+
+	if !cron.started || cron.catchUp == CatchUpNone {
+		for _, entry := range cron.entries {
+			if !entry.next.IsZero() && !entry.next.After(now) {
+				cron.reschedule(entry, now)
+			}
+		}
+	}
+
+	cron.started = true
+	cron.running = true
+	cron.stop = make(chan struct{})
+	cron.stopped = make(chan struct{})
+
+	go cron.run(cron.stop, cron.stopped)
+}
+
+/*
+Stop stops submitting jobs, and waits until nothing more is submitted. Runs that were
+already submitted are not affected.
+
+Example:
+
+	cron.Stop()
+*/
+func (cron *Cron) Stop() {
+	cron.mu.Lock()
+
+	if !cron.running {
+		cron.mu.Unlock()
+		return
+	}
+
+	cron.running = false
+	stop, stopped := cron.stop, cron.stopped
+	cron.mu.Unlock()
+
+	close(stop)
+	<-stopped
+}
+
+/*
+run submits the jobs that are due, and then waits until the next one is, until an entry
+is added, or until it is stopped. It reuses a single timer for all of its waits.
+*/
+func (cron *Cron) run(stop, stopped chan struct{}) {
+	defer close(stopped)
+
+	var timer Timer
+
+	defer func() {
+		if timer != nil {
+			timer.Stop()
+		}
+	}()
+
+	for {
+		now := cron.clock.Now()
+		runs, next := cron.due(now)
+
+		for _, run := range runs {
+			cron.pool.Submit(run)
+		}
+
+		var timeout <-chan time.Time
+		if !next.IsZero() {
+			if timer == nil {
+				timer = cron.clock.NewTimer(next.Sub(now))
+			} else {
+				timer.Reset(next.Sub(now))
+			}
+			timeout = timer.C()
+		}
+
+		select {
+		case <-timeout:
+			continue
+		case <-cron.wake:
+		case <-stop:
+			return
+		case <-cron.pool.ctx.Done():
+			return
+		}
+
+		// The timer may have fired in the meantime, which would wake the next wait early.
+		if timer != nil && !timer.Stop() {
+			select {
+			case <-timer.C():
+			default:
+			}
+		}
+	}
+}
+
+/*
+due returns the runs that have to be submitted now, and the time the next entry is due.
+*/
+func (cron *Cron) due(now time.Time) ([]Job, time.Time) {
+	cron.mu.Lock()
+	defer cron.mu.Unlock()
+
+	var runs []Job
+	var earliest time.Time
+
+	for _, entry := range cron.entries {
+		if entry.next.IsZero() {
+			continue
+		}
+
+		if !entry.due.After(now) {
+			missed := 0
+			for !entry.next.IsZero() && !entry.next.After(now) && missed < maxCatchUp {
+				missed++
+				entry.prev = entry.next
+				entry.next = entry.schedule.Next(entry.next)
+			}
+
+			count := 1
+			if cron.catchUp == CatchUpAll {
+				count = missed
+			}
+
+			for range count {
+				if run := cron.admit(entry); run != nil {
+					runs = append(runs, run)
+				}
+			}
+
+			entry.due = entry.withJitter(entry.next)
+		}
+
+		if !entry.next.IsZero() && (earliest.IsZero() || entry.due.Before(earliest)) {
+			earliest = entry.due
+		}
+	}
+
+	return runs, earliest
+}
+
+/*
+admit applies the overlap policy of the entry to a run that is due, returning the run
+when it can be submitted.
+*/
+func (cron *Cron) admit(entry *cronEntry) Job {
+	if entry.active > 0 {
+		switch entry.overlap {
+		case OverlapSkip:
+			entry.skipped++
+			return nil
+		case OverlapQueue:
+			entry.queued++
+			return nil
+		}
+	}
+
+	entry.active++
+	return cronRun{cron: cron, entry: entry}
+}
+
+/*
+finished accounts for a run that is done, and submits the next queued run, if any.
+*/
+func (cron *Cron) finished(entry *cronEntry) {
+	cron.mu.Lock()
+	entry.active--
+
+	var next Job
+	if entry.queued > 0 {
+		entry.queued--
+		entry.active++
+		next = cronRun{cron: cron, entry: entry}
+	}
+	cron.mu.Unlock()
+
+	if next != nil {
+		cron.pool.Submit(next)
+	}
+}
+
+/*
+reschedule sets the next run of the entry to the first one after now.
+*/
+func (cron *Cron) reschedule(entry *cronEntry, now time.Time) {
+	entry.next = entry.schedule.Next(now.In(cron.location))
+	entry.due = entry.withJitter(entry.next)
+}
+
+func (cron *Cron) notify() {
+	select {
+	case cron.wake <- struct{}{}:
+	default:
+	}
+}
+
+/*
+cronEntry is a job of a Cron, with the state of its schedule.
+*/
+type cronEntry struct {
+	id       int
+	spec     string
+	schedule CronSchedule
+	job      Job
+	overlap  OverlapPolicy
+	jitter   time.Duration
+	next     time.Time
+	due      time.Time
+	prev     time.Time
+	runs     int
+	skipped  int
+	active   int
+	queued   int
+}
+
+func (entry *cronEntry) withJitter(next time.Time) time.Time {
+	if entry.jitter <= 0 || next.IsZero() {
+		return next
+	}
+
+	return next.Add(time.Duration(rand.Int64N(int64(entry.jitter))))
+}
+
+func (entry *cronEntry) snapshot() CronEntry {
+	return CronEntry{
+		ID:      entry.id,
+		Spec:    entry.spec,
+		Next:    entry.next,
+		Prev:    entry.prev,
+		Runs:    entry.runs,
+		Skipped: entry.skipped,
+		Running: entry.active,
+	}
+}
+
+/*
+cronRun is a single run of an entry, that lets the Cron know when it is done.
+*/
+type cronRun struct {
+	cron  *Cron
+	entry *cronEntry
+}
+
+func (run cronRun) Do() Result[any, error] {
+	defer run.cron.finished(run.entry)
+
+	run.cron.mu.Lock()
+	run.entry.runs++
+	run.cron.mu.Unlock()
+
+	return run.entry.job.Do()
+}
+
+func (run cronRun) discard(err error) {
+	discardJob(run.entry.job, err)
+	run.cron.finished(run.entry)
+}
@@ -0,0 +1,241 @@
+package twoface
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/smartystreets/goconvey/convey"
+)
+
+func TestCron(t *testing.T) {
+	convey.Convey("Cron", t, func() {
+		ctx := context.Background()
+		pool := NewPool(ctx, 4)
+		start := time.Date(2024, time.January, 1, 10, 0, 30, 0, time.UTC)
+		clock := &testClock{now: start}
+
+		var runs atomic.Int64
+		counter := JobFunc(func() Result[any, error] {
+			runs.Add(1)
+			return Ok[any, error](nil)
+		})
+
+		release := make(chan struct{})
+		var running, peak atomic.Int64
+		blocking := JobFunc(func() Result[any, error] {
+			current := running.Add(1)
+			if current > peak.Load() {
+				peak.Store(current)
+			}
+			<-release
+			running.Add(-1)
+			runs.Add(1)
+			return Ok[any, error](nil)
+		})
+
+		newCron := func(options ...CronOption) *Cron {
+			return NewCron(pool, append([]CronOption{WithCronClock(clock), WithCronLocation(time.UTC)}, options...)...)
+		}
+
+		// advanceUntil moves the clock forward in steps, giving the cron a moment to catch
+		// up after every step, until the condition holds.
+		advanceUntil := func(step time.Duration, condition func() bool) bool {
+			for range 1000 {
+				if condition() {
+					return true
+				}
+				clock.Advance(step)
+				time.Sleep(time.Millisecond)
+			}
+			return condition()
+		}
+
+		eventually := func(condition func() bool) bool {
+			return advanceUntil(0, condition)
+		}
+
+		convey.Convey("Should expose the next run of its entries", func() {
+			cron := newCron()
+			id, err := cron.Add("*/15 * * * *", counter)
+			convey.So(err, convey.ShouldBeNil)
+
+			entry := cron.Entry(id)
+			convey.So(entry.IsSome(), convey.ShouldBeTrue)
+			convey.So(entry.UnwrapOrZero().Spec, convey.ShouldEqual, "*/15 * * * *")
+			convey.So(entry.UnwrapOrZero().Next, convey.ShouldEqual, time.Date(2024, 1, 1, 10, 15, 0, 0, time.UTC))
+			convey.So(cron.Entries(), convey.ShouldHaveLength, 1)
+
+			_, err = cron.Add("not a cron expression", counter)
+			convey.So(err, convey.ShouldNotBeNil)
+
+			convey.So(cron.Remove(id), convey.ShouldBeTrue)
+			convey.So(cron.Remove(id), convey.ShouldBeFalse)
+			convey.So(cron.Entry(id).IsNone(), convey.ShouldBeTrue)
+		})
+
+		convey.Convey("Should submit jobs to the pool on schedule", func() {
+			cron := newCron()
+			id, _ := cron.Add("@every 1m", counter)
+			cron.Start()
+
+			clock.Advance(59 * time.Second)
+			time.Sleep(5 * time.Millisecond)
+			convey.So(runs.Load(), convey.ShouldEqual, 0)
+
+			convey.So(advanceUntil(10*time.Second, func() bool { return runs.Load() >= 3 }), convey.ShouldBeTrue)
+			cron.Stop()
+
+			entry := cron.Entry(id).UnwrapOrZero()
+			convey.So(entry.Runs, convey.ShouldEqual, runs.Load())
+			convey.So(entry.Next.Sub(entry.Prev), convey.ShouldEqual, time.Minute)
+		})
+
+		convey.Convey("Should wait on a single timer, and stop it when it stops", func() {
+			cron := newCron()
+			cron.Start()
+
+			for range 10 {
+				cron.Add("@every 1m", counter)
+				time.Sleep(time.Millisecond)
+			}
+			convey.So(eventually(func() bool { return clock.pending() == 1 }), convey.ShouldBeTrue)
+
+			convey.So(advanceUntil(10*time.Second, func() bool { return runs.Load() >= 10 }), convey.ShouldBeTrue)
+			convey.So(eventually(func() bool { return clock.pending() == 1 }), convey.ShouldBeTrue)
+
+			cron.Stop()
+			convey.So(clock.pending(), convey.ShouldEqual, 0)
+		})
+
+		convey.Convey("Should run on a pool that is backed by a FileQueue", func() {
+			queue, err := OpenFileQueue(FileQueueConfig{Dir: t.TempDir(), Registry: testRegistry()})
+			convey.So(err, convey.ShouldBeNil)
+			defer queue.Close()
+
+			queued := NewPool(ctx, 1, WithQueue(queue))
+			defer queued.Shutdown()
+
+			cron := NewCron(queued, WithCronClock(clock), WithCronLocation(time.UTC))
+			cron.Add("@every 1m", counter)
+			cron.Start()
+
+			convey.So(advanceUntil(10*time.Second, func() bool { return runs.Load() >= 2 }), convey.ShouldBeTrue)
+			cron.Stop()
+			convey.So(queue.Len(), convey.ShouldEqual, 0)
+		})
+
+		convey.Convey("Should skip runs that overlap with OverlapSkip", func() {
+			cron := newCron()
+			id, _ := cron.Add("@every 1m", blocking, WithOverlap(OverlapSkip))
+			cron.Start()
+
+			convey.So(advanceUntil(10*time.Second, func() bool {
+				return cron.Entry(id).UnwrapOrZero().Skipped >= 2
+			}), convey.ShouldBeTrue)
+			cron.Stop()
+			close(release)
+
+			convey.So(eventually(func() bool { return runs.Load() == 1 }), convey.ShouldBeTrue)
+			convey.So(cron.Entry(id).UnwrapOrZero().Runs, convey.ShouldEqual, 1)
+		})
+
+		convey.Convey("Should run overlapping runs one after the other with OverlapQueue", func() {
+			cron := newCron()
+			id, _ := cron.Add("@every 1m", blocking, WithOverlap(OverlapQueue))
+			cron.Start()
+
+			convey.So(advanceUntil(10*time.Second, func() bool {
+				return !cron.Entry(id).UnwrapOrZero().Next.Before(start.Add(4 * time.Minute))
+			}), convey.ShouldBeTrue)
+			cron.Stop()
+			close(release)
+
+			convey.So(eventually(func() bool { return runs.Load() == 3 }), convey.ShouldBeTrue)
+			convey.So(peak.Load(), convey.ShouldEqual, 1)
+		})
+
+		convey.Convey("Should run overlapping runs at the same time with OverlapAllow", func() {
+			cron := newCron()
+			cron.Add("@every 1m", blocking)
+			cron.Start()
+
+			convey.So(advanceUntil(10*time.Second, func() bool { return running.Load() == 2 }), convey.ShouldBeTrue)
+			cron.Stop()
+			close(release)
+		})
+
+		convey.Convey("Should skip the missed runs with CatchUpNone", func() {
+			cron := newCron()
+			id, _ := cron.Add("@every 1m", counter)
+			cron.Start()
+			cron.Stop()
+
+			clock.Advance(5 * time.Minute)
+			cron.Start()
+			time.Sleep(5 * time.Millisecond)
+			cron.Stop()
+
+			convey.So(runs.Load(), convey.ShouldEqual, 0)
+			convey.So(cron.Entry(id).UnwrapOrZero().Next.After(clock.Now()), convey.ShouldBeTrue)
+		})
+
+		convey.Convey("Should make up for the missed runs once with CatchUpOnce", func() {
+			cron := newCron(WithCatchUp(CatchUpOnce))
+			cron.Add("@every 1m", counter)
+			cron.Start()
+			cron.Stop()
+
+			clock.Advance(5 * time.Minute)
+			cron.Start()
+			convey.So(eventually(func() bool { return runs.Load() == 1 }), convey.ShouldBeTrue)
+			time.Sleep(5 * time.Millisecond)
+			cron.Stop()
+			convey.So(runs.Load(), convey.ShouldEqual, 1)
+		})
+
+		convey.Convey("Should make up for every missed run with CatchUpAll", func() {
+			cron := newCron(WithCatchUp(CatchUpAll))
+			cron.Add("@every 1m", counter)
+			cron.Start()
+			cron.Stop()
+
+			clock.Advance(5 * time.Minute)
+			cron.Start()
+			convey.So(eventually(func() bool { return runs.Load() == 5 }), convey.ShouldBeTrue)
+			time.Sleep(5 * time.Millisecond)
+			cron.Stop()
+			convey.So(runs.Load(), convey.ShouldEqual, 5)
+		})
+
+		convey.Convey("Should delay runs by at most the jitter", func() {
+			cron := newCron()
+			id, _ := cron.Add("@every 1m", counter, WithJitter(30*time.Second))
+
+			cron.mu.Lock()
+			entry := cron.entries[id]
+			delay := entry.due.Sub(entry.next)
+			cron.mu.Unlock()
+
+			convey.So(delay, convey.ShouldBeGreaterThanOrEqualTo, 0)
+			convey.So(delay, convey.ShouldBeLessThan, 30*time.Second)
+
+			cron.Start()
+			convey.So(advanceUntil(5*time.Second, func() bool { return runs.Load() >= 1 }), convey.ShouldBeTrue)
+			cron.Stop()
+			convey.So(clock.Now().Sub(start), convey.ShouldBeLessThan, 95*time.Second)
+		})
+
+		convey.Convey("Should follow the time zone of the cron", func() {
+			newYork, _ := time.LoadLocation("America/New_York")
+			cron := newCron(WithCronLocation(newYork))
+			id, _ := cron.Add("0 9 * * *", counter)
+			convey.So(cron.Entry(id).UnwrapOrZero().Next.Equal(time.Date(2024, 1, 1, 9, 0, 0, 0, newYork)), convey.ShouldBeTrue)
+		})
+
+		convey.Reset(func() {
+			pool.Shutdown()
+		})
+	})
+}
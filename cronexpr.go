@@ -0,0 +1,359 @@
+package twoface
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+/*
+CronSchedule decides when a recurring job runs next.
+*/
+type CronSchedule interface {
+	// Next returns the first time after t that the job should run, or the zero time
+	// when it never runs again.
+	Next(t time.Time) time.Time
+}
+
+/*
+ParseCron parses a cron expression, with either five fields (minute, hour, day of the
+month, month, day of the week), or six fields, with seconds in front. Fields accept
+`*`, `?`, lists, ranges, steps, and the names of months and days of the week. The
+descriptors `@yearly`, `@annually`, `@monthly`, `@weekly`, `@daily`, `@midnight`,
+`@hourly` and `@every <duration>` are supported too. A `CRON_TZ=` or `TZ=` prefix
+sets the time zone of the expression, which otherwise is the one of the times it is
+given.
+
+Example:
+
+	schedule, err := ParseCron("CRON_TZ=Europe/Amsterdam 30 9 * * MON-FRI")
+	next := schedule.Next(time.Now())
+*/
+func ParseCron(expr string) (CronSchedule, error) {
+	spec := strings.TrimSpace(expr)
+	var location *time.Location
+
+	if strings.HasPrefix(spec, "CRON_TZ=") || strings.HasPrefix(spec, "TZ=") {
+		zone, rest, _ := strings.Cut(spec, " ")
+		_, name, _ := strings.Cut(zone, "=")
+
+		loaded, err := time.LoadLocation(name)
+		if err != nil {
+			return nil, fmt.Errorf("cannot parse cron expression %q: %w", expr, err)
+		}
+
+		location = loaded
+		spec = strings.TrimSpace(rest)
+	}
+
+	if strings.HasPrefix(spec, "@") {
+		schedule, err := parseDescriptor(spec, location)
+		if err != nil {
+			return nil, fmt.Errorf("cannot parse cron expression %q: %w", expr, err)
+		}
+		return schedule, nil
+	}
+
+	fields := strings.Fields(spec)
+
+	switch len(fields) {
+	case 5:
+		fields = append([]string{"0"}, fields...)
+	case 6:
+	default:
+		return nil, fmt.Errorf("cannot parse cron expression %q: expected 5 or 6 fields, got %d", expr, len(fields))
+	}
+
+	schedule := &cronSpec{location: location}
+	targets := []*uint64{&schedule.second, &schedule.minute, &schedule.hour, &schedule.dom, &schedule.month, &schedule.dow}
+
+	for idx, field := range fields {
+		bits, err := cronFields[idx].parse(field)
+		if err != nil {
+			return nil, fmt.Errorf("cannot parse cron expression %q: %w", expr, err)
+		}
+		*targets[idx] = bits
+	}
+
+	// Sunday can be written as both 0 and 7.
+	if schedule.dow&(1<<7) != 0 {
+		schedule.dow = schedule.dow&^(1<<7) | 1
+	}
+
+	return schedule, nil
+}
+
+/*
+MustParseCron is like ParseCron, but panics when the expression is invalid.
+
+Example:
+
+	schedule := MustParseCron("@hourly")
+*/
+func MustParseCron(expr string) CronSchedule {
+	schedule, err := ParseCron(expr)
+	if err != nil {
+		panic(err)
+	}
+	return schedule
+}
+
+/*
+parseDescriptor parses the expressions that start with an @.
+*/
+func parseDescriptor(spec string, location *time.Location) (CronSchedule, error) {
+	if every, ok := strings.CutPrefix(spec, "@every "); ok {
+		interval, err := time.ParseDuration(strings.TrimSpace(every))
+		if err != nil {
+			return nil, err
+		}
+		if interval <= 0 {
+			return nil, fmt.Errorf("the interval of `@every` has to be positive, got %v", interval)
+		}
+		return everySchedule{interval: interval}, nil
+	}
+
+	descriptors := map[string]string{
+		"@yearly":   "0 0 0 1 1 *",
+		"@annually": "0 0 0 1 1 *",
+		"@monthly":  "0 0 0 1 * *",
+		"@weekly":   "0 0 0 * * 0",
+		"@daily":    "0 0 0 * * *",
+		"@midnight": "0 0 0 * * *",
+		"@hourly":   "0 0 * * * *",
+	}
+
+	expr, ok := descriptors[spec]
+	if !ok {
+		return nil, fmt.Errorf("unknown descriptor %s", spec)
+	}
+
+	schedule, err := ParseCron(expr)
+	if err != nil {
+		return nil, err
+	}
+
+	schedule.(*cronSpec).location = location
+	return schedule, nil
+}
+
+/*
+everySchedule runs at a fixed interval, which starts counting at the last run.
+*/
+type everySchedule struct {
+	interval time.Duration
+}
+
+func (schedule everySchedule) Next(t time.Time) time.Time {
+	if schedule.interval < time.Second {
+		return t.Add(schedule.interval)
+	}
+
+	return t.Add(schedule.interval).Truncate(time.Second)
+}
+
+/*
+cronField describes the values that a field of a cron expression can take.
+*/
+type cronField struct {
+	name  string
+	min   int
+	max   int
+	names map[string]int
+}
+
+/*
+starBit marks a day field that was given as `*` or `?`, which matters for how the day of
+the month and the day of the week combine.
+*/
+const starBit = 1 << 63
+
+var cronFields = []cronField{
+	{name: "second", min: 0, max: 59},
+	{name: "minute", min: 0, max: 59},
+	{name: "hour", min: 0, max: 23},
+	{name: "day of the month", min: 1, max: 31},
+	{name: "month", min: 1, max: 12, names: map[string]int{
+		"JAN": 1, "FEB": 2, "MAR": 3, "APR": 4, "MAY": 5, "JUN": 6,
+		"JUL": 7, "AUG": 8, "SEP": 9, "OCT": 10, "NOV": 11, "DEC": 12,
+	}},
+	{name: "day of the week", min: 0, max: 7, names: map[string]int{
+		"SUN": 0, "MON": 1, "TUE": 2, "WED": 3, "THU": 4, "FRI": 5, "SAT": 6,
+	}},
+}
+
+/*
+parse turns a field into a bit set, with a bit for every value that matches.
+*/
+func (field cronField) parse(value string) (uint64, error) {
+	var bits uint64
+
+	for _, part := range strings.Split(value, ",") {
+		partBits, err := field.parsePart(part)
+		if err != nil {
+			return 0, err
+		}
+		bits |= partBits
+	}
+
+	return bits, nil
+}
+
+func (field cronField) parsePart(part string) (uint64, error) {
+	rangePart, stepPart, hasStep := strings.Cut(part, "/")
+	low, high := field.min, field.max
+	var extra uint64
+
+	switch rangePart {
+	case "*", "?":
+		if !hasStep {
+			extra = starBit
+		}
+	default:
+		first, last, isRange := strings.Cut(rangePart, "-")
+
+		var err error
+		if low, err = field.value(first); err != nil {
+			return 0, err
+		}
+
+		high = low
+		if isRange {
+			if high, err = field.value(last); err != nil {
+				return 0, err
+			}
+		} else if hasStep {
+			high = field.max
+		}
+	}
+
+	step := 1
+	if hasStep {
+		var err error
+		if step, err = strconv.Atoi(stepPart); err != nil || step <= 0 {
+			return 0, fmt.Errorf("invalid step %q in the %s field", stepPart, field.name)
+		}
+	}
+
+	if low > high {
+		return 0, fmt.Errorf("invalid range %q in the %s field: %d is after %d", rangePart, field.name, low, high)
+	}
+
+	bits := extra
+	for value := low; value <= high; value += step {
+		bits |= 1 << uint(value)
+	}
+
+	return bits, nil
+}
+
+func (field cronField) value(text string) (int, error) {
+	if value, ok := field.names[strings.ToUpper(text)]; ok {
+		return value, nil
+	}
+
+	value, err := strconv.Atoi(text)
+	if err != nil {
+		return 0, fmt.Errorf("invalid value %q in the %s field", text, field.name)
+	}
+
+	if value < field.min || value > field.max {
+		return 0, fmt.Errorf("value %d in the %s field is out of range [%d, %d]", value, field.name, field.min, field.max)
+	}
+
+	return value, nil
+}
+
+/*
+cronSpec is a parsed cron expression, with a bit set for every field.
+*/
+type cronSpec struct {
+	second, minute, hour, dom, month, dow uint64
+	location                              *time.Location
+}
+
+/*
+Next finds the next matching time by moving forward one field at a time, starting at the
+month, and starting over from the month whenever a field wraps around. A schedule that
+does not match within five years, like the 30th of February, never runs.
+*/
+func (spec *cronSpec) Next(t time.Time) time.Time {
+	original := t.Location()
+	location := spec.location
+	if location == nil {
+		location = original
+	}
+
+	t = t.In(location).Truncate(time.Second).Add(time.Second)
+	limit := t.Year() + 5
+
+	// The first field that does not match resets the fields below it. After that, the
+	// lower fields are already zero whenever a field is moved forward.
+	reset := false
+	truncate := func(truncated time.Time) time.Time {
+		if reset {
+			return t
+		}
+		reset = true
+		return truncated
+	}
+
+wrap:
+	for t.Year() <= limit {
+		for spec.month&(1<<uint(t.Month())) == 0 {
+			t = truncate(time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, location)).AddDate(0, 1, 0)
+			if t.Month() == time.January {
+				continue wrap
+			}
+		}
+
+		for !spec.matchesDay(t) {
+			t = truncate(time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, location))
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, location)
+			if t.Day() == 1 {
+				continue wrap
+			}
+		}
+
+		for spec.hour&(1<<uint(t.Hour())) == 0 {
+			t = truncate(time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), 0, 0, 0, location)).Add(time.Hour)
+			if t.Hour() == 0 {
+				continue wrap
+			}
+		}
+
+		for spec.minute&(1<<uint(t.Minute())) == 0 {
+			t = truncate(t.Truncate(time.Minute)).Add(time.Minute)
+			if t.Minute() == 0 {
+				continue wrap
+			}
+		}
+
+		for spec.second&(1<<uint(t.Second())) == 0 {
+			t = truncate(t.Truncate(time.Second)).Add(time.Second)
+			if t.Second() == 0 {
+				continue wrap
+			}
+		}
+
+		return t.In(original)
+	}
+
+	return time.Time{}
+}
+
+/*
+matchesDay follows the usual cron rule, where a day has to match both day fields when
+either of them is `*`, and only one of them when both are restricted.
+*/
+func (spec *cronSpec) matchesDay(t time.Time) bool {
+	domMatch := spec.dom&(1<<uint(t.Day())) != 0
+	dowMatch := spec.dow&(1<<uint(t.Weekday())) != 0
+
+	if spec.dom&starBit != 0 || spec.dow&starBit != 0 {
+		return domMatch && dowMatch
+	}
+
+	return domMatch || dowMatch
+}
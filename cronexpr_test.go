@@ -0,0 +1,98 @@
+package twoface
+
+import (
+	"testing"
+	"time"
+
+	"github.com/smartystreets/goconvey/convey"
+)
+
+func TestParseCron(t *testing.T) {
+	convey.Convey("ParseCron", t, func() {
+		// A Monday.
+		base := time.Date(2024, time.January, 1, 10, 0, 30, 0, time.UTC)
+
+		convey.Convey("Should find the next run of an expression", func() {
+			cases := []struct {
+				expr string
+				next time.Time
+			}{
+				{"* * * * *", time.Date(2024, 1, 1, 10, 1, 0, 0, time.UTC)},
+				{"*/15 * * * *", time.Date(2024, 1, 1, 10, 15, 0, 0, time.UTC)},
+				{"30 * * * * *", time.Date(2024, 1, 1, 10, 1, 30, 0, time.UTC)},
+				{"5,10-12/2 * * * * *", time.Date(2024, 1, 1, 10, 1, 5, 0, time.UTC)},
+				{"0 9 * * MON-FRI", time.Date(2024, 1, 2, 9, 0, 0, 0, time.UTC)},
+				{"0 9 * jan-mar mon", time.Date(2024, 1, 8, 9, 0, 0, 0, time.UTC)},
+				{"0 0 1 * *", time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)},
+				{"0 12 * * 7", time.Date(2024, 1, 7, 12, 0, 0, 0, time.UTC)},
+				{"0 0 13 * FRI", time.Date(2024, 1, 5, 0, 0, 0, 0, time.UTC)},
+				{"0 0 29 2 ?", time.Date(2024, 2, 29, 0, 0, 0, 0, time.UTC)},
+				{"@hourly", time.Date(2024, 1, 1, 11, 0, 0, 0, time.UTC)},
+				{"@daily", time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)},
+				{"@weekly", time.Date(2024, 1, 7, 0, 0, 0, 0, time.UTC)},
+				{"@monthly", time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)},
+				{"@yearly", time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)},
+				{"@every 90s", time.Date(2024, 1, 1, 10, 2, 0, 0, time.UTC)},
+			}
+
+			for _, tc := range cases {
+				schedule, err := ParseCron(tc.expr)
+				convey.So(err, convey.ShouldBeNil)
+				convey.So(schedule.Next(base), convey.ShouldEqual, tc.next)
+			}
+		})
+
+		convey.Convey("Should never run a schedule that cannot match", func() {
+			convey.So(MustParseCron("0 0 30 2 *").Next(base).IsZero(), convey.ShouldBeTrue)
+		})
+
+		convey.Convey("Should use the time zone of the expression", func() {
+			newYork, err := time.LoadLocation("America/New_York")
+			convey.So(err, convey.ShouldBeNil)
+
+			next := MustParseCron("CRON_TZ=America/New_York 0 9 * * *").Next(base)
+			convey.So(next, convey.ShouldEqual, time.Date(2024, 1, 1, 9, 0, 0, 0, newYork))
+			convey.So(next.Location(), convey.ShouldEqual, time.UTC)
+
+			daily := MustParseCron("TZ=America/New_York @daily").Next(base)
+			convey.So(daily, convey.ShouldEqual, time.Date(2024, 1, 2, 0, 0, 0, 0, newYork))
+		})
+
+		convey.Convey("Should use the time zone of the time without one of its own", func() {
+			newYork, _ := time.LoadLocation("America/New_York")
+			next := MustParseCron("0 9 * * *").Next(base.In(newYork))
+			convey.So(next, convey.ShouldEqual, time.Date(2024, 1, 1, 9, 0, 0, 0, newYork))
+		})
+
+		convey.Convey("Should skip the hour that does not exist when clocks go forward", func() {
+			newYork, _ := time.LoadLocation("America/New_York")
+			before := time.Date(2024, 3, 10, 1, 0, 0, 0, newYork)
+			next := MustParseCron("30 2 * * *").Next(before)
+			convey.So(next.After(before), convey.ShouldBeTrue)
+			convey.So(next.Day(), convey.ShouldEqual, 11)
+		})
+
+		convey.Convey("Should reject invalid expressions", func() {
+			for _, expr := range []string{
+				"",
+				"* * * *",
+				"60 * * * *",
+				"* * * * MOO",
+				"*/0 * * * *",
+				"5-1 * * * *",
+				"@every -1m",
+				"@sometimes",
+				"TZ=Nowhere/Nope * * * * *",
+			} {
+				_, err := ParseCron(expr)
+				convey.So(err, convey.ShouldNotBeNil)
+			}
+		})
+	})
+}
+
+func BenchmarkParseCron(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		MustParseCron("0 9 * * MON-FRI").Next(time.Now())
+	}
+}
@@ -0,0 +1,551 @@
+package twoface
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+/*
+DAGJob is a node of a DAG. It receives the Results of the nodes it depends on, by name.
+
+Example:
+
+	type Sum struct{}
+
+	func (Sum) Do(ctx context.Context, upstream map[string]Result[any, error]) Result[any, error] {
+	    return Ok[any, error](upstream["a"].Unwrap().(int) + upstream["b"].Unwrap().(int))
+	}
+*/
+type DAGJob interface {
+	Do(ctx context.Context, upstream map[string]Result[any, error]) Result[any, error]
+}
+
+/*
+DAGJobFunc is an adapter to allow the use of an ordinary function as a DAGJob.
+
+Example:
+
+	job := DAGJobFunc(func(ctx context.Context, upstream map[string]Result[any, error]) Result[any, error] {
+	    return Ok[any, error](42)
+	})
+*/
+type DAGJobFunc func(ctx context.Context, upstream map[string]Result[any, error]) Result[any, error]
+
+/*
+Do calls the function.
+*/
+func (fn DAGJobFunc) Do(ctx context.Context, upstream map[string]Result[any, error]) Result[any, error] {
+	return fn(ctx, upstream)
+}
+
+/*
+DAGFailurePolicy decides what happens to the rest of a DAG when one of its nodes fails.
+*/
+type DAGFailurePolicy int
+
+const (
+	// DAGFailFast cancels the context of the running nodes, and does not start any
+	// other node, as soon as a node fails.
+	DAGFailFast DAGFailurePolicy = iota
+	// DAGSkipDependents skips every node that depends on a failed node, directly or
+	// not, while the other nodes still run.
+	DAGSkipDependents
+	// DAGContinue runs every node, where the dependents of a failed node receive its
+	// failed Result.
+	DAGContinue
+)
+
+/*
+NodeStatus is the outcome of a node of a DAG.
+*/
+type NodeStatus int
+
+const (
+	NodePending NodeStatus = iota
+	NodeSucceeded
+	NodeFailed
+	NodeSkipped
+	NodeCancelled
+)
+
+func (status NodeStatus) String() string {
+	switch status {
+	case NodeSucceeded:
+		return "succeeded"
+	case NodeFailed:
+		return "failed"
+	case NodeSkipped:
+		return "skipped"
+	case NodeCancelled:
+		return "cancelled"
+	default:
+		return "pending"
+	}
+}
+
+var (
+	// ErrDAGCycle is returned when the dependencies of a DAG form a cycle.
+	ErrDAGCycle = errors.New("the DAG has a cycle")
+	// ErrNodeSkipped is the error of a node that was skipped, because one of the nodes
+	// it depends on failed.
+	ErrNodeSkipped = errors.New("skipped, because a dependency failed")
+)
+
+/*
+DAG runs jobs that depend on each other's outputs, where each job starts as soon as all
+of the jobs it depends on are done, so independent jobs run in parallel. Nodes are added
+with Node, and Build checks that every dependency exists and that there are no cycles.
+
+Example:
+
+	dag := NewDAG(DAGSkipDependents).
+	    Node("a", fetchA).
+	    Node("b", fetchB).
+	    Node("c", merge, "a", "b")
+
+	report, err := dag.Run(ctx, pool)
+	fmt.Println(report.DOT())
+*/
+type DAG struct {
+	policy DAGFailurePolicy
+	nodes  map[string]*dagNode
+	names  []string
+	order  []*dagNode
+	err    error
+}
+
+type dagNode struct {
+	name       string
+	job        DAGJob
+	deps       []string
+	dependents []*dagNode
+}
+
+/*
+NewDAG creates an empty DAG, with the policy for failures.
+
+Example:
+
+	dag := NewDAG(DAGFailFast)
+*/
+func NewDAG(policy DAGFailurePolicy) *DAG {
+	return &DAG{policy: policy, nodes: make(map[string]*dagNode)}
+}
+
+/*
+Node adds a job to the DAG, that runs once all of the nodes it depends on are done. The
+dependencies do not need to be added yet.
+
+Example:
+
+	dag.Node("report", buildReport, "users", "orders")
+*/
+func (dag *DAG) Node(name string, job DAGJob, deps ...string) *DAG {
+	if _, exists := dag.nodes[name]; exists {
+		dag.err = errors.Join(dag.err, fmt.Errorf("cannot add node %q twice", name))
+		return dag
+	}
+
+	dag.nodes[name] = &dagNode{name: name, job: job, deps: deps}
+	dag.names = append(dag.names, name)
+	dag.order = nil
+	return dag
+}
+
+/*
+Build checks that every dependency is a node of the DAG, and that there are no cycles.
+Run builds the DAG when that was not done yet.
+
+Example:
+
+	if err := dag.Build(); err != nil {
+	    log.Fatal(err)
+	}
+*/
+func (dag *DAG) Build() error {
+	if dag.err != nil {
+		return dag.err
+	}
+
+	if dag.order != nil {
+		return nil
+	}
+
+	for _, node := range dag.nodes {
+		node.dependents = nil
+	}
+
+	for _, name := range dag.names {
+		node := dag.nodes[name]
+
+		for _, dep := range node.deps {
+			upstream, ok := dag.nodes[dep]
+			if !ok {
+				return fmt.Errorf("cannot build the DAG: node %q depends on unknown node %q", name, dep)
+			}
+			upstream.dependents = append(upstream.dependents, node)
+		}
+	}
+
+	order, err := dag.sort()
+	if err != nil {
+		return err
+	}
+
+	dag.order = order
+	return nil
+}
+
+/*
+sort returns the nodes in an order where every node comes after its dependencies, or the
+path of a cycle.
+*/
+func (dag *DAG) sort() ([]*dagNode, error) {
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+
+	state := make(map[string]int, len(dag.nodes))
+	order := make([]*dagNode, 0, len(dag.nodes))
+	var path []string
+
+	var visit func(node *dagNode) error
+	visit = func(node *dagNode) error {
+		switch state[node.name] {
+		case visited:
+			return nil
+		case visiting:
+			start := 0
+			for path[start] != node.name {
+				start++
+			}
+			cycle := append(append([]string{}, path[start:]...), node.name)
+			return fmt.Errorf("%w: %s", ErrDAGCycle, strings.Join(cycle, " -> "))
+		}
+
+		state[node.name] = visiting
+		path = append(path, node.name)
+
+		for _, dep := range node.deps {
+			if err := visit(dag.nodes[dep]); err != nil {
+				return err
+			}
+		}
+
+		path = path[:len(path)-1]
+		state[node.name] = visited
+		order = append(order, node)
+		return nil
+	}
+
+	for _, name := range dag.names {
+		if err := visit(dag.nodes[name]); err != nil {
+			return nil, err
+		}
+	}
+
+	return order, nil
+}
+
+/*
+NodeReport is the outcome of a single node of a DAG.
+*/
+type NodeReport struct {
+	Name         string
+	Dependencies []string
+	Status       NodeStatus
+	Result       Result[any, error]
+	Started      time.Time
+	Finished     time.Time
+}
+
+/*
+DAGReport holds the outcome of every node of a DAG, in an order where every node comes
+after its dependencies.
+*/
+type DAGReport struct {
+	Nodes []NodeReport
+}
+
+/*
+Node returns the report of the node with the name.
+
+Example:
+
+	output := report.Node("c").Expect("no such node").Result
+*/
+func (report DAGReport) Node(name string) Option[NodeReport] {
+	for _, node := range report.Nodes {
+		if node.Name == name {
+			return Some(node)
+		}
+	}
+	return None[NodeReport]()
+}
+
+/*
+Err returns the errors of the nodes that failed, joined, or nil when none did.
+
+Example:
+
+	if err := report.Err(); err != nil {
+	    log.Println(err)
+	}
+*/
+func (report DAGReport) Err() error {
+	var errs []error
+
+	for _, node := range report.Nodes {
+		if node.Status == NodeFailed {
+			errs = append(errs, fmt.Errorf("node %q: %w", node.Name, node.Result.UnwrapErr()))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+/*
+DOT returns the graph in the DOT language of Graphviz, with every node colored by its
+status.
+
+Example:
+
+	os.WriteFile("run.dot", []byte(report.DOT()), 0o644)
+*/
+func (report DAGReport) DOT() string {
+	colors := map[NodeStatus]string{
+		NodeSucceeded: "palegreen",
+		NodeFailed:    "salmon",
+		NodeSkipped:   "lightgrey",
+		NodeCancelled: "khaki",
+	}
+
+	var builder strings.Builder
+	builder.WriteString("digraph dag {\n")
+
+	for _, node := range report.Nodes {
+		attributes := fmt.Sprintf("label=%q", node.Name+"\n"+node.Status.String())
+		if color, ok := colors[node.Status]; ok {
+			attributes += fmt.Sprintf(", style=filled, fillcolor=%s", color)
+		}
+		fmt.Fprintf(&builder, "\t%q [%s];\n", node.Name, attributes)
+	}
+
+	writeEdges(&builder, report.Nodes)
+	builder.WriteString("}\n")
+	return builder.String()
+}
+
+/*
+DOT returns the graph in the DOT language of Graphviz, in the order the nodes were added.
+
+Example:
+
+	fmt.Println(dag.DOT())
+*/
+func (dag *DAG) DOT() string {
+	nodes := make([]NodeReport, 0, len(dag.names))
+	for _, name := range dag.names {
+		nodes = append(nodes, NodeReport{Name: name, Dependencies: dag.nodes[name].deps})
+	}
+
+	var builder strings.Builder
+	builder.WriteString("digraph dag {\n")
+
+	for _, node := range nodes {
+		fmt.Fprintf(&builder, "\t%q;\n", node.Name)
+	}
+
+	writeEdges(&builder, nodes)
+	builder.WriteString("}\n")
+	return builder.String()
+}
+
+func writeEdges(builder *strings.Builder, nodes []NodeReport) {
+	for _, node := range nodes {
+		for _, dep := range node.Dependencies {
+			fmt.Fprintf(builder, "\t%q -> %q;\n", dep, node.Name)
+		}
+	}
+}
+
+/*
+Run builds the DAG, and runs its nodes on the executor, each as soon as the nodes it
+depends on are done. It returns the report of every node, with the errors of the nodes
+that failed, or the error of Build.
+
+Example:
+
+	report, err := dag.Run(ctx, pool)
+	total := report.Node("sum").Expect("no such node").Result.Unwrap()
+*/
+func (dag *DAG) Run(ctx context.Context, executor Executor) (DAGReport, error) {
+	if err := dag.Build(); err != nil {
+		return DAGReport{}, err
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	run := &dagRun{
+		dag:      dag,
+		ctx:      ctx,
+		cancel:   cancel,
+		executor: executor,
+		reports:  make(map[string]*NodeReport, len(dag.order)),
+		waiting:  make(map[string]int, len(dag.order)),
+		done:     make(chan string, len(dag.order)),
+	}
+
+	return run.execute()
+}
+
+/*
+dagRun is a single run of a DAG.
+*/
+type dagRun struct {
+	dag      *DAG
+	ctx      context.Context
+	cancel   context.CancelFunc
+	executor Executor
+	mu       sync.Mutex
+	reports  map[string]*NodeReport
+	waiting  map[string]int
+	done     chan string
+	running  int
+}
+
+/*
+execute starts the nodes without dependencies, and every other node once the last of its
+dependencies is done, so a node is never started before it can run.
+*/
+func (run *dagRun) execute() (DAGReport, error) {
+	for _, node := range run.dag.order {
+		run.reports[node.name] = &NodeReport{Name: node.name, Dependencies: node.deps}
+		run.waiting[node.name] = len(node.deps)
+	}
+
+	for _, node := range run.dag.order {
+		if len(node.deps) == 0 {
+			run.start(node)
+		}
+	}
+
+	// Only the nodes that run report back. Finishing one starts, or skips, its dependents,
+	// so the waiting counts and the statuses are only touched by this goroutine.
+	for run.running > 0 {
+		name := <-run.done
+		run.running--
+		run.finish(run.dag.nodes[name])
+	}
+
+	report := DAGReport{Nodes: make([]NodeReport, 0, len(run.dag.order))}
+
+	// Every node that was started has reported back by now. Their reports were written by
+	// the callbacks of their futures, which hold the lock for it.
+	run.mu.Lock()
+	for _, node := range run.dag.order {
+		report.Nodes = append(report.Nodes, *run.reports[node.name])
+	}
+	run.mu.Unlock()
+
+	if err := report.Err(); err != nil {
+		return report, err
+	}
+
+	return report, run.ctx.Err()
+}
+
+/*
+start runs the node on the executor, unless the run was stopped, or one of its
+dependencies failed and the policy is to skip its dependents.
+*/
+func (run *dagRun) start(node *dagNode) {
+	report := run.reports[node.name]
+
+	if run.ctx.Err() != nil {
+		run.settle(node, NodeCancelled, Err[any](run.ctx.Err()))
+		return
+	}
+
+	upstream := make(map[string]Result[any, error], len(node.deps))
+	for _, dep := range node.deps {
+		upstream[dep] = run.reports[dep].Result
+
+		if run.dag.policy == DAGSkipDependents && run.reports[dep].Status != NodeSucceeded {
+			run.settle(node, NodeSkipped, Err[any](ErrNodeSkipped))
+			return
+		}
+	}
+
+	run.running++
+
+	future := AsyncOn(run.ctx, run.executor, func(ctx context.Context) (any, error) {
+		run.mu.Lock()
+		report.Started = time.Now()
+		run.mu.Unlock()
+
+		return unpack(node.job.Do(ctx, upstream))
+	})
+
+	future.onComplete(func() {
+		run.mu.Lock()
+		report.Result = future.settled()
+		report.Finished = time.Now()
+		run.mu.Unlock()
+
+		run.done <- node.name
+	})
+}
+
+/*
+finish records the outcome of a node that ran, and starts the dependents that are ready.
+*/
+func (run *dagRun) finish(node *dagNode) {
+	run.mu.Lock()
+	report := run.reports[node.name]
+
+	switch {
+	case report.Result.IsOk():
+		report.Status = NodeSucceeded
+	case run.ctx.Err() != nil && errors.Is(report.Result.UnwrapErr(), run.ctx.Err()):
+		report.Status = NodeCancelled
+	default:
+		report.Status = NodeFailed
+	}
+	run.mu.Unlock()
+
+	if report.Status == NodeFailed && run.dag.policy == DAGFailFast {
+		run.cancel()
+	}
+
+	run.release(node)
+}
+
+/*
+settle records the outcome of a node that did not run, and moves on to its dependents.
+*/
+func (run *dagRun) settle(node *dagNode, status NodeStatus, result Result[any, error]) {
+	report := run.reports[node.name]
+	report.Status = status
+	report.Result = result
+	run.release(node)
+}
+
+/*
+release starts the dependents of the node that no longer wait for any other node.
+*/
+func (run *dagRun) release(node *dagNode) {
+	for _, dependent := range node.dependents {
+		run.waiting[dependent.name]--
+		if run.waiting[dependent.name] == 0 {
+			run.start(dependent)
+		}
+	}
+}
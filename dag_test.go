@@ -0,0 +1,184 @@
+package twoface
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/smartystreets/goconvey/convey"
+)
+
+// constant is a DAGJob that returns its value.
+func constant(value any) DAGJob {
+	return DAGJobFunc(func(ctx context.Context, upstream map[string]Result[any, error]) Result[any, error] {
+		return Ok[any, error](value)
+	})
+}
+
+// failing is a DAGJob that fails with errDummy.
+var failing = DAGJobFunc(func(ctx context.Context, upstream map[string]Result[any, error]) Result[any, error] {
+	return Err[any](errDummy)
+})
+
+// sum is a DAGJob that adds up the outputs of its dependencies.
+var sum = DAGJobFunc(func(ctx context.Context, upstream map[string]Result[any, error]) Result[any, error] {
+	total := 0
+	for _, result := range upstream {
+		if result.IsErr() {
+			return Err[any](result.UnwrapErr())
+		}
+		total += result.Unwrap().(int)
+	}
+	return Ok[any, error](total)
+})
+
+func TestDAG(t *testing.T) {
+	convey.Convey("DAG", t, func() {
+		ctx := context.Background()
+		pool := NewPool(ctx, 4)
+
+		status := func(report DAGReport, name string) NodeStatus {
+			return report.Node(name).Expect("no such node").Status
+		}
+
+		convey.Convey("Should pass the outputs of dependencies to their dependents", func() {
+			report, err := NewDAG(DAGFailFast).
+				Node("total", sum, "a", "b").
+				Node("a", constant(1)).
+				Node("b", constant(2)).
+				Run(ctx, pool)
+
+			convey.So(err, convey.ShouldBeNil)
+			convey.So(report.Node("total").Expect("no such node").Result.Unwrap(), convey.ShouldEqual, 3)
+			convey.So(report.Nodes[len(report.Nodes)-1].Name, convey.ShouldEqual, "total")
+		})
+
+		convey.Convey("Should run independent nodes in parallel", func() {
+			var running, most atomic.Int32
+			slow := DAGJobFunc(func(ctx context.Context, upstream map[string]Result[any, error]) Result[any, error] {
+				now := running.Add(1)
+				for {
+					seen := most.Load()
+					if now <= seen || most.CompareAndSwap(seen, now) {
+						break
+					}
+				}
+				time.Sleep(20 * time.Millisecond)
+				running.Add(-1)
+				return Ok[any, error](1)
+			})
+
+			report, err := NewDAG(DAGFailFast).
+				Node("a", slow).Node("b", slow).Node("c", slow).
+				Node("total", sum, "a", "b", "c").
+				Run(ctx, pool)
+
+			convey.So(err, convey.ShouldBeNil)
+			convey.So(most.Load(), convey.ShouldEqual, 3)
+			convey.So(report.Node("total").Expect("no such node").Result.Unwrap(), convey.ShouldEqual, 3)
+		})
+
+		convey.Convey("Should refuse to build with a cycle or an unknown dependency", func() {
+			err := NewDAG(DAGFailFast).
+				Node("a", constant(1), "c").
+				Node("b", constant(1), "a").
+				Node("c", constant(1), "b").
+				Build()
+			convey.So(errors.Is(err, ErrDAGCycle), convey.ShouldBeTrue)
+			convey.So(err.Error(), convey.ShouldContainSubstring, "a -> c -> b -> a")
+
+			_, err = NewDAG(DAGFailFast).Node("a", constant(1), "missing").Run(ctx, pool)
+			convey.So(err, convey.ShouldNotBeNil)
+
+			err = NewDAG(DAGFailFast).Node("a", constant(1)).Node("a", constant(2)).Build()
+			convey.So(err, convey.ShouldNotBeNil)
+		})
+
+		convey.Convey("Should stop everything on a failure when failing fast", func() {
+			blocked := DAGJobFunc(func(ctx context.Context, upstream map[string]Result[any, error]) Result[any, error] {
+				<-ctx.Done()
+				return Err[any](ctx.Err())
+			})
+
+			report, err := NewDAG(DAGFailFast).
+				Node("bad", failing).
+				Node("slow", blocked).
+				Node("after", constant(1), "bad").
+				Node("later", constant(1), "slow").
+				Run(ctx, pool)
+
+			convey.So(errors.Is(err, errDummy), convey.ShouldBeTrue)
+			convey.So(status(report, "bad"), convey.ShouldEqual, NodeFailed)
+			convey.So(status(report, "slow"), convey.ShouldEqual, NodeCancelled)
+			convey.So(status(report, "after"), convey.ShouldEqual, NodeCancelled)
+			convey.So(status(report, "later"), convey.ShouldEqual, NodeCancelled)
+		})
+
+		convey.Convey("Should skip the dependents of a failed node", func() {
+			report, err := NewDAG(DAGSkipDependents).
+				Node("bad", failing).
+				Node("child", constant(1), "bad").
+				Node("grandchild", constant(1), "child").
+				Node("other", constant(1)).
+				Run(ctx, pool)
+
+			convey.So(errors.Is(err, errDummy), convey.ShouldBeTrue)
+			convey.So(status(report, "child"), convey.ShouldEqual, NodeSkipped)
+			convey.So(status(report, "grandchild"), convey.ShouldEqual, NodeSkipped)
+			convey.So(errors.Is(report.Node("grandchild").Expect("no such node").Result.UnwrapErr(), ErrNodeSkipped), convey.ShouldBeTrue)
+			convey.So(status(report, "other"), convey.ShouldEqual, NodeSucceeded)
+		})
+
+		convey.Convey("Should hand failures to the dependents when continuing", func() {
+			report, err := NewDAG(DAGContinue).
+				Node("bad", failing).
+				Node("total", sum, "bad").
+				Node("recovered", DAGJobFunc(func(ctx context.Context, upstream map[string]Result[any, error]) Result[any, error] {
+					return Ok[any, error](upstream["bad"].IsErr())
+				}), "bad").
+				Run(ctx, pool)
+
+			convey.So(err, convey.ShouldNotBeNil)
+			convey.So(status(report, "total"), convey.ShouldEqual, NodeFailed)
+			convey.So(report.Node("recovered").Expect("no such node").Result.Unwrap(), convey.ShouldEqual, true)
+		})
+
+		convey.Convey("Should describe itself in DOT", func() {
+			dag := NewDAG(DAGSkipDependents).
+				Node("a", failing).
+				Node("b", constant(1), "a")
+
+			dot := dag.DOT()
+			convey.So(dot, convey.ShouldStartWith, "digraph dag {")
+			convey.So(dot, convey.ShouldContainSubstring, `"a" -> "b";`)
+
+			report, _ := dag.Run(ctx, pool)
+			dot = report.DOT()
+			convey.So(dot, convey.ShouldContainSubstring, `"a" -> "b";`)
+			convey.So(dot, convey.ShouldContainSubstring, "fillcolor=salmon")
+			convey.So(strings.Count(dot, "fillcolor=lightgrey"), convey.ShouldEqual, 1)
+		})
+
+		convey.Reset(func() {
+			pool.Shutdown()
+		})
+	})
+}
+
+func BenchmarkDAG(b *testing.B) {
+	ctx := context.Background()
+	pool := NewPool(ctx, 4)
+	defer pool.Shutdown()
+
+	dag := NewDAG(DAGFailFast).
+		Node("a", constant(1)).
+		Node("b", constant(2)).
+		Node("total", sum, "a", "b")
+
+	for i := 0; i < b.N; i++ {
+		dag.Run(ctx, pool)
+	}
+}
@@ -0,0 +1,504 @@
+package twoface
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+/*
+DeadLetter is a job that failed every attempt of its Retrier, kept so it can be inspected,
+and replayed once whatever made it fail is fixed.
+*/
+type DeadLetter struct {
+	// ID is set by the sink that holds the letter.
+	ID uint64
+	// Envelope holds the job as it was submitted to the pool.
+	Envelope *Envelope
+	Attempts []Attempt
+	At       time.Time
+}
+
+/*
+DeadLetterSink stores the jobs that a Pool gave up on.
+
+Example:
+
+	letters := NewMemoryDeadLetters()
+	pool := NewPool(ctx, 4, WithDeadLetters(letters))
+*/
+type DeadLetterSink interface {
+	// Put stores a letter, and returns its ID.
+	Put(letter DeadLetter) (uint64, error)
+	// List returns the letters in the order they were stored.
+	List() ([]DeadLetter, error)
+	// Remove deletes a letter.
+	Remove(id uint64) error
+}
+
+/*
+WithDeadLetters makes the workers of the pool store the jobs that fail with a RetryError
+in the sink, together with the error of every attempt, instead of only printing the error.
+
+Example:
+
+	pool := NewPool(ctx, 4, WithDeadLetters(NewMemoryDeadLetters()))
+	pool.Submit(NewRetriableJob(ctx, &EmailJob{To: "someone@example.com"}))
+*/
+func WithDeadLetters(sink DeadLetterSink) PoolOption {
+	return func(pool *Pool) {
+		pool.deadLetters = sink
+	}
+}
+
+/*
+InspectDeadLetters returns the letters in the sink that the filter accepts, or all of them
+when the filter is nil.
+
+Example:
+
+	letters, err := InspectDeadLetters(sink, func(letter DeadLetter) bool {
+	    return letter.Envelope.Type == "email"
+	})
+*/
+func InspectDeadLetters(sink DeadLetterSink, filter func(DeadLetter) bool) ([]DeadLetter, error) {
+	letters, err := sink.List()
+	if err != nil {
+		return nil, err
+	}
+
+	if filter == nil {
+		return letters, nil
+	}
+
+	accepted := letters[:0]
+	for _, letter := range letters {
+		if filter(letter) {
+			accepted = append(accepted, letter)
+		}
+	}
+
+	return accepted, nil
+}
+
+/*
+ReplayDeadLetters submits the jobs of the letters that the filter accepts to the pool, as
+they were submitted the first time, and removes them from the sink. It returns the number
+of jobs that were submitted. A job that was submitted as a RetriableJob is retried again,
+with the default Retrier.
+
+Example:
+
+	replayed, err := ReplayDeadLetters(pool, sink, nil)
+*/
+func ReplayDeadLetters(pool *Pool, sink DeadLetterSink, filter func(DeadLetter) bool) (int, error) {
+	letters, err := InspectDeadLetters(sink, filter)
+	if err != nil {
+		return 0, err
+	}
+
+	for idx, letter := range letters {
+		if letter.Envelope.Headers[retriableHeader] != "" {
+			pool.Submit(NewRetriableJob(pool.ctx, letter.Envelope))
+		} else {
+			pool.Submit(letter.Envelope)
+		}
+
+		if err := sink.Remove(letter.ID); err != nil {
+			return idx + 1, err
+		}
+	}
+
+	return len(letters), nil
+}
+
+/*
+PurgeDeadLetters removes the letters that the filter accepts from the sink, and returns
+how many were removed.
+
+Example:
+
+	purged, err := PurgeDeadLetters(sink, func(letter DeadLetter) bool {
+	    return time.Since(letter.At) > 7*24*time.Hour
+	})
+*/
+func PurgeDeadLetters(sink DeadLetterSink, filter func(DeadLetter) bool) (int, error) {
+	letters, err := InspectDeadLetters(sink, filter)
+	if err != nil {
+		return 0, err
+	}
+
+	for idx, letter := range letters {
+		if err := sink.Remove(letter.ID); err != nil {
+			return idx, err
+		}
+	}
+
+	return len(letters), nil
+}
+
+/*
+newDeadLetter turns a job that a worker gave up on into a letter.
+*/
+func newDeadLetter(job Job, exhausted *RetryError, worker int) DeadLetter {
+	attempts := make([]Attempt, len(exhausted.Attempts))
+	for idx, attempt := range exhausted.Attempts {
+		attempt.Worker = worker
+		attempts[idx] = attempt
+	}
+
+	return DeadLetter{Envelope: submittedEnvelope(job), Attempts: attempts, At: time.Now()}
+}
+
+/*
+retriableHeader marks the Envelope of a letter whose job was submitted as a RetriableJob,
+so a replay retries it again.
+*/
+const retriableHeader = "twoface-retriable"
+
+/*
+submittedEnvelope finds the job that was submitted to the pool behind the wrappers the
+pool adds to it, in an Envelope. A RetriableJob is replaced by the job it retries, which,
+unlike the RetriableJob, can go through a JobRegistry, and the Envelope is marked so a
+replay retries it again.
+*/
+func submittedEnvelope(job Job) *Envelope {
+	envelope, retriable := unwrapSubmitted(job)
+	if !retriable {
+		return envelope
+	}
+
+	marked := *envelope
+	marked.Headers = make(map[string]string, len(envelope.Headers)+1)
+	for key, value := range envelope.Headers {
+		marked.Headers[key] = value
+	}
+	marked.Headers[retriableHeader] = "true"
+
+	return &marked
+}
+
+/*
+unwrapSubmitted removes the wrappers from the job, and reports whether one of them was a
+RetriableJob.
+*/
+func unwrapSubmitted(job Job) (*Envelope, bool) {
+	retriable := false
+
+	for {
+		switch wrapped := job.(type) {
+		case trackedJob:
+			job = wrapped.job
+		case keyedJob:
+			job = wrapped.job
+		case RetriableJob:
+			job = wrapped.fn
+			retriable = true
+		case deliveredJob:
+			if envelope, ok := wrapped.delivery.Job.(*Envelope); ok {
+				return envelope, retriable
+			}
+			return &Envelope{ID: wrapped.delivery.ID, Attempts: wrapped.delivery.Attempts, Job: wrapped.delivery.Job}, retriable
+		case *Envelope:
+			return wrapped, retriable
+		default:
+			return &Envelope{Job: job}, retriable
+		}
+	}
+}
+
+/*
+MarshalJSON writes the error of the attempt with the current ErrorCodec.
+*/
+func (attempt Attempt) MarshalJSON() ([]byte, error) {
+	frame := attemptFrame{Worker: attempt.Worker, Started: attempt.Started, Finished: attempt.Finished}
+
+	if attempt.Err != nil {
+		data, err := CurrentErrorCodec().EncodeError(attempt.Err)
+		if err != nil {
+			return nil, err
+		}
+		frame.Err = data
+	}
+
+	return json.Marshal(frame)
+}
+
+/*
+UnmarshalJSON rebuilds the error of the attempt with the current ErrorCodec.
+*/
+func (attempt *Attempt) UnmarshalJSON(data []byte) error {
+	var frame attemptFrame
+	if err := json.Unmarshal(data, &frame); err != nil {
+		return err
+	}
+
+	*attempt = Attempt{Worker: frame.Worker, Started: frame.Started, Finished: frame.Finished}
+
+	if len(frame.Err) > 0 {
+		decoded, err := CurrentErrorCodec().DecodeError(frame.Err)
+		if err != nil {
+			return err
+		}
+		attempt.Err = decoded
+	}
+
+	return nil
+}
+
+type attemptFrame struct {
+	Worker   int             `json:"worker"`
+	Started  time.Time       `json:"started"`
+	Finished time.Time       `json:"finished"`
+	Err      json.RawMessage `json:"error,omitempty"`
+}
+
+/*
+MemoryDeadLetters is a DeadLetterSink that keeps its letters in memory.
+
+Example:
+
+	letters := NewMemoryDeadLetters()
+*/
+type MemoryDeadLetters struct {
+	mu      sync.Mutex
+	letters []DeadLetter
+	nextID  uint64
+}
+
+/*
+NewMemoryDeadLetters creates an empty MemoryDeadLetters.
+
+Example:
+
+	letters := NewMemoryDeadLetters()
+*/
+func NewMemoryDeadLetters() *MemoryDeadLetters {
+	return &MemoryDeadLetters{}
+}
+
+/*
+Put keeps the letter, and returns the ID it was given.
+*/
+func (sink *MemoryDeadLetters) Put(letter DeadLetter) (uint64, error) {
+	sink.mu.Lock()
+	defer sink.mu.Unlock()
+
+	sink.nextID++
+	letter.ID = sink.nextID
+	sink.letters = append(sink.letters, letter)
+
+	return letter.ID, nil
+}
+
+/*
+List returns a copy of the letters, in the order they were put.
+*/
+func (sink *MemoryDeadLetters) List() ([]DeadLetter, error) {
+	sink.mu.Lock()
+	defer sink.mu.Unlock()
+
+	return append([]DeadLetter{}, sink.letters...), nil
+}
+
+/*
+Remove forgets the letter with the ID, or returns an error if there is none.
+*/
+func (sink *MemoryDeadLetters) Remove(id uint64) error {
+	sink.mu.Lock()
+	defer sink.mu.Unlock()
+
+	for idx, letter := range sink.letters {
+		if letter.ID == id {
+			sink.letters = append(sink.letters[:idx], sink.letters[idx+1:]...)
+			return nil
+		}
+	}
+
+	return fmt.Errorf("dead letter %d does not exist", id)
+}
+
+/*
+FileDeadLetters is a DeadLetterSink that keeps every letter in a file of its own, so they
+survive restarts. Jobs are stored with a JobRegistry, so only jobs of a registered type
+can be dead-lettered; to keep a job that retries itself, give it a registered type whose
+Do uses a Retrier, rather than wrapping it in a RetriableJob.
+
+Example:
+
+	letters, err := OpenFileDeadLetters("dead-letters", registry)
+	pool := NewPool(ctx, 4, WithDeadLetters(letters))
+*/
+type FileDeadLetters struct {
+	dir      string
+	registry *JobRegistry
+	mu       sync.Mutex
+	nextID   uint64
+}
+
+/*
+fileLetter is the form a DeadLetter takes on disk.
+*/
+type fileLetter struct {
+	ID       uint64          `json:"id"`
+	Envelope json.RawMessage `json:"envelope"`
+	Attempts []Attempt       `json:"attempts"`
+	At       time.Time       `json:"at"`
+}
+
+const letterExtension = ".json"
+
+/*
+OpenFileDeadLetters opens the sink in the directory, creating it when needed.
+
+Example:
+
+	letters, err := OpenFileDeadLetters("dead-letters", registry)
+*/
+func OpenFileDeadLetters(dir string, registry *JobRegistry) (*FileDeadLetters, error) {
+	if registry == nil {
+		return nil, errors.New("a FileDeadLetters needs a JobRegistry")
+	}
+
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+
+	sink := &FileDeadLetters{dir: dir, registry: registry}
+
+	ids, err := sink.ids()
+	if err != nil {
+		return nil, err
+	}
+
+	if len(ids) > 0 {
+		sink.nextID = ids[len(ids)-1]
+	}
+
+	return sink, nil
+}
+
+/*
+Put writes the letter to a temporary file first, syncs it, and then renames it, so a crash
+never leaves a letter half written.
+*/
+func (sink *FileDeadLetters) Put(letter DeadLetter) (uint64, error) {
+	envelope, err := sink.registry.MarshalEnvelope(letter.Envelope)
+	if err != nil {
+		return 0, err
+	}
+
+	sink.mu.Lock()
+	defer sink.mu.Unlock()
+
+	id := sink.nextID + 1
+
+	data, err := json.Marshal(fileLetter{ID: id, Envelope: envelope, Attempts: letter.Attempts, At: letter.At})
+	if err != nil {
+		return 0, err
+	}
+
+	temp := filepath.Join(sink.dir, ".tmp-"+strconv.FormatUint(id, 10))
+
+	file, err := os.Create(temp)
+	if err != nil {
+		return 0, err
+	}
+
+	_, err = file.Write(data)
+	if err = errors.Join(err, file.Sync(), file.Close()); err != nil {
+		os.Remove(temp)
+		return 0, err
+	}
+
+	if err := os.Rename(temp, sink.path(id)); err != nil {
+		os.Remove(temp)
+		return 0, err
+	}
+
+	sink.nextID = id
+	return id, nil
+}
+
+/*
+List reads every letter from the directory, in the order they were put. A letter that
+cannot be read fails the whole List, so it is never overlooked.
+*/
+func (sink *FileDeadLetters) List() ([]DeadLetter, error) {
+	sink.mu.Lock()
+	defer sink.mu.Unlock()
+
+	ids, err := sink.ids()
+	if err != nil {
+		return nil, err
+	}
+
+	letters := make([]DeadLetter, 0, len(ids))
+
+	for _, id := range ids {
+		data, err := os.ReadFile(sink.path(id))
+		if err != nil {
+			return nil, err
+		}
+
+		var stored fileLetter
+		if err := json.Unmarshal(data, &stored); err != nil {
+			return nil, fmt.Errorf("cannot read dead letter %d: %w", id, err)
+		}
+
+		envelope, err := sink.registry.UnmarshalEnvelope(stored.Envelope)
+		if err != nil {
+			return nil, fmt.Errorf("cannot read dead letter %d: %w", id, err)
+		}
+
+		letters = append(letters, DeadLetter{ID: id, Envelope: envelope, Attempts: stored.Attempts, At: stored.At})
+	}
+
+	return letters, nil
+}
+
+/*
+Remove deletes the file of the letter with the ID, or returns an error if there is none.
+*/
+func (sink *FileDeadLetters) Remove(id uint64) error {
+	sink.mu.Lock()
+	defer sink.mu.Unlock()
+
+	if err := os.Remove(sink.path(id)); err != nil {
+		return fmt.Errorf("dead letter %d does not exist: %w", id, err)
+	}
+
+	return nil
+}
+
+func (sink *FileDeadLetters) path(id uint64) string {
+	return filepath.Join(sink.dir, fmt.Sprintf("%020d%s", id, letterExtension))
+}
+
+/*
+ids returns the IDs of the letters in the directory, in order.
+*/
+func (sink *FileDeadLetters) ids() ([]uint64, error) {
+	paths, err := filepath.Glob(filepath.Join(sink.dir, "*"+letterExtension))
+	if err != nil {
+		return nil, err
+	}
+
+	var ids []uint64
+	for _, path := range paths {
+		id, err := strconv.ParseUint(strings.TrimSuffix(filepath.Base(path), letterExtension), 10, 64)
+		if err == nil {
+			ids = append(ids, id)
+		}
+	}
+
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	return ids, nil
+}
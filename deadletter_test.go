@@ -0,0 +1,196 @@
+package twoface
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/smartystreets/goconvey/convey"
+)
+
+// brittleJob fails every attempt of its retrier while brittleFailing is set.
+type brittleJob struct {
+	Name string
+}
+
+var brittleFailing atomic.Bool
+
+func (job *brittleJob) Do() Result[any, error] {
+	return NewFibonacciWithUnit(2, time.Millisecond).Do(JobFunc(func() Result[any, error] {
+		if brittleFailing.Load() {
+			return Err[any](fmt.Errorf("%s failed", job.Name))
+		}
+		return (&recordedJob{Name: job.Name}).Do()
+	}))
+}
+
+// fragileJob fails once while brittleFailing is set, leaving retries to a RetriableJob.
+type fragileJob struct {
+	Name string
+}
+
+func (job *fragileJob) Do() Result[any, error] {
+	if brittleFailing.Load() {
+		return Err[any](fmt.Errorf("%s failed", job.Name))
+	}
+	return (&recordedJob{Name: job.Name}).Do()
+}
+
+func TestDeadLetters(t *testing.T) {
+	convey.Convey("DeadLetters", t, func() {
+		ctx := context.Background()
+		registry := NewJobRegistry().
+			Register("brittle", func() Job { return &brittleJob{} }).
+			Register("fragile", func() Job { return &fragileJob{} })
+		brittleFailing.Store(true)
+		resetRecorder()
+
+		convey.Convey("A Retrier should give up with the errors of all attempts", func() {
+			attempts := 0
+			result := NewFibonacciWithUnit(2, time.Millisecond).Do(JobFunc(func() Result[any, error] {
+				attempts++
+				return Err[any](errDummy)
+			}))
+
+			var exhausted *RetryError
+			convey.So(errors.As(result.UnwrapErr(), &exhausted), convey.ShouldBeTrue)
+			convey.So(errors.Is(result.UnwrapErr(), ErrMaxRetries), convey.ShouldBeTrue)
+			convey.So(errors.Is(result.UnwrapErr(), errDummy), convey.ShouldBeTrue)
+			convey.So(attempts, convey.ShouldEqual, 3)
+			convey.So(exhausted.Attempts, convey.ShouldHaveLength, 3)
+			convey.So(exhausted.Attempts[1].Started.After(exhausted.Attempts[0].Finished), convey.ShouldBeTrue)
+		})
+
+		convey.Convey("A Pool should store the jobs that failed every attempt", func() {
+			sink := NewMemoryDeadLetters()
+			pool := NewPool(ctx, 1, WithDeadLetters(sink))
+			pool.Submit(&brittleJob{Name: "bare"})
+			pool.Submit(&Envelope{Headers: map[string]string{"tenant": "acme"}, Job: &brittleJob{Name: "sealed"}})
+			pool.Submit(DummyJob{Err[any](errDummy)})
+			pool.Shutdown()
+
+			letters, err := sink.List()
+			convey.So(err, convey.ShouldBeNil)
+			convey.So(letters, convey.ShouldHaveLength, 2)
+
+			convey.So(letters[0].Envelope.Job, convey.ShouldResemble, &brittleJob{Name: "bare"})
+			convey.So(letters[0].Attempts, convey.ShouldHaveLength, 3)
+			convey.So(letters[0].Attempts[2].Err.Error(), convey.ShouldEqual, "bare failed")
+			convey.So(letters[0].Attempts[2].Worker, convey.ShouldEqual, 0)
+			convey.So(letters[0].At.IsZero(), convey.ShouldBeFalse)
+
+			convey.So(letters[1].Envelope.Headers["tenant"], convey.ShouldEqual, "acme")
+		})
+
+		convey.Convey("Should replay and purge letters", func() {
+			sink := NewMemoryDeadLetters()
+			pool := NewPool(ctx, 2, WithDeadLetters(sink))
+			for _, name := range []string{"a", "b", "c"} {
+				pool.Submit(&brittleJob{Name: name})
+			}
+			pool.Shutdown()
+
+			isA := func(letter DeadLetter) bool { return letter.Envelope.Job.(*brittleJob).Name == "a" }
+
+			letters, _ := InspectDeadLetters(sink, isA)
+			convey.So(letters, convey.ShouldHaveLength, 1)
+
+			purged, err := PurgeDeadLetters(sink, isA)
+			convey.So(err, convey.ShouldBeNil)
+			convey.So(purged, convey.ShouldEqual, 1)
+
+			brittleFailing.Store(false)
+			pool = NewPool(ctx, 2, WithDeadLetters(sink))
+			replayed, err := ReplayDeadLetters(pool, sink, nil)
+			pool.Shutdown()
+
+			convey.So(err, convey.ShouldBeNil)
+			convey.So(replayed, convey.ShouldEqual, 2)
+			convey.So(recordedNames(), convey.ShouldHaveLength, 2)
+			convey.So(recordedNames(), convey.ShouldNotContain, "a")
+
+			letters, _ = sink.List()
+			convey.So(letters, convey.ShouldBeEmpty)
+		})
+
+		convey.Convey("FileDeadLetters should keep letters across restarts", func() {
+			dir := t.TempDir()
+			sink, err := OpenFileDeadLetters(dir, registry)
+			convey.So(err, convey.ShouldBeNil)
+
+			pool := NewPool(ctx, 1, WithDeadLetters(sink))
+			pool.Submit(&Envelope{Headers: map[string]string{"trace": "abc"}, Job: &brittleJob{Name: "durable"}})
+			pool.Submit(JobFunc((&brittleJob{Name: "unregistered"}).Do))
+			pool.Shutdown()
+
+			sink, err = OpenFileDeadLetters(dir, registry)
+			convey.So(err, convey.ShouldBeNil)
+
+			letters, err := sink.List()
+			convey.So(err, convey.ShouldBeNil)
+			convey.So(letters, convey.ShouldHaveLength, 1)
+			convey.So(letters[0].Envelope.Job, convey.ShouldResemble, &brittleJob{Name: "durable"})
+			convey.So(letters[0].Envelope.Headers["trace"], convey.ShouldEqual, "abc")
+			convey.So(letters[0].Attempts, convey.ShouldHaveLength, 3)
+			convey.So(letters[0].Attempts[0].Err.Error(), convey.ShouldEqual, "durable failed")
+
+			next, _ := sink.Put(DeadLetter{Envelope: &Envelope{Job: &brittleJob{}}})
+			convey.So(next, convey.ShouldEqual, letters[0].ID+1)
+
+			brittleFailing.Store(false)
+			pool = NewPool(ctx, 1)
+			replayed, err := ReplayDeadLetters(pool, sink, nil)
+			pool.Shutdown()
+
+			convey.So(err, convey.ShouldBeNil)
+			convey.So(replayed, convey.ShouldEqual, 2)
+			convey.So(recordedNames(), convey.ShouldContain, "durable")
+
+			letters, _ = sink.List()
+			convey.So(letters, convey.ShouldBeEmpty)
+			convey.So(sink.Remove(next), convey.ShouldNotBeNil)
+		})
+
+		convey.Convey("FileDeadLetters should keep the job of a RetriableJob, and retry it on replay", func() {
+			dir := t.TempDir()
+			sink, err := OpenFileDeadLetters(dir, registry)
+			convey.So(err, convey.ShouldBeNil)
+
+			pool := NewPool(ctx, 1, WithDeadLetters(sink))
+			pool.Submit(NewRetriableJobWithRetrier(ctx, &fragileJob{Name: "retried"}, NewFibonacciWithUnit(2, time.Millisecond)))
+			pool.Shutdown()
+
+			sink, err = OpenFileDeadLetters(dir, registry)
+			convey.So(err, convey.ShouldBeNil)
+
+			letters, err := sink.List()
+			convey.So(err, convey.ShouldBeNil)
+			convey.So(letters, convey.ShouldHaveLength, 1)
+			convey.So(letters[0].Envelope.Job, convey.ShouldResemble, &fragileJob{Name: "retried"})
+			convey.So(letters[0].Attempts, convey.ShouldHaveLength, 3)
+			convey.So(letters[0].Attempts[2].Err.Error(), convey.ShouldEqual, "retried failed")
+
+			brittleFailing.Store(false)
+			pool = NewPool(ctx, 1)
+			replayed, err := ReplayDeadLetters(pool, sink, nil)
+			pool.Shutdown()
+
+			convey.So(err, convey.ShouldBeNil)
+			convey.So(replayed, convey.ShouldEqual, 1)
+			convey.So(recordedNames(), convey.ShouldResemble, []string{"retried"})
+		})
+	})
+}
+
+func BenchmarkDeadLetters(b *testing.B) {
+	sink := NewMemoryDeadLetters()
+	letter := DeadLetter{Envelope: &Envelope{Job: &recordedJob{}}}
+
+	for i := 0; i < b.N; i++ {
+		id, _ := sink.Put(letter)
+		sink.Remove(id)
+	}
+}
@@ -1,5 +1,11 @@
 package twoface
 
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
 /*
 Either represents a value that can be one of two possible types.
 
@@ -96,3 +102,272 @@ func (e Either[L, R]) UnwrapRight() R {
 	}
 	return *e.right
 }
+
+/*
+MarshalJSON encodes the Either as a tagged object, either {"left":...} or
+{"right":...}, depending on which side holds the value.
+
+Example:
+
+	data, _ := json.Marshal(Left[int, string](42))
+	fmt.Println(string(data)) // Output: {"left":42}
+*/
+func (e Either[L, R]) MarshalJSON() ([]byte, error) {
+	switch {
+	case e.IsLeft():
+		return json.Marshal(map[string]L{"left": *e.left})
+	case e.IsRight():
+		return json.Marshal(map[string]R{"right": *e.right})
+	}
+
+	return nil, errors.New("cannot marshal an empty `Either`")
+}
+
+/*
+UnmarshalJSON decodes an Either from a tagged object, as produced by MarshalJSON.
+Exactly one of the "left" or "right" keys must be present.
+
+Example:
+
+	var either Either[int, string]
+	_ = json.Unmarshal([]byte(`{"right":"hello"}`), &either)
+	fmt.Println(either.UnwrapRight()) // Output: hello
+*/
+func (e *Either[L, R]) UnmarshalJSON(data []byte) error {
+	var tagged map[string]json.RawMessage
+
+	if err := json.Unmarshal(data, &tagged); err != nil {
+		return err
+	}
+
+	left, isLeft := tagged["left"]
+	right, isRight := tagged["right"]
+
+	if len(tagged) != 1 || isLeft == isRight {
+		return fmt.Errorf("cannot unmarshal %s into an `Either`: expected exactly one of \"left\" or \"right\"", data)
+	}
+
+	if isLeft {
+		var value L
+		if err := json.Unmarshal(left, &value); err != nil {
+			return err
+		}
+		*e = Left[L, R](value)
+		return nil
+	}
+
+	var value R
+	if err := json.Unmarshal(right, &value); err != nil {
+		return err
+	}
+	*e = Right[L](value)
+	return nil
+}
+
+/*
+LeftOr returns the left value, or the given default if the Either contains a right value.
+
+Example:
+
+	either := Right[int, string]("hello")
+	fmt.Println(either.LeftOr(99)) // Output: 99
+*/
+func (e Either[L, R]) LeftOr(defaultValue L) L {
+	if e.IsLeft() {
+		return *e.left
+	}
+	return defaultValue
+}
+
+/*
+RightOr returns the right value, or the given default if the Either contains a left value.
+
+Example:
+
+	either := Left[int, string](42)
+	fmt.Println(either.RightOr("default")) // Output: default
+*/
+func (e Either[L, R]) RightOr(defaultValue R) R {
+	if e.IsRight() {
+		return *e.right
+	}
+	return defaultValue
+}
+
+/*
+LeftOption returns the left value as Some, or None if the Either contains a right value.
+
+Example:
+
+	either := Left[int, string](42)
+	fmt.Println(either.LeftOption().UnwrapOr(0)) // Output: 42
+*/
+func (e Either[L, R]) LeftOption() Option[L] {
+	if e.IsLeft() {
+		return Some(*e.left)
+	}
+	return None[L]()
+}
+
+/*
+RightOption returns the right value as Some, or None if the Either contains a left value.
+
+Example:
+
+	either := Right[int, string]("hello")
+	fmt.Println(either.RightOption().UnwrapOr("")) // Output: hello
+*/
+func (e Either[L, R]) RightOption() Option[R] {
+	if e.IsRight() {
+		return Some(*e.right)
+	}
+	return None[R]()
+}
+
+/*
+Swap turns a left value into a right value, and the other way around.
+
+Example:
+
+	either := Left[int, string](42).Swap()
+	fmt.Println(either.UnwrapRight()) // Output: 42
+*/
+func (e Either[L, R]) Swap() Either[R, L] {
+	if e.IsLeft() {
+		return Right[R](*e.left)
+	}
+	if e.IsRight() {
+		return Left[R, L](*e.right)
+	}
+	return Either[R, L]{}
+}
+
+/*
+MapRight transforms the right value using the provided function, leaving a left value untouched.
+Either is right-biased, so this is the regular functor map.
+
+Example:
+
+	either := MapRight(Right[error, int](21), func(v int) string { return strconv.Itoa(v * 2) })
+	fmt.Println(either.UnwrapRight()) // Output: 42
+*/
+func MapRight[L any, R any, U any](e Either[L, R], f func(R) U) Either[L, U] {
+	if e.IsRight() {
+		return Right[L](f(*e.right))
+	}
+	return Either[L, U]{left: e.left}
+}
+
+/*
+FlatMapRight transforms the right value using the provided function that returns an Either,
+leaving a left value untouched.
+
+Example:
+
+	either := FlatMapRight(Right[error, int](42), func(v int) Either[error, string] {
+	    return Right[error](strconv.Itoa(v))
+	})
+	fmt.Println(either.UnwrapRight()) // Output: 42
+*/
+func FlatMapRight[L any, R any, U any](e Either[L, R], f func(R) Either[L, U]) Either[L, U] {
+	if e.IsRight() {
+		return f(*e.right)
+	}
+	return Either[L, U]{left: e.left}
+}
+
+/*
+MapLeft transforms the left value using the provided function, leaving a right value untouched.
+
+Example:
+
+	either := MapLeft(Left[int, string](42), func(v int) int { return v + 1 })
+	fmt.Println(either.UnwrapLeft()) // Output: 43
+*/
+func MapLeft[L any, R any, U any](e Either[L, R], f func(L) U) Either[U, R] {
+	if e.IsLeft() {
+		return Left[U, R](f(*e.left))
+	}
+	return Either[U, R]{right: e.right}
+}
+
+/*
+BiMap transforms whichever value the Either contains, using the matching function.
+
+Example:
+
+	either := BiMap(Left[int, string](42),
+	    func(v int) string { return strconv.Itoa(v) },
+	    func(v string) int { return len(v) },
+	)
+	fmt.Println(either.UnwrapLeft()) // Output: 42
+*/
+func BiMap[L any, R any, L2 any, R2 any](e Either[L, R], onLeft func(L) L2, onRight func(R) R2) Either[L2, R2] {
+	if e.IsLeft() {
+		return Left[L2, R2](onLeft(*e.left))
+	}
+	if e.IsRight() {
+		return Right[L2](onRight(*e.right))
+	}
+	return Either[L2, R2]{}
+}
+
+/*
+Fold collapses the Either into a single value, using the function that matches its side.
+It panics on an empty Either, which can only be created as a zero value.
+
+Example:
+
+	length := Fold(Right[int, string]("hello"),
+	    func(v int) int { return v },
+	    func(v string) int { return len(v) },
+	)
+	fmt.Println(length) // Output: 5
+*/
+func Fold[L any, R any, U any](e Either[L, R], onLeft func(L) U, onRight func(R) U) U {
+	if e.IsLeft() {
+		return onLeft(*e.left)
+	}
+	if e.IsRight() {
+		return onRight(*e.right)
+	}
+	panic("called `Fold` on an empty `Either`")
+}
+
+/*
+EitherToResult converts an Either with an error on the left into a Result,
+where the right value becomes Ok and the left value becomes Err.
+
+Example:
+
+	result := EitherToResult(Right[error, int](42))
+	fmt.Println(result.Unwrap()) // Output: 42
+*/
+func EitherToResult[E error, T any](e Either[E, T]) Result[T, E] {
+	if e.IsLeft() {
+		return Err[T](*e.left)
+	}
+	if e.IsRight() {
+		return Ok[T, E](*e.right)
+	}
+	return Result[T, E]{}
+}
+
+/*
+ResultToEither converts a Result into an Either, where Ok becomes the right value
+and Err becomes the left value.
+
+Example:
+
+	either := ResultToEither(Ok[int, error](42))
+	fmt.Println(either.UnwrapRight()) // Output: 42
+*/
+func ResultToEither[T any, E error](r Result[T, E]) Either[E, T] {
+	if r.IsErr() {
+		return Left[E, T](*r.err)
+	}
+	if r.IsOk() {
+		return Right[E](*r.ok)
+	}
+	return Either[E, T]{}
+}
@@ -1,6 +1,8 @@
 package twoface
 
 import (
+	"encoding/json"
+	"errors"
 	"testing"
 
 	"github.com/smartystreets/goconvey/convey"
@@ -39,6 +41,131 @@ func TestEither(t *testing.T) {
 			either := Left[int, string](1)
 			convey.So(func() { either.UnwrapRight() }, convey.ShouldPanicWith, "called `UnwrapRight` on a `Left` value")
 		})
+
+		convey.Convey("MarshalJSON", func() {
+			left, err := json.Marshal(Left[int, string](42))
+			convey.So(err, convey.ShouldBeNil)
+			convey.So(string(left), convey.ShouldEqual, `{"left":42}`)
+
+			right, err := json.Marshal(Right[int]("hello"))
+			convey.So(err, convey.ShouldBeNil)
+			convey.So(string(right), convey.ShouldEqual, `{"right":"hello"}`)
+
+			_, err = json.Marshal(Either[int, string]{})
+			convey.So(err, convey.ShouldNotBeNil)
+		})
+
+		convey.Convey("UnmarshalJSON", func() {
+			var either Either[int, string]
+			convey.So(json.Unmarshal([]byte(`{"left":42}`), &either), convey.ShouldBeNil)
+			convey.So(either.UnwrapLeft(), convey.ShouldEqual, 42)
+
+			convey.So(json.Unmarshal([]byte(`{"right":"hello"}`), &either), convey.ShouldBeNil)
+			convey.So(either.IsLeft(), convey.ShouldBeFalse)
+			convey.So(either.UnwrapRight(), convey.ShouldEqual, "hello")
+		})
+
+		convey.Convey("UnmarshalJSON with an invalid tag", func() {
+			var either Either[int, string]
+			convey.So(json.Unmarshal([]byte(`{"left":42,"right":"hello"}`), &either), convey.ShouldNotBeNil)
+			convey.So(json.Unmarshal([]byte(`{"middle":42}`), &either), convey.ShouldNotBeNil)
+			convey.So(json.Unmarshal([]byte(`{"left":"hello"}`), &either), convey.ShouldNotBeNil)
+		})
+
+		convey.Convey("LeftOr and RightOr", func() {
+			convey.So(Left[int, string](42).LeftOr(99), convey.ShouldEqual, 42)
+			convey.So(Right[int]("hello").LeftOr(99), convey.ShouldEqual, 99)
+			convey.So(Right[int]("hello").RightOr("default"), convey.ShouldEqual, "hello")
+			convey.So(Left[int, string](42).RightOr("default"), convey.ShouldEqual, "default")
+		})
+
+		convey.Convey("LeftOption and RightOption", func() {
+			convey.So(Left[int, string](42).LeftOption().UnwrapOr(0), convey.ShouldEqual, 42)
+			convey.So(Left[int, string](42).RightOption().IsNone(), convey.ShouldBeTrue)
+			convey.So(Right[int]("hello").RightOption().UnwrapOr(""), convey.ShouldEqual, "hello")
+			convey.So(Right[int]("hello").LeftOption().IsNone(), convey.ShouldBeTrue)
+		})
+
+		convey.Convey("Swap", func() {
+			convey.So(Left[int, string](42).Swap().UnwrapRight(), convey.ShouldEqual, 42)
+			convey.So(Right[int]("hello").Swap().UnwrapLeft(), convey.ShouldEqual, "hello")
+		})
+
+		convey.Convey("MapRight, FlatMapRight and MapLeft", func() {
+			double := func(v int) int { return v * 2 }
+			convey.So(MapRight(Right[string](21), double).UnwrapRight(), convey.ShouldEqual, 42)
+			convey.So(MapRight(Left[string, int]("err"), double).UnwrapLeft(), convey.ShouldEqual, "err")
+			convey.So(MapLeft(Left[int, string](21), double).UnwrapLeft(), convey.ShouldEqual, 42)
+			convey.So(MapLeft(Right[int]("hello"), double).UnwrapRight(), convey.ShouldEqual, "hello")
+
+			halve := func(v int) Either[string, int] {
+				if v%2 != 0 {
+					return Left[string, int]("odd")
+				}
+				return Right[string](v / 2)
+			}
+			convey.So(FlatMapRight(Right[string](84), halve).UnwrapRight(), convey.ShouldEqual, 42)
+			convey.So(FlatMapRight(Right[string](43), halve).UnwrapLeft(), convey.ShouldEqual, "odd")
+			convey.So(FlatMapRight(Left[string, int]("err"), halve).UnwrapLeft(), convey.ShouldEqual, "err")
+		})
+
+		convey.Convey("BiMap and Fold", func() {
+			length := func(v string) int { return len(v) }
+			negate := func(v int) int { return -v }
+			convey.So(BiMap(Left[int, string](42), negate, length).UnwrapLeft(), convey.ShouldEqual, -42)
+			convey.So(BiMap(Right[int]("hello"), negate, length).UnwrapRight(), convey.ShouldEqual, 5)
+			convey.So(Fold(Left[int, string](42), negate, length), convey.ShouldEqual, -42)
+			convey.So(Fold(Right[int]("hello"), negate, length), convey.ShouldEqual, 5)
+			convey.So(func() { Fold(Either[int, string]{}, negate, length) }, convey.ShouldPanic)
+		})
+
+		convey.Convey("Functor laws", func() {
+			identity := func(v int) int { return v }
+			f := func(v int) int { return v + 1 }
+			g := func(v int) int { return v * 2 }
+
+			for _, either := range []Either[string, int]{Right[string](21), Left[string, int]("err")} {
+				convey.So(MapRight(either, identity), convey.ShouldResemble, either)
+				convey.So(
+					MapRight(MapRight(either, f), g),
+					convey.ShouldResemble,
+					MapRight(either, func(v int) int { return g(f(v)) }),
+				)
+				convey.So(BiMap(either, func(v string) string { return v }, identity), convey.ShouldResemble, either)
+				convey.So(either.Swap().Swap(), convey.ShouldResemble, either)
+			}
+		})
+
+		convey.Convey("Monad laws", func() {
+			f := func(v int) Either[string, int] { return Right[string](v + 1) }
+			g := func(v int) Either[string, int] {
+				if v > 10 {
+					return Left[string, int]("too big")
+				}
+				return Right[string](v * 2)
+			}
+
+			convey.So(FlatMapRight(Right[string](5), f), convey.ShouldResemble, f(5))
+
+			for _, either := range []Either[string, int]{Right[string](5), Right[string](21), Left[string, int]("err")} {
+				convey.So(FlatMapRight(either, Right[string, int]), convey.ShouldResemble, either)
+				convey.So(
+					FlatMapRight(FlatMapRight(either, f), g),
+					convey.ShouldResemble,
+					FlatMapRight(either, func(v int) Either[string, int] { return FlatMapRight(f(v), g) }),
+				)
+			}
+		})
+
+		convey.Convey("Conversions to and from Result", func() {
+			err := errors.New("an error")
+			convey.So(EitherToResult(Right[error](42)).Unwrap(), convey.ShouldEqual, 42)
+			convey.So(EitherToResult(Left[error, int](err)).UnwrapErr(), convey.ShouldEqual, err)
+			convey.So(ResultToEither(Ok[int, error](42)).UnwrapRight(), convey.ShouldEqual, 42)
+			convey.So(ResultToEither(Err[int](err)).UnwrapLeft(), convey.ShouldEqual, err)
+
+			convey.So(ResultToEither(EitherToResult(Right[error](42))), convey.ShouldResemble, Right[error](42))
+		})
 	})
 }
 
@@ -0,0 +1,93 @@
+package twoface
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+/*
+Envelope wraps a Job with what is needed to store or send it: the name and version of its
+type, how often it was attempted, free-form headers and a deadline. It is a Job itself, so
+it can be submitted like any other, and a queue that is given one keeps its headers and
+deadline.
+
+Example:
+
+	pool.Submit(&Envelope{
+	    Job:      &EmailJob{To: "someone@example.com"},
+	    Headers:  map[string]string{"trace": traceID},
+	    Deadline: time.Now().Add(time.Hour),
+	})
+*/
+type Envelope struct {
+	// ID is set by the queue that holds the envelope.
+	ID uint64
+	// Type is the name the type of the job was registered under, and Version the
+	// version of its payload. They are filled in when the envelope is unmarshaled.
+	Type    string
+	Version int
+	// Attempts is the number of times the job was delivered before, and put back.
+	Attempts int
+	Headers  map[string]string
+	// Deadline is the time after which the job is no longer run. The zero time means
+	// there is no deadline.
+	Deadline time.Time
+	Job      Job
+}
+
+/*
+Do runs the job, unless its deadline has passed.
+*/
+func (envelope *Envelope) Do() Result[any, error] {
+	if envelope.Expired(time.Now()) {
+		return Err[any](fmt.Errorf("job %d: %w", envelope.ID, context.DeadlineExceeded))
+	}
+
+	return envelope.Job.Do()
+}
+
+/*
+Expired reports whether the deadline of the envelope has passed at the given time.
+
+Example:
+
+	if envelope.Expired(time.Now()) {
+	    log.Println("too late for", envelope.Type)
+	}
+*/
+func (envelope *Envelope) Expired(now time.Time) bool {
+	return !envelope.Deadline.IsZero() && now.After(envelope.Deadline)
+}
+
+func (envelope *Envelope) discard(err error) {
+	discardJob(envelope.Job, err)
+}
+
+/*
+envelopeFrame is the form an Envelope takes in bytes. The frame is always JSON, while
+the payload is written by the codec that is named in it.
+*/
+type envelopeFrame struct {
+	ID       uint64            `json:"id,omitempty"`
+	Type     string            `json:"type"`
+	Version  int               `json:"version"`
+	Codec    string            `json:"codec"`
+	Attempts int               `json:"attempts,omitempty"`
+	Headers  map[string]string `json:"headers,omitempty"`
+	Deadline *time.Time        `json:"deadline,omitempty"`
+	Payload  []byte            `json:"payload"`
+}
+
+/*
+stampDelivery copies the ID and attempts of a delivery into its job, when that is an
+Envelope.
+*/
+func stampDelivery(delivery Delivery) Delivery {
+	if envelope, ok := delivery.Job.(*Envelope); ok {
+		envelope.ID = delivery.ID
+		envelope.Attempts = delivery.Attempts
+	}
+
+	return delivery
+}
@@ -0,0 +1,194 @@
+package twoface
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+)
+
+/*
+ErrorCodec turns errors into JSON and back, so a Result that carries an Err
+value can be persisted or sent over the wire, and rebuilt on the other side.
+
+Example:
+
+	SetErrorCodec(NewTypedErrorCodec())
+*/
+type ErrorCodec interface {
+	EncodeError(err error) (json.RawMessage, error)
+	DecodeError(data json.RawMessage) (error, error)
+}
+
+var (
+	errorCodecMu sync.RWMutex
+	errorCodec   ErrorCodec = MessageErrorCodec{}
+)
+
+/*
+SetErrorCodec replaces the ErrorCodec that is used when a Result is marshaled
+to, or unmarshaled from, JSON.
+
+Example:
+
+	codec := NewTypedErrorCodec()
+	codec.Register("not_found", func() error { return &NotFoundError{} })
+	SetErrorCodec(codec)
+*/
+func SetErrorCodec(codec ErrorCodec) {
+	errorCodecMu.Lock()
+	defer errorCodecMu.Unlock()
+	errorCodec = codec
+}
+
+/*
+CurrentErrorCodec returns the ErrorCodec that is currently in use.
+
+Example:
+
+	data, err := CurrentErrorCodec().EncodeError(fmt.Errorf("an error"))
+*/
+func CurrentErrorCodec() ErrorCodec {
+	errorCodecMu.RLock()
+	defer errorCodecMu.RUnlock()
+	return errorCodec
+}
+
+/*
+errorMessage is the wire format both built-in codecs share.
+*/
+type errorMessage struct {
+	Type    string          `json:"type,omitempty"`
+	Message string          `json:"message"`
+	Data    json.RawMessage `json:"data,omitempty"`
+}
+
+/*
+MessageErrorCodec is the default ErrorCodec. It only keeps the error message,
+so every decoded error is a plain error created with errors.New.
+
+Example:
+
+	data, _ := MessageErrorCodec{}.EncodeError(fmt.Errorf("an error"))
+	fmt.Println(string(data)) // Output: {"message":"an error"}
+*/
+type MessageErrorCodec struct{}
+
+/*
+EncodeError writes the message of the error.
+*/
+func (MessageErrorCodec) EncodeError(err error) (json.RawMessage, error) {
+	return json.Marshal(errorMessage{Message: err.Error()})
+}
+
+/*
+DecodeError rebuilds a plain error from its message.
+*/
+func (MessageErrorCodec) DecodeError(data json.RawMessage) (error, error) {
+	var msg errorMessage
+
+	if err := json.Unmarshal(data, &msg); err != nil {
+		return nil, err
+	}
+
+	return errors.New(msg.Message), nil
+}
+
+/*
+TypedErrorCodec is an ErrorCodec that knows about registered error types. A
+registered error is written together with its type name and its own JSON
+representation, so it can be rebuilt as the same type on decode. Errors of an
+unknown type fall back to the behavior of MessageErrorCodec.
+
+Example:
+
+	codec := NewTypedErrorCodec()
+	codec.Register("not_found", func() error { return &NotFoundError{} })
+*/
+type TypedErrorCodec struct {
+	mu     sync.RWMutex
+	byName map[string]func() error
+	byType map[reflect.Type]string
+}
+
+/*
+NewTypedErrorCodec creates an empty TypedErrorCodec.
+
+Example:
+
+	codec := NewTypedErrorCodec()
+*/
+func NewTypedErrorCodec() *TypedErrorCodec {
+	return &TypedErrorCodec{
+		byName: make(map[string]func() error),
+		byType: make(map[reflect.Type]string),
+	}
+}
+
+/*
+Register adds an error type under the given name. The constructor must return
+a fresh value of the type, usually a pointer, that JSON can be decoded into.
+
+Example:
+
+	codec.Register("not_found", func() error { return &NotFoundError{} })
+*/
+func (codec *TypedErrorCodec) Register(name string, constructor func() error) *TypedErrorCodec {
+	codec.mu.Lock()
+	defer codec.mu.Unlock()
+
+	codec.byName[name] = constructor
+	codec.byType[reflect.TypeOf(constructor())] = name
+
+	return codec
+}
+
+/*
+EncodeError writes the type name, message and data of a registered error, or
+only the message of an unknown one.
+*/
+func (codec *TypedErrorCodec) EncodeError(err error) (json.RawMessage, error) {
+	codec.mu.RLock()
+	name, ok := codec.byType[reflect.TypeOf(err)]
+	codec.mu.RUnlock()
+
+	if !ok {
+		return MessageErrorCodec{}.EncodeError(err)
+	}
+
+	data, marshalErr := json.Marshal(err)
+	if marshalErr != nil {
+		return nil, marshalErr
+	}
+
+	return json.Marshal(errorMessage{Type: name, Message: err.Error(), Data: data})
+}
+
+/*
+DecodeError rebuilds a registered error as its original type, or a plain error
+when the type name is missing or unknown.
+*/
+func (codec *TypedErrorCodec) DecodeError(data json.RawMessage) (error, error) {
+	var msg errorMessage
+
+	if err := json.Unmarshal(data, &msg); err != nil {
+		return nil, err
+	}
+
+	codec.mu.RLock()
+	constructor, ok := codec.byName[msg.Type]
+	codec.mu.RUnlock()
+
+	if !ok || len(msg.Data) == 0 {
+		return errors.New(msg.Message), nil
+	}
+
+	decoded := constructor()
+
+	if err := json.Unmarshal(msg.Data, decoded); err != nil {
+		return nil, fmt.Errorf("decoding error of type %q: %w", msg.Type, err)
+	}
+
+	return decoded, nil
+}
@@ -0,0 +1,50 @@
+package twoface
+
+/*
+Executor runs tasks, for instance the callbacks of a Future. Implementations decide
+where and when a task runs, on a new goroutine, on the calling goroutine, or on a
+worker of a Pool.
+
+Example:
+
+	f.WithExecutor(InlineExecutor)
+*/
+type Executor interface {
+	Execute(task func())
+}
+
+/*
+refusingExecutor is implemented by executors that can refuse a task, like a Pool that was
+shut down, which then call refused with the reason, instead of dropping the task silently.
+*/
+type refusingExecutor interface {
+	executeOr(task func(), refused func(error))
+}
+
+/*
+ExecutorFunc is an adapter to allow the use of an ordinary function as an Executor.
+
+Example:
+
+	executor := ExecutorFunc(func(task func()) { go task() })
+*/
+type ExecutorFunc func(task func())
+
+/*
+Execute calls the function with the task.
+*/
+func (fn ExecutorFunc) Execute(task func()) {
+	fn(task)
+}
+
+/*
+GoExecutor runs every task on a new goroutine. It is the default Executor of a Future.
+*/
+var GoExecutor Executor = ExecutorFunc(func(task func()) { go task() })
+
+/*
+InlineExecutor runs every task directly on the calling goroutine. For the callbacks of
+a Future, that is the goroutine that completes it, or the one that registers a callback
+on an already completed Future.
+*/
+var InlineExecutor Executor = ExecutorFunc(func(task func()) { task() })
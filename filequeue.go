@@ -0,0 +1,664 @@
+package twoface
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+/*
+SyncPolicy decides how often a FileQueue flushes its writes to disk.
+*/
+type SyncPolicy int
+
+const (
+	// SyncEveryWrite flushes after every write, so nothing is lost when the machine
+	// crashes, at the cost of throughput.
+	SyncEveryWrite SyncPolicy = iota
+	// SyncPeriodically flushes at a fixed interval, so a crash of the machine loses at
+	// most that interval of writes.
+	SyncPeriodically
+	// SyncNever leaves flushing to the operating system. Writes survive a crash of the
+	// process, but not of the machine.
+	SyncNever
+)
+
+/*
+FileQueueConfig configures a FileQueue.
+*/
+type FileQueueConfig struct {
+	// Dir is the directory that holds the segment files of the queue.
+	Dir string
+	// Registry turns jobs into bytes and back.
+	Registry *JobRegistry
+	// SegmentSize is the size after which a new segment file is started. It defaults
+	// to 64 MiB.
+	SegmentSize int64
+	// Sync decides how often writes are flushed to disk.
+	Sync SyncPolicy
+	// SyncInterval is the interval of SyncPeriodically. It defaults to 100ms.
+	SyncInterval time.Duration
+}
+
+/*
+FileQueue is a Queue that survives restarts, by appending every change to a log on disk
+that is replayed when the queue is opened. The log is split into segment files, which are
+removed once all of their jobs were acknowledged. Compact rewrites the log to only the
+jobs that are left. A write that was cut short by a crash is discarded on replay.
+
+Example:
+
+	registry := NewJobRegistry().Register("email", func() Job { return &EmailJob{} })
+	queue, err := OpenFileQueue(FileQueueConfig{Dir: "jobs", Registry: registry})
+	if err != nil {
+	    log.Fatal(err)
+	}
+	defer queue.Close()
+
+	pool := NewPool(ctx, 4, WithQueue(queue))
+*/
+type FileQueue struct {
+	config   FileQueueConfig
+	mu       sync.Mutex
+	segments []*walSegment
+	active   *os.File
+	written  int64
+	items    map[uint64]*walItem
+	ready    []uint64
+	inFlight map[uint64]struct{}
+	nextID   uint64
+	signal   chan struct{}
+	closed   bool
+	dirty    bool
+	stopSync chan struct{}
+	synced   chan struct{}
+}
+
+/*
+walSegment is a file of the log, with the number of jobs that were enqueued in it and
+are not yet acknowledged.
+*/
+type walSegment struct {
+	seq  uint64
+	path string
+	live int
+}
+
+/*
+walItem is a job in the queue, kept in its encoded form until it is delivered.
+*/
+type walItem struct {
+	id       uint64
+	data     []byte
+	attempts int
+	sealed   bool
+	segment  *walSegment
+	order    uint64
+}
+
+/*
+walRecord is a single change in the log.
+*/
+type walRecord struct {
+	Op       string          `json:"op"`
+	ID       uint64          `json:"id"`
+	Job      json.RawMessage `json:"job,omitempty"`
+	Attempts int             `json:"attempts,omitempty"`
+	// Sealed marks a job that was enqueued as an Envelope, and is delivered as one.
+	Sealed bool `json:"sealed,omitempty"`
+}
+
+const (
+	walEnqueue = "enqueue"
+	walAck     = "ack"
+	walNack    = "nack"
+	walNext    = "next"
+
+	walHeaderSize = 8
+	walExtension  = ".wal"
+)
+
+/*
+ErrCorruptSegment is returned when a segment other than the last one cannot be read,
+which a crash cannot explain.
+*/
+var ErrCorruptSegment = errors.New("corrupt segment")
+
+/*
+OpenFileQueue opens the queue in the directory, creating it when needed, and replays its
+log. Jobs that were delivered but not acknowledged before the queue was closed, or the
+process crashed, are delivered again.
+
+Example:
+
+	queue, err := OpenFileQueue(FileQueueConfig{Dir: "jobs", Registry: registry})
+*/
+func OpenFileQueue(config FileQueueConfig) (*FileQueue, error) {
+	if config.Registry == nil {
+		return nil, errors.New("a FileQueue needs a JobRegistry")
+	}
+
+	if config.SegmentSize <= 0 {
+		config.SegmentSize = 64 << 20
+	}
+
+	if config.SyncInterval <= 0 {
+		config.SyncInterval = 100 * time.Millisecond
+	}
+
+	if err := os.MkdirAll(config.Dir, 0o755); err != nil {
+		return nil, err
+	}
+
+	queue := &FileQueue{
+		config:   config,
+		items:    make(map[uint64]*walItem),
+		inFlight: make(map[uint64]struct{}),
+		nextID:   1,
+		signal:   make(chan struct{}),
+	}
+
+	if err := queue.replay(); err != nil {
+		return nil, err
+	}
+
+	if err := queue.rotate(); err != nil {
+		return nil, err
+	}
+
+	queue.removeEmptySegments()
+
+	if config.Sync == SyncPeriodically {
+		queue.stopSync = make(chan struct{})
+		queue.synced = make(chan struct{})
+		go queue.syncPeriodically()
+	}
+
+	return queue, nil
+}
+
+/*
+Enqueue writes the job to the log, and adds it to the back of the queue. Its ID keeps
+increasing across restarts, also when every segment was removed in between.
+*/
+func (queue *FileQueue) Enqueue(job Job) (uint64, error) {
+	data, err := queue.config.Registry.Marshal(job)
+	if err != nil {
+		return 0, err
+	}
+
+	queue.mu.Lock()
+	defer queue.mu.Unlock()
+
+	if queue.closed {
+		return 0, ErrQueueClosed
+	}
+
+	// The ID is taken before the write, so a segment that starts on it records the next one.
+	id := queue.nextID
+	queue.nextID++
+	_, sealed := job.(*Envelope)
+
+	segment, err := queue.write(walRecord{Op: walEnqueue, ID: id, Job: data, Sealed: sealed})
+	if err != nil {
+		return 0, err
+	}
+
+	queue.items[id] = &walItem{id: id, data: data, sealed: sealed, segment: segment}
+	segment.live++
+	queue.ready = append(queue.ready, id)
+	queue.notify()
+
+	return id, nil
+}
+
+/*
+Dequeue takes the job at the front of the queue, blocking until there is one, the queue
+is closed, or the context is done. A job that is not acknowledged before the queue is
+closed is delivered again once it is reopened.
+*/
+func (queue *FileQueue) Dequeue(ctx context.Context) (Delivery, error) {
+	for {
+		queue.mu.Lock()
+
+		if queue.closed {
+			queue.mu.Unlock()
+			return Delivery{}, ErrQueueClosed
+		}
+
+		if len(queue.ready) > 0 {
+			item := queue.items[queue.ready[0]]
+			queue.ready = queue.ready[1:]
+			queue.inFlight[item.id] = struct{}{}
+			queue.mu.Unlock()
+
+			return stampDelivery(Delivery{ID: item.id, Job: queue.decode(item), Attempts: item.attempts}), nil
+		}
+
+		signal := queue.signal
+		queue.mu.Unlock()
+
+		select {
+		case <-signal:
+		case <-ctx.Done():
+			return Delivery{}, ctx.Err()
+		}
+	}
+}
+
+/*
+Ack records that a job that is in flight is done, and removes the segments at the start
+of the log that no longer hold any jobs.
+*/
+func (queue *FileQueue) Ack(id uint64) error {
+	queue.mu.Lock()
+	defer queue.mu.Unlock()
+
+	if _, ok := queue.inFlight[id]; !ok {
+		return fmt.Errorf("job %d is not in flight", id)
+	}
+
+	if _, err := queue.write(walRecord{Op: walAck, ID: id}); err != nil {
+		return err
+	}
+
+	item := queue.items[id]
+	item.segment.live--
+	delete(queue.items, id)
+	delete(queue.inFlight, id)
+	queue.removeEmptySegments()
+
+	return nil
+}
+
+/*
+Nack records another attempt of a job that is in flight, and puts it back at the end of
+the queue.
+*/
+func (queue *FileQueue) Nack(id uint64) error {
+	queue.mu.Lock()
+	defer queue.mu.Unlock()
+
+	if _, ok := queue.inFlight[id]; !ok {
+		return fmt.Errorf("job %d is not in flight", id)
+	}
+
+	item := queue.items[id]
+
+	if _, err := queue.write(walRecord{Op: walNack, ID: id, Attempts: item.attempts + 1}); err != nil {
+		return err
+	}
+
+	delete(queue.inFlight, id)
+	item.attempts++
+	queue.ready = append(queue.ready, id)
+	queue.notify()
+
+	return nil
+}
+
+/*
+Len returns the number of jobs in the queue, including the ones that are in flight.
+*/
+func (queue *FileQueue) Len() int {
+	queue.mu.Lock()
+	defer queue.mu.Unlock()
+
+	return len(queue.items)
+}
+
+/*
+Compact rewrites the jobs that are left into a new segment, and removes all older ones,
+which frees the space taken by jobs that were acknowledged, even when they share their
+segments with jobs that were not.
+
+Example:
+
+	if err := queue.Compact(); err != nil {
+	    log.Println(err)
+	}
+*/
+func (queue *FileQueue) Compact() error {
+	queue.mu.Lock()
+	defer queue.mu.Unlock()
+
+	if queue.closed {
+		return ErrQueueClosed
+	}
+
+	if err := queue.rotate(); err != nil {
+		return err
+	}
+
+	inFlight := make([]uint64, 0, len(queue.inFlight))
+	for id := range queue.inFlight {
+		inFlight = append(inFlight, id)
+	}
+	sort.Slice(inFlight, func(i, j int) bool { return inFlight[i] < inFlight[j] })
+
+	for _, id := range append(append([]uint64{}, queue.ready...), inFlight...) {
+		item := queue.items[id]
+
+		segment, err := queue.write(walRecord{Op: walEnqueue, ID: item.id, Job: item.data, Attempts: item.attempts, Sealed: item.sealed})
+		if err != nil {
+			return err
+		}
+
+		item.segment.live--
+		item.segment = segment
+		segment.live++
+	}
+
+	if err := queue.active.Sync(); err != nil {
+		return err
+	}
+
+	queue.removeEmptySegments()
+	return nil
+}
+
+/*
+Close stops the queue, so Enqueue and Dequeue return ErrQueueClosed, and flushes the log
+to disk. Closing it again does nothing.
+*/
+func (queue *FileQueue) Close() error {
+	queue.mu.Lock()
+
+	if queue.closed {
+		queue.mu.Unlock()
+		return nil
+	}
+
+	queue.closed = true
+	queue.notify()
+	queue.mu.Unlock()
+
+	if queue.stopSync != nil {
+		close(queue.stopSync)
+		<-queue.synced
+	}
+
+	queue.mu.Lock()
+	defer queue.mu.Unlock()
+
+	return errors.Join(queue.active.Sync(), queue.active.Close())
+}
+
+/*
+decode turns an item back into the job that was enqueued, or a job that reports why it
+could not be decoded.
+*/
+func (queue *FileQueue) decode(item *walItem) Job {
+	envelope, err := queue.config.Registry.UnmarshalEnvelope(item.data)
+	if err != nil {
+		return brokenJob{err: fmt.Errorf("job %d: %w", item.id, err)}
+	}
+
+	if item.sealed {
+		return envelope
+	}
+
+	return envelope.Job
+}
+
+/*
+replay rebuilds the state of the queue from its segments. A record that was cut short,
+or does not match its checksum, ends the last segment, which is truncated to the last
+complete record.
+*/
+func (queue *FileQueue) replay() error {
+	paths, err := filepath.Glob(filepath.Join(queue.config.Dir, "*"+walExtension))
+	if err != nil {
+		return err
+	}
+
+	for _, path := range paths {
+		seq, err := strconv.ParseUint(strings.TrimSuffix(filepath.Base(path), walExtension), 10, 64)
+		if err != nil {
+			continue
+		}
+		queue.segments = append(queue.segments, &walSegment{seq: seq, path: path})
+	}
+
+	sort.Slice(queue.segments, func(i, j int) bool { return queue.segments[i].seq < queue.segments[j].seq })
+
+	var order uint64
+	live := make(map[uint64]*walItem)
+
+	for idx, segment := range queue.segments {
+		valid, err := readSegment(segment.path, func(record walRecord) {
+			order++
+			item, exists := live[record.ID]
+
+			if record.Op == walNext {
+				queue.nextID = max(queue.nextID, record.ID)
+			} else {
+				queue.nextID = max(queue.nextID, record.ID+1)
+			}
+
+			switch record.Op {
+			case walEnqueue:
+				if exists {
+					item.segment.live--
+				}
+				live[record.ID] = &walItem{
+					id:       record.ID,
+					data:     record.Job,
+					attempts: record.Attempts,
+					sealed:   record.Sealed,
+					segment:  segment,
+					order:    order,
+				}
+				segment.live++
+			case walAck:
+				if exists {
+					item.segment.live--
+					delete(live, record.ID)
+				}
+			case walNack:
+				if exists {
+					item.attempts = record.Attempts
+					item.order = order
+				}
+			}
+		})
+
+		if err == nil {
+			continue
+		}
+
+		if idx < len(queue.segments)-1 {
+			return fmt.Errorf("%w %s: %v", ErrCorruptSegment, segment.path, err)
+		}
+
+		if err := os.Truncate(segment.path, valid); err != nil {
+			return err
+		}
+	}
+
+	items := make([]*walItem, 0, len(live))
+	for _, item := range live {
+		items = append(items, item)
+	}
+	sort.Slice(items, func(i, j int) bool { return items[i].order < items[j].order })
+
+	queue.items = live
+	for _, item := range items {
+		queue.ready = append(queue.ready, item.id)
+	}
+
+	return nil
+}
+
+/*
+readSegment calls the function for every record in the segment, and returns the offset
+after the last complete record, with an error when the segment does not end there.
+*/
+func readSegment(path string, apply func(walRecord)) (int64, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+
+	reader := bufio.NewReader(file)
+	header := make([]byte, walHeaderSize)
+	var offset int64
+
+	for {
+		if _, err := io.ReadFull(reader, header); err != nil {
+			if err == io.EOF {
+				return offset, nil
+			}
+			return offset, err
+		}
+
+		payload := make([]byte, binary.BigEndian.Uint32(header[:4]))
+		if _, err := io.ReadFull(reader, payload); err != nil {
+			return offset, err
+		}
+
+		if crc32.ChecksumIEEE(payload) != binary.BigEndian.Uint32(header[4:]) {
+			return offset, errors.New("checksum mismatch")
+		}
+
+		var record walRecord
+		if err := json.Unmarshal(payload, &record); err != nil {
+			return offset, err
+		}
+
+		apply(record)
+		offset += int64(walHeaderSize + len(payload))
+	}
+}
+
+/*
+write appends a record to the active segment, and returns that segment. A new segment is
+started once the active one is full. It has to be called with the lock held.
+*/
+func (queue *FileQueue) write(record walRecord) (*walSegment, error) {
+	payload, err := json.Marshal(record)
+	if err != nil {
+		return nil, err
+	}
+
+	buffer := make([]byte, walHeaderSize+len(payload))
+	binary.BigEndian.PutUint32(buffer[:4], uint32(len(payload)))
+	binary.BigEndian.PutUint32(buffer[4:8], crc32.ChecksumIEEE(payload))
+	copy(buffer[walHeaderSize:], payload)
+
+	segment := queue.segments[len(queue.segments)-1]
+
+	if _, err := queue.active.Write(buffer); err != nil {
+		return nil, err
+	}
+
+	queue.written += int64(len(buffer))
+	queue.dirty = true
+
+	if queue.config.Sync == SyncEveryWrite {
+		if err := queue.active.Sync(); err != nil {
+			return nil, err
+		}
+		queue.dirty = false
+	}
+
+	if queue.written >= queue.config.SegmentSize {
+		return segment, queue.rotate()
+	}
+
+	return segment, nil
+}
+
+/*
+rotate closes the active segment, and starts a new one, which begins with the next ID, so
+IDs keep increasing even when every segment with a job in it was removed.
+*/
+func (queue *FileQueue) rotate() error {
+	var seq uint64 = 1
+	if len(queue.segments) > 0 {
+		seq = queue.segments[len(queue.segments)-1].seq + 1
+	}
+
+	path := filepath.Join(queue.config.Dir, fmt.Sprintf("%020d%s", seq, walExtension))
+
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+
+	if queue.active != nil {
+		if err := errors.Join(queue.active.Sync(), queue.active.Close()); err != nil {
+			file.Close()
+			return err
+		}
+	}
+
+	queue.active = file
+	queue.written = 0
+	queue.segments = append(queue.segments, &walSegment{seq: seq, path: path})
+
+	_, err = queue.write(walRecord{Op: walNext, ID: queue.nextID})
+	return err
+}
+
+/*
+removeEmptySegments deletes the oldest segments, other than the active one, as long as
+they no longer hold any job that was not acknowledged. A segment after one that is kept
+stays as well, even when it is empty, because it can hold the acks and nacks of the jobs
+in the older one.
+*/
+func (queue *FileQueue) removeEmptySegments() {
+	removed := 0
+
+	for _, segment := range queue.segments[:len(queue.segments)-1] {
+		if segment.live > 0 || os.Remove(segment.path) != nil {
+			break
+		}
+		removed++
+	}
+
+	queue.segments = queue.segments[removed:]
+}
+
+func (queue *FileQueue) syncPeriodically() {
+	defer close(queue.synced)
+
+	ticker := time.NewTicker(queue.config.SyncInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			queue.mu.Lock()
+			if queue.dirty {
+				queue.active.Sync()
+				queue.dirty = false
+			}
+			queue.mu.Unlock()
+		case <-queue.stopSync:
+			return
+		}
+	}
+}
+
+/*
+notify wakes up every Dequeue that is waiting for a job. It has to be called with the
+lock held.
+*/
+func (queue *FileQueue) notify() {
+	close(queue.signal)
+	queue.signal = make(chan struct{})
+}
@@ -0,0 +1,318 @@
+package twoface
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/smartystreets/goconvey/convey"
+)
+
+func testRegistry() *JobRegistry {
+	return NewJobRegistry().Register("recorded", func() Job { return &recordedJob{} })
+}
+
+// crashEnv makes the test binary act as a process that gets killed while it writes.
+const crashEnv = "TWOFACE_CRASH_DIR"
+
+func TestFileQueue(t *testing.T) {
+	if dir := os.Getenv(crashEnv); dir != "" {
+		crashWhileEnqueueing(dir)
+		return
+	}
+
+	convey.Convey("FileQueue", t, func() {
+		ctx := context.Background()
+		dir := t.TempDir()
+		config := FileQueueConfig{Dir: dir, Registry: testRegistry()}
+		resetRecorder()
+
+		open := func() *FileQueue {
+			queue, err := OpenFileQueue(config)
+			convey.So(err, convey.ShouldBeNil)
+			return queue
+		}
+
+		names := func(queue *FileQueue) []string {
+			var names []string
+			for queue.Len() > len(names) {
+				timeout, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+				delivery, err := queue.Dequeue(timeout)
+				cancel()
+				if err != nil {
+					break
+				}
+				names = append(names, delivery.Job.(*recordedJob).Name)
+			}
+			return names
+		}
+
+		segments := func() []string {
+			paths, _ := filepath.Glob(filepath.Join(dir, "*.wal"))
+			return paths
+		}
+
+		convey.Convey("Should keep its jobs across restarts", func() {
+			queue := open()
+			for _, name := range []string{"a", "b", "c"} {
+				queue.Enqueue(&recordedJob{Name: name})
+			}
+			convey.So(queue.Close(), convey.ShouldBeNil)
+
+			queue = open()
+			defer queue.Close()
+			convey.So(queue.Len(), convey.ShouldEqual, 3)
+			convey.So(names(queue), convey.ShouldResemble, []string{"a", "b", "c"})
+		})
+
+		convey.Convey("Should deliver jobs again that were not acknowledged", func() {
+			queue := open()
+			first, _ := queue.Enqueue(&recordedJob{Name: "acked"})
+			queue.Enqueue(&recordedJob{Name: "in flight"})
+			queue.Enqueue(&recordedJob{Name: "nacked"})
+
+			delivery, _ := queue.Dequeue(ctx)
+			queue.Ack(delivery.ID)
+			queue.Dequeue(ctx)
+			nacked, _ := queue.Dequeue(ctx)
+			queue.Nack(nacked.ID)
+			queue.Close()
+
+			queue = open()
+			defer queue.Close()
+			convey.So(first, convey.ShouldEqual, delivery.ID)
+			convey.So(queue.Len(), convey.ShouldEqual, 2)
+
+			again, _ := queue.Dequeue(ctx)
+			convey.So(again.Job.(*recordedJob).Name, convey.ShouldEqual, "in flight")
+			again, _ = queue.Dequeue(ctx)
+			convey.So(again.Job.(*recordedJob).Name, convey.ShouldEqual, "nacked")
+			convey.So(again.Attempts, convey.ShouldEqual, 1)
+
+			next, _ := queue.Enqueue(&recordedJob{Name: "new"})
+			convey.So(next, convey.ShouldEqual, 4)
+		})
+
+		convey.Convey("Should discard a record that was cut short by a crash", func() {
+			queue := open()
+			queue.Enqueue(&recordedJob{Name: "complete"})
+			queue.Enqueue(&recordedJob{Name: "torn"})
+			queue.Close()
+
+			paths := segments()
+			last := paths[len(paths)-1]
+			info, _ := os.Stat(last)
+			os.Truncate(last, info.Size()-5)
+
+			queue = open()
+			convey.So(names(queue), convey.ShouldResemble, []string{"complete"})
+			queue.Enqueue(&recordedJob{Name: "after"})
+			queue.Close()
+
+			queue = open()
+			defer queue.Close()
+			convey.So(names(queue), convey.ShouldResemble, []string{"complete", "after"})
+		})
+
+		convey.Convey("Should discard a record that does not match its checksum", func() {
+			queue := open()
+			queue.Enqueue(&recordedJob{Name: "intact"})
+			queue.Enqueue(&recordedJob{Name: "garbled"})
+			queue.Close()
+
+			paths := segments()
+			last := paths[len(paths)-1]
+			data, _ := os.ReadFile(last)
+			data[len(data)-3] ^= 0xff
+			os.WriteFile(last, data, 0o644)
+
+			queue = open()
+			defer queue.Close()
+			convey.So(names(queue), convey.ShouldResemble, []string{"intact"})
+		})
+
+		convey.Convey("Should refuse to open with a corrupt segment in the middle", func() {
+			queue := open()
+			queue.Enqueue(&recordedJob{Name: "a"})
+			queue.Close()
+			queue = open()
+			queue.Enqueue(&recordedJob{Name: "b"})
+			queue.Close()
+
+			paths := segments()
+			convey.So(len(paths), convey.ShouldBeGreaterThan, 1)
+			data, _ := os.ReadFile(paths[0])
+			data[len(data)-3] ^= 0xff
+			os.WriteFile(paths[0], data, 0o644)
+
+			_, err := OpenFileQueue(config)
+			convey.So(err, convey.ShouldWrap, ErrCorruptSegment)
+		})
+
+		convey.Convey("Should remove segments once all of their jobs were acknowledged", func() {
+			config.SegmentSize = 200
+			queue := open()
+			defer queue.Close()
+
+			for idx := range 10 {
+				queue.Enqueue(&recordedJob{Name: fmt.Sprint(idx)})
+			}
+			convey.So(len(segments()), convey.ShouldBeGreaterThan, 3)
+
+			for range 10 {
+				delivery, _ := queue.Dequeue(ctx)
+				queue.Ack(delivery.ID)
+			}
+			convey.So(segments(), convey.ShouldHaveLength, 1)
+		})
+
+		convey.Convey("Should keep acknowledged jobs gone across several restarts", func() {
+			queue := open()
+			queue.Enqueue(&recordedJob{Name: "a"})
+			queue.Enqueue(&recordedJob{Name: "b"})
+			queue.Close()
+
+			queue = open()
+			delivery, _ := queue.Dequeue(ctx)
+			convey.So(delivery.Job.(*recordedJob).Name, convey.ShouldEqual, "a")
+			convey.So(queue.Ack(delivery.ID), convey.ShouldBeNil)
+			queue.Close()
+
+			for range 3 {
+				queue = open()
+				convey.So(queue.Len(), convey.ShouldEqual, 1)
+				queue.Close()
+			}
+
+			queue = open()
+			defer queue.Close()
+			convey.So(names(queue), convey.ShouldResemble, []string{"b"})
+		})
+
+		convey.Convey("Should keep its IDs increasing after every segment was removed", func() {
+			queue := open()
+			first, _ := queue.Enqueue(&recordedJob{Name: "a"})
+			delivery, _ := queue.Dequeue(ctx)
+			queue.Ack(delivery.ID)
+			queue.Close()
+
+			for range 2 {
+				queue = open()
+				queue.Close()
+			}
+
+			queue = open()
+			defer queue.Close()
+			next, _ := queue.Enqueue(&recordedJob{Name: "b"})
+			convey.So(next, convey.ShouldBeGreaterThan, first)
+		})
+
+		convey.Convey("Should compact the log to the jobs that are left", func() {
+			queue := open()
+			for idx := range 10 {
+				queue.Enqueue(&recordedJob{Name: fmt.Sprint(idx)})
+			}
+			for range 9 {
+				delivery, _ := queue.Dequeue(ctx)
+				queue.Ack(delivery.ID)
+			}
+
+			before, _ := os.Stat(segments()[0])
+			convey.So(queue.Compact(), convey.ShouldBeNil)
+			paths := segments()
+			convey.So(paths, convey.ShouldHaveLength, 1)
+			after, _ := os.Stat(paths[0])
+			convey.So(after.Size(), convey.ShouldBeLessThan, before.Size())
+			queue.Close()
+
+			queue = open()
+			defer queue.Close()
+			convey.So(names(queue), convey.ShouldResemble, []string{"9"})
+		})
+
+		convey.Convey("Should flush periodically", func() {
+			config.Sync = SyncPeriodically
+			config.SyncInterval = time.Millisecond
+			queue := open()
+			queue.Enqueue(&recordedJob{Name: "a"})
+			time.Sleep(5 * time.Millisecond)
+			convey.So(queue.Close(), convey.ShouldBeNil)
+
+			queue = open()
+			defer queue.Close()
+			convey.So(names(queue), convey.ShouldResemble, []string{"a"})
+		})
+
+		convey.Convey("Should recover after the process was killed while writing", func() {
+			cmd := exec.Command(os.Args[0], "-test.run=^TestFileQueue$")
+			cmd.Env = append(os.Environ(), crashEnv+"="+dir)
+			convey.So(cmd.Start(), convey.ShouldBeNil)
+
+			for len(segments()) == 0 {
+				time.Sleep(time.Millisecond)
+			}
+			time.Sleep(50 * time.Millisecond)
+			cmd.Process.Kill()
+			cmd.Wait()
+
+			queue := open()
+			defer queue.Close()
+
+			recovered := names(queue)
+			convey.So(len(recovered), convey.ShouldBeGreaterThan, 0)
+			for idx, name := range recovered {
+				convey.So(name, convey.ShouldEqual, fmt.Sprint(idx))
+			}
+		})
+
+		convey.Convey("Should let a Pool run the jobs that survived a restart", func() {
+			queue := open()
+			queue.Enqueue(&recordedJob{Name: "survivor"})
+			queue.Close()
+
+			queue = open()
+			pool := NewPool(ctx, 1, WithQueue(queue))
+			pool.Submit(&recordedJob{Name: "new"})
+			pool.Shutdown()
+			convey.So(queue.Len(), convey.ShouldEqual, 0)
+			queue.Close()
+
+			convey.So(recordedNames(), convey.ShouldResemble, []string{"survivor", "new"})
+		})
+
+		convey.Convey("Should refuse jobs of a type that is not registered", func() {
+			queue := open()
+			defer queue.Close()
+			_, err := queue.Enqueue(DummyJob{})
+			convey.So(err, convey.ShouldNotBeNil)
+		})
+	})
+}
+
+// crashWhileEnqueueing writes jobs as fast as it can, until the process is killed.
+func crashWhileEnqueueing(dir string) {
+	queue, err := OpenFileQueue(FileQueueConfig{Dir: dir, Registry: testRegistry(), Sync: SyncNever})
+	if err != nil {
+		os.Exit(1)
+	}
+
+	for idx := 0; ; idx++ {
+		queue.Enqueue(&recordedJob{Name: fmt.Sprint(idx)})
+	}
+}
+
+func BenchmarkFileQueue(b *testing.B) {
+	queue, _ := OpenFileQueue(FileQueueConfig{Dir: b.TempDir(), Registry: testRegistry(), Sync: SyncNever})
+	defer queue.Close()
+	ctx := context.Background()
+
+	for i := 0; i < b.N; i++ {
+		queue.Enqueue(&recordedJob{Name: "benchmark"})
+		delivery, _ := queue.Dequeue(ctx)
+		queue.Ack(delivery.ID)
+	}
+}
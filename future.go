@@ -1,9 +1,18 @@
 package twoface
 
 import (
+	"context"
+	"errors"
 	"sync"
+	"time"
 )
 
+/*
+ErrNilFuture is the error of the Future of ThenCompose when its function returns nil
+instead of a Future.
+*/
+var ErrNilFuture = errors.New("the function returned a nil Future")
+
 /*
 Future represents a value that will be available at some point in the future.
 
@@ -13,10 +22,25 @@ f := NewFuture[int]()
 result, err := f.Result()
 */
 type Future[T any] struct {
-	result T
-	err    error
-	done   chan struct{}
-	mu     sync.Mutex
+	result    T
+	err       error
+	done      chan struct{}
+	mu        sync.Mutex
+	callbacks []futureCallback
+	draining  bool
+	executor  Executor
+	ctx       context.Context
+	cancel    context.CancelFunc
+	cancelled bool
+	cancelers []func()
+	settlers  []func()
+	// dependents is the number of futures that were derived from this one, and were not
+	// cancelled yet.
+	dependents int
+	// awaiters is the number of callers that are blocked in Result or Await.
+	awaiters int
+	start    func()
+	starting sync.Once
 }
 
 /*
@@ -27,13 +51,22 @@ Example:
 f := NewFuture[int]()
 */
 func NewFuture[T any]() *Future[T] {
+	return newFuture[T](context.Background())
+}
+
+func newFuture[T any](ctx context.Context) *Future[T] {
+	ctx, cancel := context.WithCancel(ctx)
+
 	return &Future[T]{
-		done: make(chan struct{}),
+		done:   make(chan struct{}),
+		ctx:    ctx,
+		cancel: cancel,
 	}
 }
 
 /*
 Result blocks until the future value is available and returns it.
+A lazy Future, as created by Lazy, starts its work on the first call.
 
 Example:
 
@@ -41,12 +74,153 @@ f := NewFuture[int]()
 result, err := f.Result()
 */
 func (f *Future[T]) Result() (T, error) {
+	f.begin()
+
+	release := f.await()
 	<-f.done
+	release()
+
 	return f.result, f.err
 }
 
+/*
+Await blocks until the future value is available, or until the context is done, in
+which case it returns the context error. Giving up on a Future does not affect it, so
+it can still be awaited again later.
+
+Example:
+
+f := NewFuture[int]()
+result, err := f.Await(ctx)
+*/
+func (f *Future[T]) Await(ctx context.Context) (T, error) {
+	f.begin()
+
+	release := f.await()
+	defer release()
+
+	select {
+	case <-f.done:
+		return f.result, f.err
+	case <-ctx.Done():
+		var zero T
+		return zero, ctx.Err()
+	}
+}
+
+/*
+ResultTimeout blocks until the future value is available, or until the timeout has
+passed, in which case it returns context.DeadlineExceeded.
+
+Example:
+
+f := NewFuture[int]()
+result, err := f.ResultTimeout(time.Second)
+*/
+func (f *Future[T]) ResultTimeout(timeout time.Duration) (T, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	return f.Await(ctx)
+}
+
+/*
+Done returns a channel that is closed once the future value is available, so a Future
+can be waited on in a select statement. Like Result and Await, it starts a lazy Future.
+
+Example:
+
+f := NewFuture[int]()
+
+	select {
+	case <-f.Done():
+	    result, err := f.Result()
+	case <-time.After(time.Second):
+	}
+*/
+func (f *Future[T]) Done() <-chan struct{} {
+	f.begin()
+	return f.done
+}
+
+/*
+IsDone returns true if the future value is available.
+
+Example:
+
+f := NewFuture[int]()
+fmt.Println(f.IsDone()) // false
+*/
+func (f *Future[T]) IsDone() bool {
+	select {
+	case <-f.done:
+		return true
+	default:
+		return false
+	}
+}
+
+/*
+Poll returns the outcome of the Future without blocking, or None if it is not available yet.
+
+Example:
+
+p, f := NewPromise[int]()
+p.Set(42, nil)
+fmt.Println(f.Poll().UnwrapOrZero().Unwrap()) // 42
+*/
+func (f *Future[T]) Poll() Option[Result[T, error]] {
+	if !f.IsDone() {
+		return None[Result[T, error]]()
+	}
+	return Some(f.settled())
+}
+
+/*
+Cancel tells the producer that the value is no longer needed, by completing the Future
+with context.Canceled and cancelling the context of its Promise. Cancelling a Future
+that was derived from others, like the ones returned by ThenApply or All, also cancels
+the futures it was derived from, once no other Future that was derived from them is left
+that was not cancelled, and no caller is waiting for them in Result or Await. It returns
+false if the Future was already completed.
+
+Example:
+
+f := Async(ctx, slowComputation)
+f.Cancel()
+_, err := f.Result() // context.Canceled
+*/
+func (f *Future[T]) Cancel() bool {
+	var zero T
+	cancelers, ok := f.settle(zero, context.Canceled, true)
+
+	for _, canceler := range cancelers {
+		canceler()
+	}
+
+	return ok
+}
+
+/*
+WithExecutor sets the Executor that runs the callbacks of the Future, and of the futures
+derived from it. Without one, callbacks run on a new goroutine. A Pool that was shut down
+refuses the callbacks, and then the derived futures fail with ErrPoolShutdown, while the
+handlers of Then, Catch and Finally are not called.
+
+Example:
+
+f := NewFuture[int]().WithExecutor(InlineExecutor)
+*/
+func (f *Future[T]) WithExecutor(executor Executor) *Future[T] {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.executor = executor
+	return f
+}
+
 /*
 Then adds a handler to be called when the Future is completed successfully.
+Handlers of all kinds, including the ones of Catch and Finally, are called one after
+the other, in the order they were added, also when they are added after completion.
 
 Example:
 
@@ -54,19 +228,17 @@ f := NewFuture[int]()
 f.Then(func(result int) { fmt.Println(result) })
 */
 func (f *Future[T]) Then(handler func(T)) *Future[T] {
-	go func() {
-		<-f.done
-		f.mu.Lock()
-		defer f.mu.Unlock()
+	f.onComplete(func() {
 		if f.err == nil {
 			handler(f.result)
 		}
-	}()
+	})
 	return f
 }
 
 /*
-Catch adds a handler to be called if the Future is completed with an error.
+Catch adds a handler to be called if the Future is completed with an error, in order
+with the other handlers, like Then.
 
 Example:
 
@@ -74,19 +246,17 @@ f := NewFuture[int]()
 f.Catch(func(err error) { fmt.Println(err) })
 */
 func (f *Future[T]) Catch(handler func(error)) *Future[T] {
-	go func() {
-		<-f.done
-		f.mu.Lock()
-		defer f.mu.Unlock()
+	f.onComplete(func() {
 		if f.err != nil {
 			handler(f.err)
 		}
-	}()
+	})
 	return f
 }
 
 /*
-Finally adds a handler to be called when the Future is completed, regardless of success or failure.
+Finally adds a handler to be called when the Future is completed, regardless of success or failure,
+in order with the other handlers, like Then.
 
 Example:
 
@@ -94,15 +264,113 @@ f := NewFuture[int]()
 f.Finally(func() { fmt.Println("Completed") })
 */
 func (f *Future[T]) Finally(handler func()) *Future[T] {
-	go func() {
-		<-f.done
-		f.mu.Lock()
-		defer f.mu.Unlock()
-		handler()
-	}()
+	f.onComplete(handler)
 	return f
 }
 
+/*
+Recover returns a new Future that completes with the same value as this one, or, if
+this one fails, with the outcome of the handler. The handler can return an error of
+its own to leave the failure unrecovered.
+
+Example:
+
+f := fetch().Recover(func(err error) (int, error) { return 0, nil })
+*/
+func (f *Future[T]) Recover(handler func(error) (T, error)) *Future[T] {
+	promise, future := derive[T, T](f)
+
+	f.onCompleteOr(func() {
+		if f.err != nil {
+			promise.Set(handler(f.err))
+			return
+		}
+		promise.Set(f.result, nil)
+	}, promise.fail)
+
+	return future
+}
+
+/*
+WhenComplete returns a new Future that completes with the same outcome as this one,
+but only after the handler has been called with it.
+
+Example:
+
+f := fetch().WhenComplete(func(result int, err error) { log.Println(result, err) })
+*/
+func (f *Future[T]) WhenComplete(handler func(T, error)) *Future[T] {
+	promise, future := derive[T, T](f)
+
+	f.onCompleteOr(func() {
+		handler(f.result, f.err)
+		promise.Set(f.result, f.err)
+	}, promise.fail)
+
+	return future
+}
+
+/*
+ThenApply returns a new Future that completes with the outcome of applying the function
+to the value of the given Future. If the given Future fails, the function is not called
+and the new Future fails with the same error.
+
+Example:
+
+f := ThenApply(fetch(), func(v int) (string, error) { return strconv.Itoa(v), nil })
+*/
+func ThenApply[T any, U any](f *Future[T], fn func(T) (U, error)) *Future[U] {
+	promise, future := derive[T, U](f)
+
+	f.onCompleteOr(func() {
+		if f.err != nil {
+			var zero U
+			promise.Set(zero, f.err)
+			return
+		}
+		promise.Set(fn(f.result))
+	}, promise.fail)
+
+	return future
+}
+
+/*
+ThenCompose returns a new Future that completes with the outcome of the Future that
+the function returns for the value of the given Future. It is the flattening version
+of ThenApply, for steps that are asynchronous themselves. When the function returns nil,
+the new Future fails with ErrNilFuture.
+
+Example:
+
+f := ThenCompose(fetchID(), func(id int) *Future[User] { return fetchUser(id) })
+*/
+func ThenCompose[T any, U any](f *Future[T], fn func(T) *Future[U]) *Future[U] {
+	promise, future := derive[T, U](f)
+
+	f.onCompleteOr(func() {
+		if f.err != nil {
+			var zero U
+			promise.Set(zero, f.err)
+			return
+		}
+
+		next := fn(f.result)
+		if next == nil {
+			var zero U
+			promise.Set(zero, ErrNilFuture)
+			return
+		}
+
+		next.begin()
+		future.onCancel(next.depend())
+		next.onCompleteOr(func() {
+			promise.Set(next.result, next.err)
+		}, promise.fail)
+	}, promise.fail)
+
+	return future
+}
+
 /*
 Promise represents a writable, single-assignment container for a Future.
 
@@ -114,7 +382,6 @@ result, err := f.Result()
 */
 type Promise[T any] struct {
 	future *Future[T]
-	once   sync.Once
 }
 
 /*
@@ -125,12 +392,45 @@ Example:
 p, f := NewPromise[int]()
 */
 func NewPromise[T any]() (*Promise[T], *Future[T]) {
-	future := NewFuture[T]()
+	return NewPromiseWithContext[T](context.Background())
+}
+
+/*
+NewPromiseWithContext creates a new Promise and its associated Future, where the context
+of the Promise is derived from the given one.
+
+Example:
+
+p, f := NewPromiseWithContext[int](ctx)
+*/
+func NewPromiseWithContext[T any](ctx context.Context) (*Promise[T], *Future[T]) {
+	future := newFuture[T](ctx)
 	return &Promise[T]{future: future}, future
 }
 
 /*
-Set sets the value of the Future, completing it.
+Context returns a context that is done once the Future is completed, whether by the
+Promise or because the Future was cancelled, so the producer knows when it can stop.
+
+Example:
+
+p, f := NewPromise[int]()
+
+	go func() {
+	    select {
+	    case <-p.Context().Done():
+	        return
+	    case value := <-work:
+	        p.Set(value, nil)
+	    }
+	}()
+*/
+func (p *Promise[T]) Context() context.Context {
+	return p.future.ctx
+}
+
+/*
+Set sets the value of the Future, completing it. Only the first call has any effect.
 
 Example:
 
@@ -138,11 +438,304 @@ p, f := NewPromise[int]()
 p.Set(42, nil)
 */
 func (p *Promise[T]) Set(result T, err error) {
-	p.once.Do(func() {
-		p.future.mu.Lock()
-		defer p.future.mu.Unlock()
-		p.future.result = result
-		p.future.err = err
-		close(p.future.done)
-	})
+	p.future.complete(result, err)
+}
+
+/*
+fail completes the Future with the error.
+*/
+func (p *Promise[T]) fail(err error) {
+	var zero T
+	p.future.complete(zero, err)
+}
+
+/*
+derive creates a Promise for a Future that follows on from the given one, and so
+inherits its Executor, cancels it when cancelled, unless other futures still depend on
+it, and starts it when started.
+*/
+func derive[T any, U any](f *Future[T]) (*Promise[U], *Future[U]) {
+	promise, future := NewPromise[U]()
+
+	f.mu.Lock()
+	future.executor = f.executor
+	f.mu.Unlock()
+
+	future.onCancel(f.depend())
+	future.start = f.begin
+
+	return promise, future
+}
+
+/*
+depend registers a Future that depends on this one, and returns the function that cancels
+this one on its behalf. This one is only cancelled once every dependent has asked for it,
+and no caller is blocked waiting for it, so cancelling one dependent never fails another
+consumer.
+*/
+func (f *Future[T]) depend() func() {
+	f.mu.Lock()
+	f.dependents++
+	f.mu.Unlock()
+
+	var once sync.Once
+
+	return func() {
+		once.Do(func() {
+			f.mu.Lock()
+			f.dependents--
+			last := f.dependents == 0 && f.awaiters == 0
+			f.mu.Unlock()
+
+			if last {
+				f.Cancel()
+			}
+		})
+	}
+}
+
+/*
+await registers a caller that blocks until the Future is completed, and returns the
+function that unregisters it.
+*/
+func (f *Future[T]) await() func() {
+	f.mu.Lock()
+	f.awaiters++
+	f.mu.Unlock()
+
+	return func() {
+		f.mu.Lock()
+		f.awaiters--
+		f.mu.Unlock()
+	}
+}
+
+/*
+begin starts the work of a lazy Future, the first time it is called.
+*/
+func (f *Future[T]) begin() {
+	if f.start != nil {
+		f.starting.Do(f.start)
+	}
+}
+
+/*
+complete sets the outcome of the Future and runs the callbacks that are waiting for it.
+It returns false if the Future was already completed.
+*/
+func (f *Future[T]) complete(result T, err error) bool {
+	_, ok := f.settle(result, err, false)
+	return ok
+}
+
+/*
+settle does the work of complete and Cancel. When the Future is cancelled, it returns
+the cancelers that need to run, otherwise they are simply released.
+*/
+func (f *Future[T]) settle(result T, err error, cancelled bool) ([]func(), bool) {
+	f.mu.Lock()
+
+	select {
+	case <-f.done:
+		f.mu.Unlock()
+		return nil, false
+	default:
+	}
+
+	f.result = result
+	f.err = err
+	f.cancelled = cancelled
+	close(f.done)
+	f.cancel()
+
+	drain := f.startDraining()
+	cancelers := f.cancelers
+	f.cancelers = nil
+	settlers := f.settlers
+	f.settlers = nil
+	executor := f.executorOrDefault()
+	f.mu.Unlock()
+
+	for _, settler := range settlers {
+		settler()
+	}
+
+	if drain {
+		f.startDrain(executor)
+	}
+
+	if !cancelled {
+		cancelers = nil
+	}
+
+	return cancelers, true
+}
+
+/*
+onCancel registers a function to run when the Future is cancelled, or runs it straight
+away if that already happened.
+*/
+func (f *Future[T]) onCancel(canceler func()) {
+	f.mu.Lock()
+
+	select {
+	case <-f.done:
+		cancelled := f.cancelled
+		f.mu.Unlock()
+		if cancelled {
+			canceler()
+		}
+		return
+	default:
+	}
+
+	f.cancelers = append(f.cancelers, canceler)
+	f.mu.Unlock()
+}
+
+/*
+onSettled registers a function that runs on the goroutine that completes the Future, right
+after it is completed, or straight away if that already happened. Unlike a callback, it
+does not go through the Executor, so it also runs when that refuses to, and has to be quick.
+*/
+func (f *Future[T]) onSettled(settler func()) {
+	f.mu.Lock()
+
+	select {
+	case <-f.done:
+		f.mu.Unlock()
+		settler()
+		return
+	default:
+	}
+
+	f.settlers = append(f.settlers, settler)
+	f.mu.Unlock()
+}
+
+/*
+futureCallback is a callback of a Future, with the function that completes the Future
+that waits for it, for when the Executor refuses to run it.
+*/
+type futureCallback struct {
+	run     func()
+	refused func(error)
+}
+
+/*
+onComplete registers a callback to run once the Future is completed. Callbacks run one
+after the other, in the order they were registered, on a task of the Executor, also when
+they are registered after completion.
+*/
+func (f *Future[T]) onComplete(callback func()) {
+	f.onCompleteOr(callback, nil)
+}
+
+/*
+onCompleteOr registers a callback like onComplete, together with refused, which completes
+a derived Future with the error of the Executor, when that refuses to run the callback.
+*/
+func (f *Future[T]) onCompleteOr(callback func(), refused func(error)) {
+	f.mu.Lock()
+	f.callbacks = append(f.callbacks, futureCallback{run: callback, refused: refused})
+
+	select {
+	case <-f.done:
+	default:
+		f.mu.Unlock()
+		return
+	}
+
+	drain := f.startDraining()
+	executor := f.executorOrDefault()
+	f.mu.Unlock()
+
+	if drain {
+		f.startDrain(executor)
+	}
+}
+
+/*
+startDraining reports whether a task has to be started to run the callbacks, which is
+when there are any, and no task is running them already. It has to be called with the
+lock held, once the Future is completed.
+*/
+func (f *Future[T]) startDraining() bool {
+	if f.draining || len(f.callbacks) == 0 {
+		return false
+	}
+
+	f.draining = true
+	return true
+}
+
+/*
+startDrain hands the task that runs the callbacks to the Executor. An Executor that can
+refuse it, like a Pool that was shut down, has the callbacks refused instead.
+*/
+func (f *Future[T]) startDrain(executor Executor) {
+	if refusing, ok := executor.(refusingExecutor); ok {
+		refusing.executeOr(f.drain, f.refuse)
+		return
+	}
+
+	executor.Execute(f.drain)
+}
+
+/*
+drain runs the callbacks until none are left, including the ones that are registered
+while it runs, so they all run in order, and never at the same time.
+*/
+func (f *Future[T]) drain() {
+	for {
+		f.mu.Lock()
+
+		if len(f.callbacks) == 0 {
+			f.draining = false
+			f.mu.Unlock()
+			return
+		}
+
+		callback := f.callbacks[0]
+		f.callbacks = f.callbacks[1:]
+		f.mu.Unlock()
+
+		callback.run()
+	}
+}
+
+/*
+refuse fails the derived futures that wait for the callbacks, because the Executor refused
+to run them. Handlers, like the ones of Then, are not called, as there is nothing left to
+run them on.
+*/
+func (f *Future[T]) refuse(err error) {
+	f.mu.Lock()
+	callbacks := f.callbacks
+	f.callbacks = nil
+	f.draining = false
+	f.mu.Unlock()
+
+	for _, callback := range callbacks {
+		if callback.refused != nil {
+			callback.refused(err)
+		}
+	}
+}
+
+func (f *Future[T]) executorOrDefault() Executor {
+	if f.executor == nil {
+		return GoExecutor
+	}
+	return f.executor
+}
+
+/*
+settled returns the outcome of a completed Future as a Result.
+*/
+func (f *Future[T]) settled() Result[T, error] {
+	if f.err != nil {
+		return Err[T](f.err)
+	}
+	return Ok[T, error](f.result)
 }
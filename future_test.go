@@ -1,7 +1,10 @@
 package twoface
 
 import (
+	"context"
 	"errors"
+	"strconv"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -41,6 +44,265 @@ func TestFuture(t *testing.T) {
 			p.Set(42, nil)
 			convey.So(<-ch, convey.ShouldBeTrue)
 		})
+
+		convey.Convey("Should Await the result", func() {
+			p, f := NewPromise[int]()
+			go p.Set(42, nil)
+			result, err := f.Await(context.Background())
+			convey.So(result, convey.ShouldEqual, 42)
+			convey.So(err, convey.ShouldBeNil)
+		})
+
+		convey.Convey("Should stop Awaiting when the context is done", func() {
+			p, f := NewPromise[int]()
+			ctx, cancel := context.WithCancel(context.Background())
+			cancel()
+			_, err := f.Await(ctx)
+			convey.So(err, convey.ShouldEqual, context.Canceled)
+
+			p.Set(42, nil)
+			result, err := f.Await(context.Background())
+			convey.So(result, convey.ShouldEqual, 42)
+			convey.So(err, convey.ShouldBeNil)
+		})
+
+		convey.Convey("Should time out with ResultTimeout", func() {
+			_, f := NewPromise[int]()
+			_, err := f.ResultTimeout(time.Millisecond)
+			convey.So(err, convey.ShouldEqual, context.DeadlineExceeded)
+		})
+
+		convey.Convey("Should report completion with Done and IsDone", func() {
+			p, f := NewPromise[int]()
+			convey.So(f.IsDone(), convey.ShouldBeFalse)
+			p.Set(42, nil)
+			<-f.Done()
+			convey.So(f.IsDone(), convey.ShouldBeTrue)
+		})
+
+		convey.Convey("Should Poll without blocking", func() {
+			p, f := NewPromise[int]()
+			convey.So(f.Poll().IsNone(), convey.ShouldBeTrue)
+			p.Set(0, errDummy)
+			convey.So(f.Poll().Expect("should be done").UnwrapErr(), convey.ShouldEqual, errDummy)
+		})
+
+		convey.Convey("Should call handlers in the order they were added", func() {
+			p, f := NewPromise[int]()
+			ch := make(chan string, 3)
+			f.Then(func(int) { ch <- "then" }).
+				Catch(func(error) { ch <- "catch" }).
+				Finally(func() { ch <- "finally" })
+			f.Then(func(int) { ch <- "then again" })
+			p.Set(42, nil)
+			convey.So([]string{<-ch, <-ch, <-ch}, convey.ShouldResemble, []string{"then", "finally", "then again"})
+		})
+
+		convey.Convey("Should call handlers added after completion", func() {
+			p, f := NewPromise[int]()
+			p.Set(42, nil)
+			ch := make(chan int)
+			f.Then(func(result int) { ch <- result })
+			convey.So(<-ch, convey.ShouldEqual, 42)
+		})
+
+		convey.Convey("Should call handlers added after completion in order, one at a time", func() {
+			p, f := NewPromise[int]()
+			p.Set(42, nil)
+
+			var running, overlapped atomic.Int32
+			order := make(chan int, 50)
+
+			for idx := range 50 {
+				f.Then(func(int) {
+					if running.Add(1) > 1 {
+						overlapped.Add(1)
+					}
+					order <- idx
+					running.Add(-1)
+				})
+			}
+
+			for idx := range 50 {
+				convey.So(<-order, convey.ShouldEqual, idx)
+			}
+			convey.So(overlapped.Load(), convey.ShouldEqual, 0)
+		})
+
+		convey.Convey("Should run handlers on the given Executor", func() {
+			p, f := NewPromise[int]()
+			var order []int
+			f.WithExecutor(InlineExecutor)
+			f.Then(func(result int) { order = append(order, result) })
+			ThenApply(f, func(v int) (int, error) { return v + 1, nil }).
+				Then(func(result int) { order = append(order, result) })
+			p.Set(42, nil)
+			convey.So(order, convey.ShouldResemble, []int{42, 43})
+		})
+
+		convey.Convey("Should transform the value with ThenApply", func() {
+			p, f := NewPromise[int]()
+			applied := ThenApply(f, func(v int) (string, error) { return strconv.Itoa(v * 2), nil })
+			p.Set(21, nil)
+			result, err := applied.Result()
+			convey.So(result, convey.ShouldEqual, "42")
+			convey.So(err, convey.ShouldBeNil)
+		})
+
+		convey.Convey("Should propagate errors through ThenApply", func() {
+			p, f := NewPromise[int]()
+			called := false
+			applied := ThenApply(f, func(v int) (string, error) {
+				called = true
+				return "", nil
+			})
+			p.Set(0, errDummy)
+			_, err := applied.Result()
+			convey.So(err, convey.ShouldEqual, errDummy)
+			convey.So(called, convey.ShouldBeFalse)
+		})
+
+		convey.Convey("Should flatten futures with ThenCompose", func() {
+			p, f := NewPromise[int]()
+			composed := ThenCompose(f, func(v int) *Future[string] {
+				inner, future := NewPromise[string]()
+				go inner.Set(strconv.Itoa(v), nil)
+				return future
+			})
+			p.Set(42, nil)
+			result, err := composed.Result()
+			convey.So(result, convey.ShouldEqual, "42")
+			convey.So(err, convey.ShouldBeNil)
+		})
+
+		convey.Convey("Should fail a ThenCompose whose function returns a nil Future", func() {
+			p, f := NewPromise[int]()
+			composed := ThenCompose(f, func(v int) *Future[string] { return nil })
+			p.Set(42, nil)
+			_, err := composed.Result()
+			convey.So(err, convey.ShouldEqual, ErrNilFuture)
+		})
+
+		convey.Convey("Should turn an error into a value with Recover", func() {
+			p, f := NewPromise[int]()
+			recovered := f.Recover(func(err error) (int, error) { return 99, nil })
+			p.Set(0, errDummy)
+			result, err := recovered.Result()
+			convey.So(result, convey.ShouldEqual, 99)
+			convey.So(err, convey.ShouldBeNil)
+
+			_, err = f.Result()
+			convey.So(err, convey.ShouldEqual, errDummy)
+		})
+
+		convey.Convey("Should call the handler before completing with WhenComplete", func() {
+			p, f := NewPromise[int]()
+			var seen int
+			completed := f.WhenComplete(func(result int, err error) { seen = result })
+			p.Set(42, nil)
+			result, err := completed.Result()
+			convey.So(result, convey.ShouldEqual, 42)
+			convey.So(err, convey.ShouldBeNil)
+			convey.So(seen, convey.ShouldEqual, 42)
+		})
+
+		convey.Convey("Should Cancel and signal the producer", func() {
+			p, f := NewPromise[int]()
+			convey.So(p.Context().Err(), convey.ShouldBeNil)
+			convey.So(f.Cancel(), convey.ShouldBeTrue)
+			convey.So(f.Cancel(), convey.ShouldBeFalse)
+			<-p.Context().Done()
+
+			p.Set(42, nil)
+			_, err := f.Result()
+			convey.So(err, convey.ShouldEqual, context.Canceled)
+		})
+
+		convey.Convey("Should not Cancel a completed Future", func() {
+			p, f := NewPromise[int]()
+			p.Set(42, nil)
+			convey.So(f.Cancel(), convey.ShouldBeFalse)
+			result, err := f.Result()
+			convey.So(result, convey.ShouldEqual, 42)
+			convey.So(err, convey.ShouldBeNil)
+		})
+
+		convey.Convey("Should propagate cancellation through derived futures", func() {
+			p, f := NewPromise[int]()
+			applied := ThenApply(f, func(v int) (int, error) { return v, nil })
+			recovered := applied.Recover(func(err error) (int, error) { return 0, err })
+			recovered.Cancel()
+			<-p.Context().Done()
+
+			_, err := applied.Result()
+			convey.So(err, convey.ShouldEqual, context.Canceled)
+		})
+
+		convey.Convey("Should only cancel a shared Future once all of its dependents are", func() {
+			p, f := NewPromise[int]()
+			a := ThenApply(f, func(v int) (int, error) { return v + 1, nil })
+			b := ThenApply(f, func(v int) (int, error) { return v + 2, nil })
+
+			a.Cancel()
+			convey.So(f.IsDone(), convey.ShouldBeFalse)
+
+			p.Set(40, nil)
+			value, err := b.Result()
+			convey.So(err, convey.ShouldBeNil)
+			convey.So(value, convey.ShouldEqual, 42)
+
+			q, g := NewPromise[int]()
+			c := ThenApply(g, func(v int) (int, error) { return v, nil })
+			d := All(context.Background(), g)
+			c.Cancel()
+			convey.So(g.IsDone(), convey.ShouldBeFalse)
+			d.Cancel()
+			<-q.Context().Done()
+		})
+
+		convey.Convey("Should not cancel a shared Future that a caller is waiting for", func() {
+			p, f := NewPromise[int]()
+			applied := ThenApply(f, func(v int) (int, error) { return v, nil })
+
+			waited := make(chan int)
+			go func() {
+				value, _ := f.Result()
+				waited <- value
+			}()
+
+			for {
+				f.mu.Lock()
+				awaiting := f.awaiters > 0
+				f.mu.Unlock()
+				if awaiting {
+					break
+				}
+				time.Sleep(time.Millisecond)
+			}
+
+			applied.Cancel()
+			convey.So(f.IsDone(), convey.ShouldBeFalse)
+
+			p.Set(42, nil)
+			convey.So(<-waited, convey.ShouldEqual, 42)
+		})
+
+		convey.Convey("Should propagate cancellation into a composed Future", func() {
+			p, f := NewPromise[int]()
+			inner, innerFuture := NewPromise[int]()
+			composed := ThenCompose(f.WithExecutor(InlineExecutor), func(v int) *Future[int] { return innerFuture })
+			p.Set(42, nil)
+			composed.Cancel()
+			<-inner.Context().Done()
+		})
+
+		convey.Convey("Should propagate cancellation through combinators", func() {
+			a, fa := NewPromise[int]()
+			b, fb := NewPromise[int]()
+			All(context.Background(), fa, fb).Cancel()
+			<-a.Context().Done()
+			<-b.Context().Done()
+		})
 	})
 }
 
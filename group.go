@@ -0,0 +1,232 @@
+package twoface
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+/*
+Group runs a collection of tasks, each receiving a context that is derived from the
+one the Group was created with. By default the Group fails fast, the first task that
+fails cancels the context of all the others. Tasks run on their own goroutine, unless
+the Group is given an Executor, like a Pool. Groups can be nested, where the tasks of
+a subgroup also count as tasks of its parent.
+
+Example:
+
+	group := NewGroup(ctx).WithLimit(4)
+	for _, url := range urls {
+	    group.Go(func(ctx context.Context) error { return fetch(ctx, url) })
+	}
+	result := group.Wait()
+*/
+type Group struct {
+	ctx      context.Context
+	cancel   context.CancelFunc
+	parent   *Group
+	executor Executor
+	mode     ErrorMode
+	slots    chan struct{}
+	wg       sync.WaitGroup
+	mu       sync.Mutex
+	errs     []error
+	count    int
+}
+
+/*
+NewGroup creates an empty Group.
+
+Example:
+
+	group := NewGroup(ctx)
+*/
+func NewGroup(ctx context.Context) *Group {
+	ctx, cancel := context.WithCancel(ctx)
+
+	return &Group{
+		ctx:      ctx,
+		cancel:   cancel,
+		executor: GoExecutor,
+		mode:     FailFast,
+	}
+}
+
+/*
+WithExecutor sets the Executor that the tasks run on.
+
+Example:
+
+	group := NewGroup(ctx).WithExecutor(pool)
+*/
+func (group *Group) WithExecutor(executor Executor) *Group {
+	group.executor = executor
+	return group
+}
+
+/*
+WithLimit sets the maximum number of tasks that run at once. Go blocks while the limit
+is reached. A limit of zero or less means no limit.
+
+Example:
+
+	group := NewGroup(ctx).WithLimit(4)
+*/
+func (group *Group) WithLimit(limit int) *Group {
+	group.slots = nil
+
+	if limit > 0 {
+		group.slots = make(chan struct{}, limit)
+	}
+
+	return group
+}
+
+/*
+WithErrorMode sets whether the first failure cancels the other tasks, with FailFast,
+or all tasks run regardless, with CollectAll.
+
+Example:
+
+	group := NewGroup(ctx).WithErrorMode(CollectAll)
+*/
+func (group *Group) WithErrorMode(mode ErrorMode) *Group {
+	group.mode = mode
+	return group
+}
+
+/*
+Context returns the context that the tasks of the Group receive.
+
+Example:
+
+	ctx := group.Context()
+*/
+func (group *Group) Context() context.Context {
+	return group.ctx
+}
+
+/*
+Sub creates a subgroup, whose context is derived from the context of the Group, and whose
+tasks and failures also count for the Group. It runs on the same Executor, with the same
+error mode, but without a limit of its own.
+
+Example:
+
+	sub := group.Sub()
+	sub.Go(task)
+	sub.Wait()  // waits for the tasks of the subgroup
+	group.Wait() // waits for the tasks of both
+*/
+func (group *Group) Sub() *Group {
+	sub := NewGroup(group.ctx)
+	sub.parent = group
+	sub.executor = group.executor
+	sub.mode = group.mode
+	return sub
+}
+
+/*
+Go runs the task as part of the Group.
+
+Example:
+
+	group.Go(func(ctx context.Context) error { return fetch(ctx, url) })
+*/
+func (group *Group) Go(task func(context.Context) error) {
+	GoFuture(group, func(ctx context.Context) (struct{}, error) {
+		return struct{}{}, task(ctx)
+	})
+}
+
+/*
+GoFuture runs the function as a task of the Group, and returns a Future for its outcome,
+for when the result of every task matters, and not only whether they all succeeded.
+
+Example:
+
+	f := GoFuture(group, func(ctx context.Context) (User, error) { return fetchUser(ctx, id) })
+	group.Wait()
+	user, err := f.Result()
+*/
+func GoFuture[T any](group *Group, fn func(context.Context) (T, error)) *Future[T] {
+	group.add()
+
+	if group.slots != nil && !acquire(group.ctx, group.slots) {
+		promise, future := NewPromise[T]()
+		var zero T
+		promise.Set(zero, group.ctx.Err())
+		group.done(group.ctx.Err())
+		return future
+	}
+
+	future := AsyncOn(group.ctx, group.executor, fn)
+
+	future.onComplete(func() {
+		if group.slots != nil {
+			<-group.slots
+		}
+		group.done(future.err)
+	})
+
+	return future
+}
+
+/*
+Wait blocks until all tasks of the Group, including the ones of its subgroups, are done,
+and then cancels its context. The Result holds the number of tasks on success. With
+FailFast, the error is the first failure. With CollectAll, it is all failures joined.
+
+Example:
+
+	if result := group.Wait(); result.IsErr() {
+	    log.Println(result.UnwrapErr())
+	}
+*/
+func (group *Group) Wait() Result[int, error] {
+	group.wg.Wait()
+	group.cancel()
+
+	group.mu.Lock()
+	defer group.mu.Unlock()
+
+	if len(group.errs) == 0 {
+		return Ok[int, error](group.count)
+	}
+
+	if group.mode == FailFast {
+		return Err[int](group.errs[0])
+	}
+
+	return Err[int](errors.Join(group.errs...))
+}
+
+/*
+add accounts for a new task, in the Group and all of its parents.
+*/
+func (group *Group) add() {
+	for current := group; current != nil; current = current.parent {
+		current.wg.Add(1)
+	}
+}
+
+/*
+done accounts for a finished task, in the Group and all of its parents, and records its
+failure. With FailFast, a failure cancels the context of the groups it is recorded in.
+*/
+func (group *Group) done(err error) {
+	for current := group; current != nil; current = current.parent {
+		current.mu.Lock()
+		current.count++
+		if err != nil {
+			current.errs = append(current.errs, err)
+		}
+		current.mu.Unlock()
+
+		if err != nil && current.mode == FailFast {
+			current.cancel()
+		}
+
+		current.wg.Done()
+	}
+}
@@ -0,0 +1,125 @@
+package twoface
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/smartystreets/goconvey/convey"
+)
+
+func TestGroup(t *testing.T) {
+	convey.Convey("Group", t, func() {
+		ctx := context.Background()
+
+		waitForCancel := func(ctx context.Context) error {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(time.Second):
+				return nil
+			}
+		}
+
+		convey.Convey("Should wait for all tasks", func() {
+			group := NewGroup(ctx)
+			var count atomic.Int64
+			for range 10 {
+				group.Go(func(ctx context.Context) error {
+					count.Add(1)
+					return nil
+				})
+			}
+			result := group.Wait()
+			convey.So(result.Unwrap(), convey.ShouldEqual, 10)
+			convey.So(count.Load(), convey.ShouldEqual, 10)
+			convey.So(group.Context().Err(), convey.ShouldEqual, context.Canceled)
+		})
+
+		convey.Convey("Should cancel the other tasks on the first failure", func() {
+			group := NewGroup(ctx)
+			group.Go(waitForCancel)
+			group.Go(func(ctx context.Context) error { return errDummy })
+			convey.So(group.Wait().UnwrapErr(), convey.ShouldEqual, errDummy)
+		})
+
+		convey.Convey("Should collect all failures with CollectAll", func() {
+			other := errors.New("other error")
+			group := NewGroup(ctx).WithErrorMode(CollectAll)
+			var finished atomic.Bool
+			group.Go(func(ctx context.Context) error { return errDummy })
+			group.Go(func(ctx context.Context) error {
+				time.Sleep(10 * time.Millisecond)
+				finished.Store(ctx.Err() == nil)
+				return other
+			})
+
+			err := group.Wait().UnwrapErr()
+			convey.So(errors.Is(err, errDummy), convey.ShouldBeTrue)
+			convey.So(errors.Is(err, other), convey.ShouldBeTrue)
+			convey.So(finished.Load(), convey.ShouldBeTrue)
+		})
+
+		convey.Convey("Should limit the number of tasks that run at once", func() {
+			group := NewGroup(ctx).WithLimit(2)
+			var running, peak atomic.Int64
+			for range 10 {
+				group.Go(func(ctx context.Context) error {
+					current := running.Add(1)
+					if current > peak.Load() {
+						peak.Store(current)
+					}
+					time.Sleep(2 * time.Millisecond)
+					running.Add(-1)
+					return nil
+				})
+			}
+			group.Wait()
+			convey.So(peak.Load(), convey.ShouldBeLessThanOrEqualTo, 2)
+		})
+
+		convey.Convey("Should report the result of each task through a Future", func() {
+			pool := NewPool(ctx, 2)
+			group := NewGroup(ctx).WithExecutor(pool)
+			f := GoFuture(group, func(ctx context.Context) (int, error) { return 42, nil })
+			convey.So(group.Wait().IsOk(), convey.ShouldBeTrue)
+
+			result, err := f.Result()
+			convey.So(result, convey.ShouldEqual, 42)
+			convey.So(err, convey.ShouldBeNil)
+			pool.Shutdown()
+		})
+
+		convey.Convey("Should recover a panicking task", func() {
+			group := NewGroup(ctx)
+			group.Go(func(ctx context.Context) error { panic("boom") })
+
+			var panicErr *PanicError
+			convey.So(errors.As(group.Wait().UnwrapErr(), &panicErr), convey.ShouldBeTrue)
+		})
+
+		convey.Convey("Should nest groups", func() {
+			group := NewGroup(ctx)
+			sub := group.Sub()
+
+			group.Go(waitForCancel)
+			sub.Go(func(ctx context.Context) error { return errDummy })
+
+			convey.So(sub.Wait().UnwrapErr(), convey.ShouldEqual, errDummy)
+			convey.So(group.Wait().UnwrapErr(), convey.ShouldEqual, errDummy)
+		})
+
+		convey.Convey("Should cancel subgroups with their parent", func() {
+			group := NewGroup(ctx)
+			sub := group.Sub()
+
+			sub.Go(waitForCancel)
+			group.Go(func(ctx context.Context) error { return errDummy })
+
+			convey.So(sub.Wait().UnwrapErr(), convey.ShouldEqual, context.Canceled)
+			convey.So(group.Wait().UnwrapErr(), convey.ShouldEqual, errDummy)
+		})
+	})
+}
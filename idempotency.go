@@ -0,0 +1,325 @@
+package twoface
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+/*
+IdempotentJob is implemented by jobs that can be submitted more than once, for instance
+by an upstream retry, while they should only run once. Jobs with the same key are the
+same logical job.
+
+Example:
+
+	type ChargeJob struct {
+	    Order string
+	}
+
+	func (job ChargeJob) IdempotencyKey() string {
+	    return "charge/" + job.Order
+	}
+*/
+type IdempotentJob interface {
+	Job
+	IdempotencyKey() string
+}
+
+/*
+DedupStore keeps the Results of idempotent jobs that completed, so a duplicate that is
+submitted within the TTL receives the same Result, instead of running again. A store that
+is shared between processes, like Redis, deduplicates across all of them.
+
+Example:
+
+	pool := NewPool(ctx, 4, WithIdempotency(NewMemoryDedupStore(), time.Hour))
+*/
+type DedupStore interface {
+	// Get returns the Result that was stored for the key, unless it has expired.
+	Get(key string) (Option[Result[any, error]], error)
+	// Put stores the Result for the key, until the TTL has passed.
+	Put(key string, result Result[any, error], ttl time.Duration) error
+	// Delete forgets the Result for the key.
+	Delete(key string) error
+}
+
+/*
+WithIdempotency makes SubmitIdempotent deduplicate jobs by their key. The Result of a job
+that completed, including an error, is kept in the store for the TTL.
+
+Example:
+
+	pool := NewPool(ctx, 4, WithIdempotency(NewMemoryDedupStore(), time.Hour))
+*/
+func WithIdempotency(store DedupStore, ttl time.Duration) PoolOption {
+	return func(pool *Pool) {
+		pool.idempotency = &idempotency{store: store, ttl: ttl, inflight: make(map[string]*Future[any])}
+	}
+}
+
+/*
+idempotency holds the futures of idempotent jobs that are still in flight, which cannot go
+into a DedupStore, next to the store with the Results of the jobs that completed.
+*/
+type idempotency struct {
+	store    DedupStore
+	ttl      time.Duration
+	mu       sync.Mutex
+	inflight map[string]*Future[any]
+}
+
+/*
+SubmitIdempotent runs the job on the pool, and returns a Future for its Result. When a job
+with the same key is still in flight, the Future follows that job instead, and when one
+completed within the TTL, it holds its Result. The job never runs in that case. The job
+runs on the context of the pool, so it is shared by every caller, and cancelling the
+context or Future of one caller only fails the Future of that caller. Without
+WithIdempotency, every job runs. Like Futures, these jobs are never put in a Queue.
+
+Example:
+
+	result, err := pool.SubmitIdempotent(ctx, ChargeJob{Order: "42"}).Result()
+*/
+func (pool *Pool) SubmitIdempotent(ctx context.Context, job IdempotentJob) *Future[any] {
+	dedup := pool.idempotency
+	if dedup == nil {
+		return AsyncOn(ctx, pool, func(ctx context.Context) (any, error) {
+			return unpack(job.Do())
+		})
+	}
+
+	promise, future := NewPromiseWithContext[any](ctx)
+	if err := ctx.Err(); err != nil {
+		promise.Set(nil, err)
+		return future
+	}
+
+	key := job.IdempotencyKey()
+
+	dedup.mu.Lock()
+
+	// A shared Future that is done was dropped by the pool, as completed jobs are no longer
+	// in flight.
+	if shared, ok := dedup.inflight[key]; ok && !shared.IsDone() {
+		dedup.mu.Unlock()
+		return follow(ctx, promise, future, shared)
+	}
+
+	stored, err := dedup.store.Get(key)
+	if err != nil || stored.IsSome() {
+		dedup.mu.Unlock()
+
+		if err != nil {
+			promise.Set(nil, fmt.Errorf("cannot deduplicate job %q: %w", key, err))
+		} else {
+			promise.Set(unpack(stored.UnwrapOrZero()))
+		}
+		return future
+	}
+
+	sharedPromise, shared := NewPromiseWithContext[any](pool.ctx)
+	dedup.inflight[key] = shared
+	dedup.mu.Unlock()
+
+	// The shared Future can already be done when the pool is, which runs the callback right away.
+	shared.onComplete(func() { dedup.forget(key, shared) })
+
+	pool.Submit(idempotentJob{job: job, key: key, dedup: dedup, promise: sharedPromise})
+	return follow(ctx, promise, future, shared)
+}
+
+/*
+follow completes the Future of a caller with the outcome of the shared one, or with the
+error of the context of the caller, if that is done first.
+*/
+func follow(ctx context.Context, promise *Promise[any], future *Future[any], shared *Future[any]) *Future[any] {
+	// The context of the promise is also done once the Future completes, when Set is a no-op.
+	context.AfterFunc(promise.Context(), func() { promise.Set(nil, ctx.Err()) })
+	shared.onComplete(func() {
+		if err := ctx.Err(); err != nil {
+			promise.Set(nil, err)
+			return
+		}
+		promise.Set(unpack(shared.settled()))
+	})
+	return future
+}
+
+/*
+ForgetIdempotencyKey makes the pool run the next job with the key, even when a job with
+the key completed within the TTL, for instance to retry one that failed. A job that is
+still in flight keeps its Future.
+
+Example:
+
+	err := pool.ForgetIdempotencyKey("charge/42")
+*/
+func (pool *Pool) ForgetIdempotencyKey(key string) error {
+	if pool.idempotency == nil {
+		return nil
+	}
+
+	return pool.idempotency.store.Delete(key)
+}
+
+/*
+forget removes the job that was in flight for the key, but only if it is still the given
+one.
+*/
+func (dedup *idempotency) forget(key string, future *Future[any]) {
+	dedup.mu.Lock()
+	defer dedup.mu.Unlock()
+
+	if dedup.inflight[key] == future {
+		delete(dedup.inflight, key)
+	}
+}
+
+/*
+idempotentJob runs an IdempotentJob, and stores its Result before it stops being in
+flight, so a duplicate always finds either the one or the other.
+*/
+type idempotentJob struct {
+	job     IdempotentJob
+	key     string
+	dedup   *idempotency
+	promise *Promise[any]
+}
+
+func (job idempotentJob) Do() Result[any, error] {
+	// A job that the pool dropped did not run, so there is no Result to keep.
+	if err := job.promise.Context().Err(); err != nil {
+		job.promise.Set(nil, err)
+		return Err[any](err)
+	}
+
+	value, err := call(job.promise.Context(), func(ctx context.Context) (any, error) {
+		return unpack(job.job.Do())
+	})
+
+	result := Ok[any, error](value)
+	if err != nil {
+		result = Err[any](err)
+	}
+
+	putErr := job.dedup.store.Put(job.key, result, job.dedup.ttl)
+	job.dedup.forget(job.key, job.promise.future)
+	job.promise.Set(value, err)
+
+	if putErr != nil {
+		// The Future still receives the Result, but the worker reports the failure.
+		return Err[any](errors.Join(err, fmt.Errorf("cannot store the result of job %q: %w", job.key, putErr)))
+	}
+
+	return result
+}
+
+func (job idempotentJob) Cancelled() bool {
+	return job.promise.future.IsDone()
+}
+
+func (job idempotentJob) discard(err error) {
+	job.promise.Set(nil, err)
+}
+
+func (job idempotentJob) local() {}
+
+/*
+MemoryDedupStore is a DedupStore that keeps Results in memory, so it only deduplicates
+jobs within a single process. Expired Results are removed as the store grows.
+
+Example:
+
+	store := NewMemoryDedupStore()
+*/
+type MemoryDedupStore struct {
+	mu      sync.Mutex
+	clock   Clock
+	entries map[string]dedupEntry
+	sweep   int
+}
+
+type dedupEntry struct {
+	result  Result[any, error]
+	expires time.Time
+}
+
+/*
+NewMemoryDedupStore creates an empty MemoryDedupStore.
+
+Example:
+
+	store := NewMemoryDedupStore()
+*/
+func NewMemoryDedupStore() *MemoryDedupStore {
+	return &MemoryDedupStore{clock: SystemClock, entries: make(map[string]dedupEntry)}
+}
+
+/*
+Get returns the Result that was stored for the key, or None when there is none, or it
+expired.
+*/
+func (store *MemoryDedupStore) Get(key string) (Option[Result[any, error]], error) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	entry, ok := store.entries[key]
+	if !ok {
+		return None[Result[any, error]](), nil
+	}
+
+	if !store.clock.Now().Before(entry.expires) {
+		delete(store.entries, key)
+		return None[Result[any, error]](), nil
+	}
+
+	return Some(entry.result), nil
+}
+
+/*
+Put stores the Result for the key until the TTL has passed, replacing any Result that
+was stored for it before.
+*/
+func (store *MemoryDedupStore) Put(key string, result Result[any, error], ttl time.Duration) error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	now := store.clock.Now()
+	store.entries[key] = dedupEntry{result: result, expires: now.Add(ttl)}
+
+	// Sweeping every time the store doubles keeps the cost of it constant per Put.
+	if len(store.entries) > store.sweep {
+		for key, entry := range store.entries {
+			if !now.Before(entry.expires) {
+				delete(store.entries, key)
+			}
+		}
+		store.sweep = max(2*len(store.entries), 64)
+	}
+
+	return nil
+}
+
+/*
+Delete forgets the Result for the key. Deleting a key that is not stored does nothing.
+*/
+func (store *MemoryDedupStore) Delete(key string) error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	delete(store.entries, key)
+	return nil
+}
+
+/*
+Len returns the number of Results in the store, including the ones that expired but were
+not removed yet.
+*/
+func (store *MemoryDedupStore) Len() int {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	return len(store.entries)
+}
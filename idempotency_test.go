@@ -0,0 +1,185 @@
+package twoface
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/smartystreets/goconvey/convey"
+)
+
+// chargeJob counts how often it runs, and waits for its gate when it has one.
+type chargeJob struct {
+	order string
+	runs  *atomic.Int32
+	gate  chan struct{}
+	err   error
+}
+
+func (job chargeJob) Do() Result[any, error] {
+	run := job.runs.Add(1)
+
+	if job.gate != nil {
+		<-job.gate
+	}
+
+	if job.err != nil {
+		return Err[any](job.err)
+	}
+	return Ok[any, error](fmt.Sprintf("%s charged %d", job.order, run))
+}
+
+func (job chargeJob) IdempotencyKey() string {
+	return "charge/" + job.order
+}
+
+func TestIdempotency(t *testing.T) {
+	convey.Convey("Idempotency", t, func() {
+		ctx := context.Background()
+		clock := &testClock{now: time.Now()}
+		store := NewMemoryDedupStore()
+		store.clock = clock
+		pool := NewPool(ctx, 4, WithIdempotency(store, time.Minute))
+
+		var runs atomic.Int32
+
+		convey.Convey("Should share the outcome of a job that is in flight", func() {
+			gate := make(chan struct{})
+			first := pool.SubmitIdempotent(ctx, chargeJob{order: "1", runs: &runs, gate: gate})
+			second := pool.SubmitIdempotent(ctx, chargeJob{order: "1", runs: &runs, gate: gate})
+			other := pool.SubmitIdempotent(ctx, chargeJob{order: "2", runs: &runs})
+
+			value, err := other.Result()
+			convey.So(err, convey.ShouldBeNil)
+			convey.So(value, convey.ShouldStartWith, "2 charged")
+
+			close(gate)
+			value, err = second.Result()
+			convey.So(err, convey.ShouldBeNil)
+			convey.So(value, convey.ShouldStartWith, "1 charged")
+
+			original, _ := first.Result()
+			convey.So(original, convey.ShouldEqual, value)
+			convey.So(runs.Load(), convey.ShouldEqual, 2)
+		})
+
+		convey.Convey("Should only fail the caller that cancels", func() {
+			// A single worker that is kept busy leaves the shared job queued.
+			single := NewPool(ctx, 1, WithIdempotency(NewMemoryDedupStore(), time.Minute))
+			defer single.Shutdown()
+
+			busy := make(chan struct{})
+			single.Submit(JobFunc(func() Result[any, error] {
+				<-busy
+				return Ok[any, error](nil)
+			}))
+
+			cancelled, cancel := context.WithCancel(ctx)
+			first := single.SubmitIdempotent(cancelled, chargeJob{order: "1", runs: &runs})
+			second := single.SubmitIdempotent(ctx, chargeJob{order: "1", runs: &runs})
+			third := single.SubmitIdempotent(ctx, chargeJob{order: "1", runs: &runs})
+
+			cancel()
+			third.Cancel()
+			close(busy)
+
+			_, err := first.Result()
+			convey.So(errors.Is(err, context.Canceled), convey.ShouldBeTrue)
+			_, err = third.Result()
+			convey.So(errors.Is(err, context.Canceled), convey.ShouldBeTrue)
+
+			value, err := second.Result()
+			convey.So(err, convey.ShouldBeNil)
+			convey.So(value, convey.ShouldEqual, "1 charged 1")
+			convey.So(runs.Load(), convey.ShouldEqual, 1)
+		})
+
+		convey.Convey("Should return the Result of a job that completed within the TTL", func() {
+			value, err := pool.SubmitIdempotent(ctx, chargeJob{order: "1", runs: &runs}).Result()
+			convey.So(err, convey.ShouldBeNil)
+			convey.So(value, convey.ShouldEqual, "1 charged 1")
+
+			clock.Advance(30 * time.Second)
+			value, err = pool.SubmitIdempotent(ctx, chargeJob{order: "1", runs: &runs}).Result()
+			convey.So(err, convey.ShouldBeNil)
+			convey.So(value, convey.ShouldEqual, "1 charged 1")
+			convey.So(runs.Load(), convey.ShouldEqual, 1)
+
+			clock.Advance(time.Minute)
+			value, err = pool.SubmitIdempotent(ctx, chargeJob{order: "1", runs: &runs}).Result()
+			convey.So(err, convey.ShouldBeNil)
+			convey.So(value, convey.ShouldEqual, "1 charged 2")
+		})
+
+		convey.Convey("Should keep errors until the key is forgotten", func() {
+			_, err := pool.SubmitIdempotent(ctx, chargeJob{order: "1", runs: &runs, err: errDummy}).Result()
+			convey.So(errors.Is(err, errDummy), convey.ShouldBeTrue)
+
+			_, err = pool.SubmitIdempotent(ctx, chargeJob{order: "1", runs: &runs}).Result()
+			convey.So(errors.Is(err, errDummy), convey.ShouldBeTrue)
+			convey.So(runs.Load(), convey.ShouldEqual, 1)
+
+			convey.So(pool.ForgetIdempotencyKey("charge/1"), convey.ShouldBeNil)
+			value, err := pool.SubmitIdempotent(ctx, chargeJob{order: "1", runs: &runs}).Result()
+			convey.So(err, convey.ShouldBeNil)
+			convey.So(value, convey.ShouldEqual, "1 charged 2")
+		})
+
+		convey.Convey("Should not run a job for a caller whose context is already done", func() {
+			cancelled, cancel := context.WithCancel(ctx)
+			cancel()
+
+			_, err := pool.SubmitIdempotent(cancelled, chargeJob{order: "1", runs: &runs}).Result()
+			convey.So(errors.Is(err, context.Canceled), convey.ShouldBeTrue)
+
+			value, err := pool.SubmitIdempotent(ctx, chargeJob{order: "1", runs: &runs}).Result()
+			convey.So(err, convey.ShouldBeNil)
+			convey.So(value, convey.ShouldEqual, "1 charged 1")
+		})
+
+		convey.Convey("Should run every job without WithIdempotency", func() {
+			plain := NewPool(ctx, 2)
+			defer plain.Shutdown()
+
+			for range 3 {
+				_, err := plain.SubmitIdempotent(ctx, chargeJob{order: "1", runs: &runs}).Result()
+				convey.So(err, convey.ShouldBeNil)
+			}
+			convey.So(runs.Load(), convey.ShouldEqual, 3)
+		})
+
+		convey.Convey("Should remove expired Results as the store grows", func() {
+			for idx := range 100 {
+				store.Put(fmt.Sprint(idx), Ok[any, error](idx), time.Second)
+			}
+			clock.Advance(time.Minute)
+			for idx := range 100 {
+				store.Put(fmt.Sprint("fresh", idx), Ok[any, error](idx), time.Second)
+			}
+
+			convey.So(store.Len(), convey.ShouldBeLessThan, 200)
+			stored, err := store.Get("1")
+			convey.So(err, convey.ShouldBeNil)
+			convey.So(stored.IsNone(), convey.ShouldBeTrue)
+		})
+
+		convey.Reset(func() {
+			pool.Shutdown()
+		})
+	})
+}
+
+func BenchmarkIdempotency(b *testing.B) {
+	ctx := context.Background()
+	pool := NewPool(ctx, 4, WithIdempotency(NewMemoryDedupStore(), time.Minute))
+	defer pool.Shutdown()
+
+	var runs atomic.Int32
+
+	for i := 0; i < b.N; i++ {
+		pool.SubmitIdempotent(ctx, chargeJob{order: fmt.Sprint(i % 16), runs: &runs}).Result()
+	}
+}
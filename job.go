@@ -36,8 +36,9 @@ Example:
 retriableJob := NewRetriableJob(context.Background(), MyJob{})
 */
 type RetriableJob struct {
-	ctx context.Context
-	fn  Job
+	ctx     context.Context
+	fn      Job
+	retrier Retrier
 }
 
 /*
@@ -54,6 +55,22 @@ func NewRetriableJob(ctx context.Context, fn Job) Job {
 	})
 }
 
+/*
+NewRetriableJobWithRetrier creates a new retriable job that retries with the given Retrier,
+instead of the default one.
+
+Example:
+
+retriableJob := NewRetriableJobWithRetrier(ctx, MyJob{}, NewFibonacciWithUnit(5, time.Millisecond))
+*/
+func NewRetriableJobWithRetrier(ctx context.Context, fn Job, retrier Retrier) Job {
+	return NewJob(RetriableJob{
+		ctx:     ctx,
+		fn:      fn,
+		retrier: retrier,
+	})
+}
+
 /*
 Do the job and retry x amount of times when needed.
 
@@ -63,5 +80,118 @@ retriableJob := NewRetriableJob(context.Background(), MyJob{})
 result := retriableJob.Do()
 */
 func (job RetriableJob) Do() Result[any, error] {
+	if job.retrier != nil {
+		return job.retrier.Do(job.fn)
+	}
 	return NewRetrier(NewFibonacci(3)).Do(job.fn)
 }
+
+/*
+JobFunc is an adapter to allow the use of an ordinary function as a Job.
+
+Example:
+
+	job := NewJob(JobFunc(func() Result[any, error] {
+	    return Ok[any, error]("done")
+	}))
+*/
+type JobFunc func() Result[any, error]
+
+/*
+Do calls the function.
+*/
+func (fn JobFunc) Do() Result[any, error] {
+	return fn()
+}
+
+/*
+TypedJob is a Job with a typed outcome, so callers don't have to type-assert it.
+
+Example:
+
+type FetchUser struct{ ID string }
+
+	func (job FetchUser) Do(ctx context.Context) Result[User, error] {
+	    return fetchUser(ctx, job.ID)
+	}
+*/
+type TypedJob[T any] interface {
+	Do(ctx context.Context) Result[T, error]
+}
+
+/*
+TypedJobFunc is an adapter to allow the use of an ordinary function as a TypedJob.
+
+Example:
+
+	job := TypedJobFunc[int](func(ctx context.Context) Result[int, error] {
+	    return Ok[int, error](42)
+	})
+*/
+type TypedJobFunc[T any] func(ctx context.Context) Result[T, error]
+
+/*
+Do calls the function.
+*/
+func (fn TypedJobFunc[T]) Do(ctx context.Context) Result[T, error] {
+	return fn(ctx)
+}
+
+/*
+SubmitTyped schedules a TypedJob onto the worker pool, and returns a Future for its outcome.
+
+Example:
+
+f := SubmitTyped(ctx, pool, FetchUser{ID: "alice"})
+user, err := f.Result()
+*/
+func SubmitTyped[T any](ctx context.Context, pool *Pool, job TypedJob[T]) *Future[T] {
+	return AsyncOn(ctx, pool, func(ctx context.Context) (T, error) {
+		return unpack(job.Do(ctx))
+	})
+}
+
+/*
+TypedPool is a view on a Pool that only accepts jobs of a single outcome type.
+
+Example:
+
+users := NewTypedPool[User](pool)
+f := users.Submit(ctx, FetchUser{ID: "alice"})
+*/
+type TypedPool[T any] struct {
+	pool *Pool
+}
+
+/*
+NewTypedPool creates a TypedPool on top of an existing Pool, so it shares its workers.
+
+Example:
+
+users := NewTypedPool[User](pool)
+*/
+func NewTypedPool[T any](pool *Pool) *TypedPool[T] {
+	return &TypedPool[T]{pool: pool}
+}
+
+/*
+Submit schedules a TypedJob onto the underlying pool, and returns a Future for its outcome.
+
+Example:
+
+f := users.Submit(ctx, FetchUser{ID: "alice"})
+*/
+func (typed *TypedPool[T]) Submit(ctx context.Context, job TypedJob[T]) *Future[T] {
+	return SubmitTyped(ctx, typed.pool, job)
+}
+
+/*
+unpack turns a Result into the value and error pair that Go functions return.
+*/
+func unpack[T any](result Result[T, error]) (T, error) {
+	if result.IsErr() {
+		var zero T
+		return zero, result.UnwrapErr()
+	}
+	return result.Unwrap(), nil
+}
@@ -39,6 +39,39 @@ func TestJob(t *testing.T) {
 	})
 }
 
+func TestTypedJob(t *testing.T) {
+	convey.Convey("TypedJob", t, func() {
+		ctx := context.Background()
+		pool := NewPool(ctx, 2)
+
+		convey.Convey("Should use a function as a Job", func() {
+			job := NewJob(JobFunc(func() Result[any, error] { return Ok[any, error]("done") }))
+			convey.So(job.Do().Unwrap(), convey.ShouldEqual, "done")
+		})
+
+		convey.Convey("Should resolve with the typed value", func() {
+			f := SubmitTyped[int](ctx, pool, TypedJobFunc[int](func(ctx context.Context) Result[int, error] {
+				return Ok[int, error](42)
+			}))
+			result, err := f.Result()
+			convey.So(result, convey.ShouldEqual, 42)
+			convey.So(err, convey.ShouldBeNil)
+		})
+
+		convey.Convey("Should resolve with the error", func() {
+			f := NewTypedPool[string](pool).Submit(ctx, TypedJobFunc[string](func(ctx context.Context) Result[string, error] {
+				return Err[string](errDummy)
+			}))
+			_, err := f.Result()
+			convey.So(err, convey.ShouldEqual, errDummy)
+		})
+
+		convey.Reset(func() {
+			pool.Shutdown()
+		})
+	})
+}
+
 func BenchmarkJob(b *testing.B) {
 	for i := 0; i < b.N; i++ {
 		job := NewJob(DummyJob{Ok[any, error]("ok")})
@@ -0,0 +1,90 @@
+package twoface
+
+import (
+	"bytes"
+	"encoding/gob"
+	"encoding/json"
+)
+
+/*
+JobCodec turns the state of a job into bytes and back. A JobRegistry uses it for the
+payload of every Envelope, and records its name, so a job is always decoded with the
+codec that encoded it.
+
+Example:
+
+	registry := NewJobRegistry(WithJobCodec(GobCodec{}))
+*/
+type JobCodec interface {
+	// Name identifies the codec in encoded envelopes.
+	Name() string
+	// Encode writes the state of the job.
+	Encode(job Job) ([]byte, error)
+	// Decode reads the state of a job into the empty job.
+	Decode(data []byte, job Job) error
+}
+
+/*
+JSONCodec is the default JobCodec. It encodes the exported fields of a job as JSON, which
+keeps the payload readable and tolerant of fields that are added or removed.
+
+Example:
+
+	data, err := JSONCodec{}.Encode(&EmailJob{To: "someone@example.com"})
+*/
+type JSONCodec struct{}
+
+/*
+Name returns "json", which identifies the codec in encoded envelopes.
+*/
+func (JSONCodec) Name() string {
+	return "json"
+}
+
+/*
+Encode writes the exported fields of the job as JSON.
+*/
+func (JSONCodec) Encode(job Job) ([]byte, error) {
+	return json.Marshal(job)
+}
+
+/*
+Decode reads JSON into the job, which has to be a pointer. Fields that the data does not
+have keep their zero value, and fields that the job does not have are ignored.
+*/
+func (JSONCodec) Decode(data []byte, job Job) error {
+	return json.Unmarshal(data, job)
+}
+
+/*
+GobCodec encodes the exported fields of a job with encoding/gob, which is more compact
+than JSON, and keeps the exact Go types of the fields.
+
+Example:
+
+	data, err := GobCodec{}.Encode(&EmailJob{To: "someone@example.com"})
+*/
+type GobCodec struct{}
+
+/*
+Name returns "gob", which identifies the codec in encoded envelopes.
+*/
+func (GobCodec) Name() string {
+	return "gob"
+}
+
+/*
+Encode writes the exported fields of the job with encoding/gob.
+*/
+func (GobCodec) Encode(job Job) ([]byte, error) {
+	var buffer bytes.Buffer
+	err := gob.NewEncoder(&buffer).Encode(job)
+	return buffer.Bytes(), err
+}
+
+/*
+Decode reads data that Encode wrote into the job, which has to be a pointer.
+*/
+func (GobCodec) Decode(data []byte, job Job) error {
+	return gob.NewDecoder(bytes.NewReader(data)).Decode(job)
+}
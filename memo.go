@@ -0,0 +1,200 @@
+package twoface
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+/*
+ErrorPolicy decides what a Memo does with a Future that fails.
+*/
+type ErrorPolicy int
+
+const (
+	// EvictErrors removes a failed Future, so the next caller starts a new one.
+	EvictErrors ErrorPolicy = iota
+	// KeepErrors keeps a failed Future, like any other, until it expires.
+	KeepErrors
+)
+
+/*
+MemoConfig configures a Memo. The zero value is a valid configuration, which keeps
+futures forever, in any number, and evicts the ones that fail.
+
+Example:
+
+	memo := NewMemo[string, int](MemoConfig{TTL: time.Minute, MaxSize: 1000})
+*/
+type MemoConfig struct {
+	// TTL is how long a completed Future is kept, counted from the moment it completed.
+	// Futures that are still in flight never expire. Zero means forever.
+	TTL time.Duration
+	// MaxSize limits the number of futures, evicting the least recently used. Zero means
+	// unbounded.
+	MaxSize int
+	// ErrorPolicy sets what happens to futures that fail. The default is EvictErrors.
+	ErrorPolicy ErrorPolicy
+
+	clock Clock
+}
+
+/*
+Memo is a keyed store of futures, that makes sure an expensive computation only runs
+once per key. Concurrent callers for the same key share the same Future, whether it is
+still in flight or already completed. Because the Future is shared, cancelling it
+cancels it for every caller.
+
+Example:
+
+	memo := NewMemo[string, User](MemoConfig{TTL: time.Minute, MaxSize: 1000})
+	user, err := memo.Get("alice", func() *Future[User] {
+	    return Async(ctx, func(ctx context.Context) (User, error) { return fetchUser(ctx, "alice") })
+	}).Result()
+*/
+type Memo[K comparable, T any] struct {
+	mu      sync.Mutex
+	config  MemoConfig
+	entries map[K]*list.Element
+	order   *list.List
+}
+
+type memoEntry[K comparable, T any] struct {
+	key     K
+	future  *Future[T]
+	expires time.Time
+}
+
+/*
+NewMemo creates an empty Memo.
+
+Example:
+
+	memo := NewMemo[string, int](MemoConfig{})
+*/
+func NewMemo[K comparable, T any](config MemoConfig) *Memo[K, T] {
+	if config.clock == nil {
+		config.clock = SystemClock
+	}
+
+	return &Memo[K, T]{
+		config:  config,
+		entries: make(map[K]*list.Element),
+		order:   list.New(),
+	}
+}
+
+/*
+Get returns the Future that is kept for the key, or calls the function to create one
+when there is none, or when the one that was kept expired or failed. The function is
+called while the Memo is locked, so it should start the work rather than wait for it.
+
+Example:
+
+	f := memo.Get("answer", func() *Future[int] { return Lazy(ctx, compute) })
+*/
+func (memo *Memo[K, T]) Get(key K, fn func() *Future[T]) *Future[T] {
+	memo.mu.Lock()
+
+	if element, ok := memo.entries[key]; ok {
+		entry := element.Value.(*memoEntry[K, T])
+
+		if memo.valid(entry) {
+			memo.order.MoveToFront(element)
+			memo.mu.Unlock()
+			return entry.future
+		}
+
+		memo.remove(element)
+	}
+
+	entry := &memoEntry[K, T]{key: key, future: fn()}
+	memo.entries[key] = memo.order.PushFront(entry)
+
+	for memo.config.MaxSize > 0 && memo.order.Len() > memo.config.MaxSize {
+		memo.remove(memo.order.Back())
+	}
+
+	memo.mu.Unlock()
+
+	// The TTL starts as soon as the Future completes, even when its Executor refuses the callbacks.
+	entry.future.onSettled(func() { memo.completed(entry) })
+
+	return entry.future
+}
+
+/*
+Forget removes the Future that is kept for the key, so the next call to Get creates a
+new one. Callers that already hold the old Future are not affected.
+
+Example:
+
+	memo.Forget("answer")
+*/
+func (memo *Memo[K, T]) Forget(key K) {
+	memo.mu.Lock()
+	defer memo.mu.Unlock()
+
+	if element, ok := memo.entries[key]; ok {
+		memo.remove(element)
+	}
+}
+
+/*
+Len returns the number of futures that are kept, including ones that expired but were
+not requested since.
+
+Example:
+
+	fmt.Println(memo.Len())
+*/
+func (memo *Memo[K, T]) Len() int {
+	memo.mu.Lock()
+	defer memo.mu.Unlock()
+	return memo.order.Len()
+}
+
+/*
+completed starts the TTL of an entry once its Future completes, and evicts it right
+away when it failed and errors are not kept.
+*/
+func (memo *Memo[K, T]) completed(entry *memoEntry[K, T]) {
+	memo.mu.Lock()
+	defer memo.mu.Unlock()
+
+	element, ok := memo.entries[entry.key]
+	if !ok || element.Value != entry {
+		return
+	}
+
+	if entry.future.err != nil && memo.config.ErrorPolicy == EvictErrors {
+		memo.remove(element)
+		return
+	}
+
+	if memo.config.TTL > 0 {
+		entry.expires = memo.config.clock.Now().Add(memo.config.TTL)
+	}
+}
+
+/*
+valid returns true if the entry can still be handed out. Failures are checked here as
+well as on completion, so a failed Future is never handed out again, even before its
+callback has run.
+*/
+func (memo *Memo[K, T]) valid(entry *memoEntry[K, T]) bool {
+	if !entry.future.IsDone() {
+		return true
+	}
+
+	if entry.future.err != nil && memo.config.ErrorPolicy == EvictErrors {
+		return false
+	}
+
+	return entry.expires.IsZero() || memo.config.clock.Now().Before(entry.expires)
+}
+
+func (memo *Memo[K, T]) remove(element *list.Element) {
+	delete(memo.entries, element.Value.(*memoEntry[K, T]).key)
+	memo.order.Remove(element)
+}
@@ -0,0 +1,142 @@
+package twoface
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/smartystreets/goconvey/convey"
+)
+
+func TestMemo(t *testing.T) {
+	convey.Convey("Memo", t, func() {
+		ctx := context.Background()
+		var calls atomic.Int64
+
+		compute := func(value int, err error) func() *Future[int] {
+			return func() *Future[int] {
+				calls.Add(1)
+				return Async(ctx, func(ctx context.Context) (int, error) { return value, err })
+			}
+		}
+
+		convey.Convey("Should share the Future between concurrent callers", func() {
+			memo := NewMemo[string, int](MemoConfig{})
+			p, f := NewPromise[int]()
+
+			var wg sync.WaitGroup
+			futures := make([]*Future[int], 10)
+			for i := range futures {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					futures[i] = memo.Get("answer", func() *Future[int] {
+						calls.Add(1)
+						return f
+					})
+				}()
+			}
+			wg.Wait()
+			p.Set(42, nil)
+
+			convey.So(calls.Load(), convey.ShouldEqual, 1)
+			for _, future := range futures {
+				convey.So(future, convey.ShouldEqual, f)
+			}
+		})
+
+		convey.Convey("Should evict failed futures by default", func() {
+			memo := NewMemo[string, int](MemoConfig{})
+			_, err := memo.Get("answer", compute(0, errDummy)).Result()
+			convey.So(err, convey.ShouldEqual, errDummy)
+
+			result, err := memo.Get("answer", compute(42, nil)).Result()
+			convey.So(err, convey.ShouldBeNil)
+			convey.So(result, convey.ShouldEqual, 42)
+			convey.So(calls.Load(), convey.ShouldEqual, 2)
+		})
+
+		convey.Convey("Should keep failed futures with KeepErrors", func() {
+			memo := NewMemo[string, int](MemoConfig{ErrorPolicy: KeepErrors})
+			memo.Get("answer", compute(0, errDummy)).Result()
+			_, err := memo.Get("answer", compute(42, nil)).Result()
+			convey.So(err, convey.ShouldEqual, errDummy)
+			convey.So(calls.Load(), convey.ShouldEqual, 1)
+		})
+
+		convey.Convey("Should expire futures after the TTL", func() {
+			clock := &testClock{now: time.Now()}
+			memo := NewMemo[string, int](MemoConfig{TTL: time.Minute, clock: clock})
+			p, first := NewPromise[int]()
+			memo.Get("answer", func() *Future[int] {
+				calls.Add(1)
+				return first.WithExecutor(InlineExecutor)
+			})
+			p.Set(42, nil)
+
+			convey.So(memo.Get("answer", compute(43, nil)), convey.ShouldEqual, first)
+
+			clock.Advance(2 * time.Minute)
+
+			result, _ := memo.Get("answer", compute(43, nil)).Result()
+			convey.So(result, convey.ShouldEqual, 43)
+			convey.So(calls.Load(), convey.ShouldEqual, 2)
+		})
+
+		convey.Convey("Should expire futures whose Executor was shut down", func() {
+			clock := &testClock{now: time.Now()}
+			memo := NewMemo[string, int](MemoConfig{TTL: time.Minute, clock: clock})
+			pool := NewPool(ctx, 1)
+			pool.Shutdown()
+
+			p, first := NewPromise[int]()
+			memo.Get("answer", func() *Future[int] {
+				calls.Add(1)
+				return first.WithExecutor(pool)
+			})
+			p.Set(42, nil)
+
+			clock.Advance(2 * time.Minute)
+
+			result, _ := memo.Get("answer", compute(43, nil)).Result()
+			convey.So(result, convey.ShouldEqual, 43)
+		})
+
+		convey.Convey("Should evict the least recently used futures", func() {
+			memo := NewMemo[int, int](MemoConfig{MaxSize: 2})
+			memo.Get(1, compute(1, nil))
+			memo.Get(2, compute(2, nil))
+			memo.Get(1, compute(1, nil))
+			memo.Get(3, compute(3, nil))
+			convey.So(memo.Len(), convey.ShouldEqual, 2)
+			convey.So(calls.Load(), convey.ShouldEqual, 3)
+
+			memo.Get(1, compute(1, nil))
+			convey.So(calls.Load(), convey.ShouldEqual, 3)
+			memo.Get(2, compute(2, nil))
+			convey.So(calls.Load(), convey.ShouldEqual, 4)
+		})
+
+		convey.Convey("Should Forget a key", func() {
+			memo := NewMemo[string, int](MemoConfig{})
+			memo.Get("answer", compute(42, nil))
+			memo.Forget("answer")
+			convey.So(memo.Len(), convey.ShouldEqual, 0)
+			memo.Get("answer", compute(42, nil))
+			convey.So(calls.Load(), convey.ShouldEqual, 2)
+		})
+	})
+}
+
+func BenchmarkMemo(b *testing.B) {
+	memo := NewMemo[int, int](MemoConfig{MaxSize: 100})
+	for i := 0; i < b.N; i++ {
+		memo.Get(i%200, func() *Future[int] {
+			p, f := NewPromise[int]()
+			p.Set(i, nil)
+			return f
+		})
+	}
+}
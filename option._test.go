@@ -67,6 +67,73 @@ func TestOption(t *testing.T) {
 			})
 			convey.So(flatMapped.UnwrapOr(0), convey.ShouldEqual, 43)
 		})
+
+		convey.Convey("FromPointer and ToPointer", func() {
+			value := 42
+			opt := FromPointer(&value)
+			value = 99
+			convey.So(opt.UnwrapOr(0), convey.ShouldEqual, 42)
+			convey.So(FromPointer[int](nil).IsNone(), convey.ShouldBeTrue)
+			convey.So(*opt.ToPointer(), convey.ShouldEqual, 42)
+			convey.So(None[int]().ToPointer(), convey.ShouldBeNil)
+		})
+
+		convey.Convey("FromMapLookup and FromComma", func() {
+			m := map[string]int{"answer": 42}
+			convey.So(FromMapLookup(m, "answer").UnwrapOr(0), convey.ShouldEqual, 42)
+			convey.So(FromMapLookup(m, "question").IsNone(), convey.ShouldBeTrue)
+			convey.So(FromComma(42, true).UnwrapOr(0), convey.ShouldEqual, 42)
+			convey.So(FromComma(42, false).IsNone(), convey.ShouldBeTrue)
+		})
+
+		convey.Convey("Expect and UnwrapOrZero", func() {
+			convey.So(Some(42).Expect("should be set"), convey.ShouldEqual, 42)
+			convey.So(func() { None[int]().Expect("should be set") }, convey.ShouldPanicWith, "should be set")
+			convey.So(Some(42).UnwrapOrZero(), convey.ShouldEqual, 42)
+			convey.So(None[int]().UnwrapOrZero(), convey.ShouldEqual, 0)
+		})
+
+		convey.Convey("Filter", func() {
+			even := func(value int) bool { return value%2 == 0 }
+			convey.So(Some(42).Filter(even).UnwrapOr(0), convey.ShouldEqual, 42)
+			convey.So(Some(43).Filter(even).IsNone(), convey.ShouldBeTrue)
+			convey.So(None[int]().Filter(even).IsNone(), convey.ShouldBeTrue)
+		})
+
+		convey.Convey("Or and OrElse", func() {
+			convey.So(Some(42).Or(Some(99)).UnwrapOr(0), convey.ShouldEqual, 42)
+			convey.So(None[int]().Or(Some(99)).UnwrapOr(0), convey.ShouldEqual, 99)
+
+			called := false
+			alternative := func() Option[int] {
+				called = true
+				return Some(99)
+			}
+			convey.So(Some(42).OrElse(alternative).UnwrapOr(0), convey.ShouldEqual, 42)
+			convey.So(called, convey.ShouldBeFalse)
+			convey.So(None[int]().OrElse(alternative).UnwrapOr(0), convey.ShouldEqual, 99)
+			convey.So(called, convey.ShouldBeTrue)
+		})
+
+		convey.Convey("And", func() {
+			convey.So(Some(42).And(Some(99)).UnwrapOr(0), convey.ShouldEqual, 99)
+			convey.So(Some(42).And(None[int]()).IsNone(), convey.ShouldBeTrue)
+			convey.So(None[int]().And(Some(99)).IsNone(), convey.ShouldBeTrue)
+		})
+
+		convey.Convey("Zip and Unzip", func() {
+			zipped := Zip(Some(42), Some("hello"))
+			convey.So(zipped.UnwrapOrZero(), convey.ShouldResemble, Pair[int, string]{First: 42, Second: "hello"})
+			convey.So(Zip(Some(42), None[string]()).IsNone(), convey.ShouldBeTrue)
+
+			a, b := Unzip(zipped)
+			convey.So(a.UnwrapOr(0), convey.ShouldEqual, 42)
+			convey.So(b.UnwrapOr(""), convey.ShouldEqual, "hello")
+
+			a, b = Unzip(None[Pair[int, string]]())
+			convey.So(a.IsNone(), convey.ShouldBeTrue)
+			convey.So(b.IsNone(), convey.ShouldBeTrue)
+		})
 	})
 }
 
@@ -167,3 +167,192 @@ func (o Option[T]) FlatMap(f func(T) Option[T]) Option[T] {
 	}
 	return None[T]()
 }
+
+/*
+FromPointer creates an Option from a pointer, which is None when the pointer is nil.
+The value is copied, so later changes through the pointer do not affect the Option.
+
+Example:
+
+	value := 42
+	opt := FromPointer(&value)
+	fmt.Println(opt.UnwrapOr(0)) // Output: 42
+*/
+func FromPointer[T any](ptr *T) Option[T] {
+	if ptr == nil {
+		return None[T]()
+	}
+	return Some(*ptr)
+}
+
+/*
+FromMapLookup creates an Option from looking up a key in a map, which is None when
+the key is not present.
+
+Example:
+
+	opt := FromMapLookup(map[string]int{"answer": 42}, "answer")
+	fmt.Println(opt.UnwrapOr(0)) // Output: 42
+*/
+func FromMapLookup[K comparable, V any](m map[K]V, key K) Option[V] {
+	value, ok := m[key]
+	return FromComma(value, ok)
+}
+
+/*
+FromComma creates an Option from the "comma ok" idiom, which is None when ok is false.
+
+Example:
+
+	value, ok := os.LookupEnv("HOME")
+	opt := FromComma(value, ok)
+*/
+func FromComma[T any](value T, ok bool) Option[T] {
+	if !ok {
+		return None[T]()
+	}
+	return Some(value)
+}
+
+/*
+ToPointer returns a pointer to a copy of the contained value, or nil if there is none.
+
+Example:
+
+	opt := Some(42)
+	fmt.Println(*opt.ToPointer()) // Output: 42
+*/
+func (o Option[T]) ToPointer() *T {
+	if o.IsNone() {
+		return nil
+	}
+	value := *o.value
+	return &value
+}
+
+/*
+Expect returns the contained value, or panics with the given message if there is none.
+
+Example:
+
+	opt := Some(42)
+	fmt.Println(opt.Expect("answer should be set")) // Output: 42
+*/
+func (o Option[T]) Expect(msg string) T {
+	if o.IsNone() {
+		panic(msg)
+	}
+	return *o.value
+}
+
+/*
+UnwrapOrZero returns the contained value, or the zero value of T if there is none.
+
+Example:
+
+	opt := None[int]()
+	fmt.Println(opt.UnwrapOrZero()) // Output: 0
+*/
+func (o Option[T]) UnwrapOrZero() T {
+	var zero T
+	return o.UnwrapOr(zero)
+}
+
+/*
+Filter returns the Option if it contains a value that satisfies the predicate, or None otherwise.
+
+Example:
+
+	opt := Some(42).Filter(func(value int) bool { return value%2 == 0 })
+	fmt.Println(opt.IsSome()) // Output: true
+*/
+func (o Option[T]) Filter(predicate func(T) bool) Option[T] {
+	if o.IsSome() && predicate(*o.value) {
+		return o
+	}
+	return None[T]()
+}
+
+/*
+Or returns the Option if it contains a value, or the other Option otherwise.
+
+Example:
+
+	opt := None[int]().Or(Some(99))
+	fmt.Println(opt.UnwrapOr(0)) // Output: 99
+*/
+func (o Option[T]) Or(other Option[T]) Option[T] {
+	if o.IsSome() {
+		return o
+	}
+	return other
+}
+
+/*
+OrElse returns the Option if it contains a value, or calls the function to produce an
+alternative otherwise. Unlike Or, the alternative is only computed when it is needed.
+
+Example:
+
+	opt := None[int]().OrElse(func() Option[int] { return Some(99) })
+	fmt.Println(opt.UnwrapOr(0)) // Output: 99
+*/
+func (o Option[T]) OrElse(f func() Option[T]) Option[T] {
+	if o.IsSome() {
+		return o
+	}
+	return f()
+}
+
+/*
+And returns None if the Option is None, or the other Option otherwise.
+
+Example:
+
+	opt := Some(42).And(Some(99))
+	fmt.Println(opt.UnwrapOr(0)) // Output: 99
+*/
+func (o Option[T]) And(other Option[T]) Option[T] {
+	if o.IsNone() {
+		return None[T]()
+	}
+	return other
+}
+
+/*
+Pair holds two values of possibly different types.
+*/
+type Pair[A any, B any] struct {
+	First  A
+	Second B
+}
+
+/*
+Zip combines two Options into an Option of a Pair, which is only Some when both contain a value.
+
+Example:
+
+	zipped := Zip(Some(42), Some("hello"))
+	fmt.Println(zipped.UnwrapOrZero().Second) // Output: hello
+*/
+func Zip[A any, B any](a Option[A], b Option[B]) Option[Pair[A, B]] {
+	if a.IsNone() || b.IsNone() {
+		return None[Pair[A, B]]()
+	}
+	return Some(Pair[A, B]{First: *a.value, Second: *b.value})
+}
+
+/*
+Unzip splits an Option of a Pair into two Options, which are both None when it is None.
+
+Example:
+
+	a, b := Unzip(Zip(Some(42), Some("hello")))
+	fmt.Println(a.UnwrapOr(0), b.UnwrapOr("")) // Output: 42 hello
+*/
+func Unzip[A any, B any](o Option[Pair[A, B]]) (Option[A], Option[B]) {
+	if o.IsNone() {
+		return None[A](), None[B]()
+	}
+	return Some(o.value.First), Some(o.value.Second)
+}
@@ -0,0 +1,205 @@
+package twoface
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+/*
+ErrorMode decides how the parallel helpers deal with failing items.
+*/
+type ErrorMode int
+
+const (
+	// FailFast stops scheduling new items as soon as one fails, and cancels the
+	// context of the items that are still running.
+	FailFast ErrorMode = iota
+	// CollectAll runs every item, and reports all of the failures together.
+	CollectAll
+)
+
+/*
+ParallelMap applies the function to every item on the workers of the pool, and returns
+the outcomes in the same order as the items. No more items are in flight at once than
+the pool has workers, and no new items are scheduled once the context is done, those
+items fail with the context error instead.
+
+Example:
+
+	results := ParallelMap(ctx, pool, urls, func(ctx context.Context, url string) (int, error) {
+	    return fetchStatus(ctx, url)
+	})
+*/
+func ParallelMap[T any, U any](
+	ctx context.Context, pool *Pool, items []T, fn func(context.Context, T) (U, error),
+) []Result[U, error] {
+	return parallel(ctx, pool, items, fn, CollectAll)
+}
+
+/*
+ForEach calls the function for every item on the workers of the pool. With FailFast it
+returns the first error, with CollectAll it returns all errors joined together.
+
+Example:
+
+	err := ForEach(ctx, pool, users, notify, FailFast)
+*/
+func ForEach[T any](ctx context.Context, pool *Pool, items []T, fn func(context.Context, T) error, mode ErrorMode) error {
+	results := parallel(ctx, pool, items, func(ctx context.Context, item T) (struct{}, error) {
+		return struct{}{}, fn(ctx, item)
+	}, mode)
+
+	return collectErrors(results, mode)
+}
+
+/*
+Filter returns the items for which the predicate holds, in their original order, checking
+them on the workers of the pool. It fails fast on the first error of the predicate.
+
+Example:
+
+	active, err := Filter(ctx, pool, users, func(ctx context.Context, user User) (bool, error) {
+	    return isActive(ctx, user)
+	})
+*/
+func Filter[T any](ctx context.Context, pool *Pool, items []T, predicate func(context.Context, T) (bool, error)) ([]T, error) {
+	results := parallel(ctx, pool, items, predicate, FailFast)
+
+	if err := collectErrors(results, FailFast); err != nil {
+		return nil, err
+	}
+
+	filtered := make([]T, 0, len(items))
+	for idx, result := range results {
+		if result.Unwrap() {
+			filtered = append(filtered, items[idx])
+		}
+	}
+
+	return filtered, nil
+}
+
+/*
+MapReduce applies the mapper to every item on the workers of the pool, and then combines
+the outcomes, in the order of the items, starting from the initial value. It fails fast
+on the first error of the mapper.
+
+Example:
+
+	total, err := MapReduce(ctx, pool, files, countLines, 0, func(total, lines int) int {
+	    return total + lines
+	})
+*/
+func MapReduce[T any, U any, R any](
+	ctx context.Context,
+	pool *Pool,
+	items []T,
+	mapper func(context.Context, T) (U, error),
+	initial R,
+	combiner func(R, U) R,
+) (R, error) {
+	results := parallel(ctx, pool, items, mapper, FailFast)
+
+	if err := collectErrors(results, FailFast); err != nil {
+		return initial, err
+	}
+
+	reduced := initial
+	for _, result := range results {
+		reduced = combiner(reduced, result.Unwrap())
+	}
+
+	return reduced, nil
+}
+
+/*
+parallel runs the function for every item on the pool, with at most as many items in
+flight as the pool has workers.
+*/
+func parallel[T any, U any](
+	ctx context.Context, pool *Pool, items []T, fn func(context.Context, T) (U, error), mode ErrorMode,
+) []Result[U, error] {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	results := make([]Result[U, error], len(items))
+	slots := make(chan struct{}, max(pool.Size(), 1))
+	wg := &sync.WaitGroup{}
+
+	for idx, item := range items {
+		if !acquire(ctx, slots) {
+			for rest := idx; rest < len(items); rest++ {
+				results[rest] = Err[U](ctx.Err())
+			}
+			break
+		}
+
+		wg.Add(1)
+
+		future := AsyncOn(ctx, pool, func(ctx context.Context) (U, error) {
+			return fn(ctx, item)
+		})
+
+		future.onComplete(func() {
+			results[idx] = future.settled()
+
+			if future.err != nil && mode == FailFast {
+				cancel()
+			}
+
+			<-slots
+			wg.Done()
+		})
+	}
+
+	wg.Wait()
+	return results
+}
+
+/*
+acquire takes a slot, unless the context is done first.
+*/
+func acquire(ctx context.Context, slots chan struct{}) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+
+	select {
+	case slots <- struct{}{}:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+/*
+collectErrors returns the first error with FailFast, or all errors joined with CollectAll.
+With FailFast, the errors caused by cancelling the other items are not the first error,
+so the one that caused the cancellation is preferred.
+*/
+func collectErrors[U any](results []Result[U, error], mode ErrorMode) error {
+	var errs []error
+
+	for _, result := range results {
+		if result.IsErr() {
+			errs = append(errs, result.UnwrapErr())
+		}
+	}
+
+	if mode == CollectAll {
+		return errors.Join(errs...)
+	}
+
+	for _, err := range errs {
+		if !errors.Is(err, context.Canceled) {
+			return err
+		}
+	}
+
+	if len(errs) > 0 {
+		return errs[0]
+	}
+
+	return nil
+}
@@ -0,0 +1,133 @@
+package twoface
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/smartystreets/goconvey/convey"
+)
+
+func TestParallel(t *testing.T) {
+	convey.Convey("Parallel helpers", t, func() {
+		ctx := context.Background()
+		pool := NewPool(ctx, 3)
+		items := []int{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}
+
+		convey.Convey("ParallelMap should keep the order of the items", func() {
+			results := ParallelMap(ctx, pool, items, func(ctx context.Context, item int) (string, error) {
+				time.Sleep(time.Duration(10-item) * time.Millisecond)
+				return fmt.Sprint(item * item), nil
+			})
+
+			convey.So(results, convey.ShouldHaveLength, len(items))
+			for idx, result := range results {
+				convey.So(result.Unwrap(), convey.ShouldEqual, fmt.Sprint(items[idx]*items[idx]))
+			}
+		})
+
+		convey.Convey("ParallelMap should respect the size of the pool", func() {
+			var running, peak atomic.Int64
+			ParallelMap(ctx, pool, items, func(ctx context.Context, item int) (int, error) {
+				current := running.Add(1)
+				for {
+					seen := peak.Load()
+					if current <= seen || peak.CompareAndSwap(seen, current) {
+						break
+					}
+				}
+				time.Sleep(5 * time.Millisecond)
+				running.Add(-1)
+				return item, nil
+			})
+			convey.So(peak.Load(), convey.ShouldBeLessThanOrEqualTo, pool.Size())
+		})
+
+		convey.Convey("ParallelMap should not schedule items once the context is done", func() {
+			ctx, cancel := context.WithCancel(ctx)
+			var calls atomic.Int64
+			results := ParallelMap(ctx, pool, items, func(ctx context.Context, item int) (int, error) {
+				if calls.Add(1) == 1 {
+					cancel()
+				}
+				<-ctx.Done()
+				return 0, ctx.Err()
+			})
+			convey.So(calls.Load(), convey.ShouldBeLessThanOrEqualTo, pool.Size())
+			for _, result := range results {
+				convey.So(result.UnwrapErr(), convey.ShouldEqual, context.Canceled)
+			}
+		})
+
+		convey.Convey("ForEach should fail fast", func() {
+			var calls atomic.Int64
+			err := ForEach(ctx, pool, items, func(ctx context.Context, item int) error {
+				calls.Add(1)
+				if item == 1 {
+					return errDummy
+				}
+				select {
+				case <-ctx.Done():
+					return ctx.Err()
+				case <-time.After(time.Second):
+					return nil
+				}
+			}, FailFast)
+			convey.So(err, convey.ShouldEqual, errDummy)
+			convey.So(calls.Load(), convey.ShouldBeLessThan, len(items))
+		})
+
+		convey.Convey("ForEach should collect all errors", func() {
+			var calls atomic.Int64
+			err := ForEach(ctx, pool, items, func(ctx context.Context, item int) error {
+				calls.Add(1)
+				if item%2 == 0 {
+					return fmt.Errorf("item %d: %w", item, errDummy)
+				}
+				return nil
+			}, CollectAll)
+			convey.So(errors.Is(err, errDummy), convey.ShouldBeTrue)
+			convey.So(calls.Load(), convey.ShouldEqual, len(items))
+			convey.So(err.Error(), convey.ShouldContainSubstring, "item 10")
+		})
+
+		convey.Convey("Filter should keep the matching items in order", func() {
+			even, err := Filter(ctx, pool, items, func(ctx context.Context, item int) (bool, error) {
+				return item%2 == 0, nil
+			})
+			convey.So(err, convey.ShouldBeNil)
+			convey.So(even, convey.ShouldResemble, []int{2, 4, 6, 8, 10})
+		})
+
+		convey.Convey("MapReduce should combine the mapped items", func() {
+			total, err := MapReduce(ctx, pool, items, func(ctx context.Context, item int) (int, error) {
+				return item * 2, nil
+			}, 0, func(total, item int) int { return total + item })
+			convey.So(err, convey.ShouldBeNil)
+			convey.So(total, convey.ShouldEqual, 110)
+
+			joined, _ := MapReduce(ctx, pool, items[:3], func(ctx context.Context, item int) (string, error) {
+				return fmt.Sprint(item), nil
+			}, "", func(joined, item string) string { return joined + item })
+			convey.So(joined, convey.ShouldEqual, "123")
+		})
+
+		convey.Reset(func() {
+			pool.Shutdown()
+		})
+	})
+}
+
+func BenchmarkParallelMap(b *testing.B) {
+	ctx := context.Background()
+	pool := NewPool(ctx, 4)
+	defer pool.Shutdown()
+
+	items := make([]int, 100)
+	for i := 0; i < b.N; i++ {
+		ParallelMap(ctx, pool, items, func(ctx context.Context, item int) (int, error) { return item, nil })
+	}
+}
@@ -0,0 +1,482 @@
+package twoface
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+/*
+PipelineConfig configures a Pipeline.
+*/
+type PipelineConfig struct {
+	// Ordered makes every stage emit its outputs in the order of its inputs. Otherwise
+	// outputs are emitted as soon as they are ready.
+	Ordered bool
+	// DeadLetters receives the inputs that a stage failed on, as a StageInput, which are
+	// then dropped from the pipeline. It is the same kind of sink a Pool keeps the jobs in
+	// that exhaust their retries. Without it, or when the sink fails to store an input,
+	// failures flow downstream as Err values, and later stages pass them on without
+	// processing them.
+	DeadLetters DeadLetterSink
+}
+
+/*
+StageInput is the job of a DeadLetter that a Pipeline stores for an input that a stage
+failed on. The stage belongs to the pipeline, so the job cannot run on a Pool of its own,
+and fails when it is replayed into one. To keep these letters in a FileDeadLetters, the
+type has to be registered in its JobRegistry, where the input is decoded like any other
+field, so a struct comes back as a map with JSON.
+
+Example:
+
+	registry.Register("stage-input", func() Job { return &StageInput{} })
+*/
+type StageInput struct {
+	Stage string
+	Input any
+}
+
+/*
+Do fails, because the input can only be processed by the stage in its pipeline.
+*/
+func (input *StageInput) Do() Result[any, error] {
+	return Err[any](fmt.Errorf("cannot run the input of stage %q outside of its pipeline", input.Stage))
+}
+
+/*
+Pipeline connects stages that each process values on their own Pool, with a bounded
+buffer between them, so a slow stage holds back the ones before it instead of letting
+work pile up.
+
+Example:
+
+	pipeline := NewPipeline(ctx, PipelineConfig{Ordered: true})
+	urls := From(pipeline, []string{"https://a.example", "https://b.example"})
+	pages := AddStage(urls, Stage[string, []byte]{Name: "fetch", Workers: 8, Fn: fetch})
+	docs := AddStage(pages, Stage[[]byte, Doc]{Name: "parse", Workers: 2, Fn: parse})
+
+	for result := range docs.Results() {
+	    fmt.Println(result.IsOk())
+	}
+	pipeline.Wait()
+*/
+type Pipeline struct {
+	ctx     context.Context
+	cancel  context.CancelFunc
+	config  PipelineConfig
+	wg      sync.WaitGroup
+	mu      sync.Mutex
+	metrics []*stageMetrics
+}
+
+/*
+NewPipeline creates a Pipeline without any stages.
+
+Example:
+
+	pipeline := NewPipeline(ctx, PipelineConfig{})
+*/
+func NewPipeline(ctx context.Context, config PipelineConfig) *Pipeline {
+	ctx, cancel := context.WithCancel(ctx)
+
+	return &Pipeline{
+		ctx:    ctx,
+		cancel: cancel,
+		config: config,
+	}
+}
+
+/*
+Wait blocks until every stage has processed all of its inputs and shut down its Pool.
+The output of the last stage has to be consumed for this to happen. A pipeline drains
+gracefully once its source is exhausted, which for FromChannel means once the channel
+is closed.
+
+Example:
+
+	pipeline.Wait()
+*/
+func (pipeline *Pipeline) Wait() {
+	pipeline.wg.Wait()
+	pipeline.cancel()
+}
+
+/*
+Stop cancels the context of the pipeline, so no new inputs are processed, and the
+outputs of the ones in flight are dropped.
+
+Example:
+
+	pipeline.Stop()
+	pipeline.Wait()
+*/
+func (pipeline *Pipeline) Stop() {
+	pipeline.cancel()
+}
+
+/*
+StageMetrics is a snapshot of the counters of a stage.
+*/
+type StageMetrics struct {
+	Name         string
+	Workers      int
+	Processed    int64
+	Failed       int64
+	DeadLettered int64
+	InFlight     int64
+	Busy         time.Duration
+}
+
+/*
+Metrics returns a snapshot of the counters of every stage, in the order they were added.
+
+Example:
+
+	for _, stage := range pipeline.Metrics() {
+	    fmt.Println(stage.Name, stage.Processed, stage.Failed)
+	}
+*/
+func (pipeline *Pipeline) Metrics() []StageMetrics {
+	pipeline.mu.Lock()
+	defer pipeline.mu.Unlock()
+
+	snapshot := make([]StageMetrics, 0, len(pipeline.metrics))
+	for _, metrics := range pipeline.metrics {
+		snapshot = append(snapshot, metrics.snapshot())
+	}
+
+	return snapshot
+}
+
+/*
+Stream is the typed output of a source or a stage of a Pipeline, that the next stage
+is added to.
+*/
+type Stream[T any] struct {
+	pipeline *Pipeline
+	items    <-chan streamItem[T]
+}
+
+/*
+streamItem is a value in flight between stages. Skipped items were sent to the dead
+letter sink, but still take up their place, so ordered stages can move past them.
+*/
+type streamItem[T any] struct {
+	index  int
+	result Result[T, error]
+	skip   bool
+}
+
+/*
+Results returns the outputs of the stream. The channel is closed once the pipeline has
+drained, or was stopped.
+
+Example:
+
+	for result := range stream.Results() {
+	    fmt.Println(result.Unwrap())
+	}
+*/
+func (stream *Stream[T]) Results() <-chan Result[T, error] {
+	results := make(chan Result[T, error])
+
+	go func() {
+		defer close(results)
+
+		for item := range stream.items {
+			if item.skip {
+				continue
+			}
+
+			select {
+			case results <- item.result:
+			case <-stream.pipeline.ctx.Done():
+			}
+		}
+	}()
+
+	return results
+}
+
+/*
+Collect consumes a stream, and returns all of its outputs.
+
+Example:
+
+	results := Collect(docs)
+	pipeline.Wait()
+*/
+func Collect[T any](stream *Stream[T]) []Result[T, error] {
+	var results []Result[T, error]
+
+	for result := range stream.Results() {
+		results = append(results, result)
+	}
+
+	return results
+}
+
+/*
+From creates the source of a pipeline from a slice.
+
+Example:
+
+	urls := From(pipeline, []string{"https://a.example", "https://b.example"})
+*/
+func From[T any](pipeline *Pipeline, values []T) *Stream[T] {
+	source := make(chan T)
+
+	go func() {
+		defer close(source)
+
+		for _, value := range values {
+			select {
+			case source <- value:
+			case <-pipeline.ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return FromChannel(pipeline, source)
+}
+
+/*
+FromChannel creates the source of a pipeline from a channel. Closing the channel drains
+the pipeline.
+
+Example:
+
+	events := make(chan Event)
+	stream := FromChannel(pipeline, events)
+*/
+func FromChannel[T any](pipeline *Pipeline, values <-chan T) *Stream[T] {
+	items := make(chan streamItem[T])
+
+	go func() {
+		defer close(items)
+
+		for index := 0; ; index++ {
+			var value T
+			var ok bool
+
+			select {
+			case value, ok = <-values:
+				if !ok {
+					return
+				}
+			case <-pipeline.ctx.Done():
+				return
+			}
+
+			select {
+			case items <- streamItem[T]{index: index, result: Ok[T, error](value)}:
+			case <-pipeline.ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return &Stream[T]{pipeline: pipeline, items: items}
+}
+
+/*
+Stage is a step in a Pipeline, that turns every input into an output with its function,
+on a Pool of its own.
+*/
+type Stage[In any, Out any] struct {
+	// Name identifies the stage in the metrics and the dead letter sink.
+	Name string
+	// Workers is the size of the Pool of the stage, and so the number of inputs that are
+	// processed at once. It defaults to 1.
+	Workers int
+	// Buffer is the number of outputs that can wait for the next stage. It defaults to 0.
+	Buffer int
+	// Fn processes a single input.
+	Fn func(context.Context, In) (Out, error)
+}
+
+/*
+AddStage adds a stage to the pipeline, that processes the outputs of the given stream,
+and returns the stream of its own outputs.
+
+Example:
+
+	docs := AddStage(pages, Stage[[]byte, Doc]{Name: "parse", Workers: 2, Fn: parse})
+*/
+func AddStage[In any, Out any](upstream *Stream[In], stage Stage[In, Out]) *Stream[Out] {
+	pipeline := upstream.pipeline
+	workers := max(stage.Workers, 1)
+
+	runner := &stageRunner[In, Out]{
+		pipeline:  pipeline,
+		stage:     stage,
+		pool:      NewPool(pipeline.ctx, workers),
+		metrics:   pipeline.register(stage.Name, workers),
+		slots:     make(chan struct{}, workers),
+		completed: make(chan streamItem[Out], workers),
+		out:       make(chan streamItem[Out], max(stage.Buffer, 0)),
+	}
+
+	pipeline.wg.Add(1)
+	go runner.feed(upstream.items)
+	go runner.collect()
+
+	return &Stream[Out]{pipeline: pipeline, items: runner.out}
+}
+
+/*
+stageRunner moves the inputs of a stage onto its Pool, and the outputs to the next stage.
+A slot is taken for every input, and only given back once its output was emitted, which
+bounds both the work in flight and the outputs that wait to be reordered.
+*/
+type stageRunner[In any, Out any] struct {
+	pipeline  *Pipeline
+	stage     Stage[In, Out]
+	pool      *Pool
+	metrics   *stageMetrics
+	slots     chan struct{}
+	completed chan streamItem[Out]
+	out       chan streamItem[Out]
+	jobs      sync.WaitGroup
+}
+
+func (runner *stageRunner[In, Out]) feed(inputs <-chan streamItem[In]) {
+	ctx := runner.pipeline.ctx
+	index := 0
+
+	for input := range inputs {
+		if !acquire(ctx, runner.slots) {
+			break
+		}
+
+		runner.jobs.Add(1)
+		runner.process(index, input)
+		index++
+	}
+
+	runner.jobs.Wait()
+	close(runner.completed)
+}
+
+func (runner *stageRunner[In, Out]) process(index int, input streamItem[In]) {
+	if input.skip || input.result.IsErr() {
+		runner.completed <- streamItem[Out]{index: index, result: Err[Out](input.result.UnwrapErr()), skip: input.skip}
+		runner.jobs.Done()
+		return
+	}
+
+	runner.metrics.inFlight.Add(1)
+	value := input.result.Unwrap()
+	started := time.Now()
+
+	future := AsyncOn(runner.pipeline.ctx, runner.pool, func(ctx context.Context) (Out, error) {
+		return runner.stage.Fn(ctx, value)
+	})
+
+	future.onComplete(func() {
+		runner.metrics.inFlight.Add(-1)
+		runner.metrics.busy.Add(int64(time.Since(started)))
+
+		item := streamItem[Out]{index: index, result: future.settled()}
+
+		if future.err == nil {
+			runner.metrics.processed.Add(1)
+		} else {
+			runner.metrics.failed.Add(1)
+
+			if sink := runner.pipeline.config.DeadLetters; sink != nil {
+				if _, err := sink.Put(DeadLetter{
+					Envelope: &Envelope{Job: &StageInput{Stage: runner.stage.Name, Input: value}},
+					Attempts: []Attempt{{Started: started, Finished: time.Now(), Err: future.err}},
+					At:       time.Now(),
+				}); err != nil {
+					item.result = Err[Out](errors.Join(future.err, fmt.Errorf("cannot dead-letter the input: %w", err)))
+				} else {
+					runner.metrics.deadLettered.Add(1)
+					item.skip = true
+				}
+			}
+		}
+
+		runner.completed <- item
+		runner.jobs.Done()
+	})
+}
+
+func (runner *stageRunner[In, Out]) collect() {
+	defer runner.pipeline.wg.Done()
+	defer runner.pool.Shutdown()
+	defer close(runner.out)
+
+	pending := make(map[int]streamItem[Out])
+	next := 0
+
+	for item := range runner.completed {
+		if !runner.pipeline.config.Ordered {
+			runner.emit(item)
+			continue
+		}
+
+		pending[item.index] = item
+
+		for {
+			ready, ok := pending[next]
+			if !ok {
+				break
+			}
+
+			delete(pending, next)
+			runner.emit(ready)
+			next++
+		}
+	}
+}
+
+func (runner *stageRunner[In, Out]) emit(item streamItem[Out]) {
+	select {
+	case runner.out <- item:
+	case <-runner.pipeline.ctx.Done():
+	}
+
+	<-runner.slots
+}
+
+/*
+stageMetrics holds the live counters of a stage.
+*/
+type stageMetrics struct {
+	name         string
+	workers      int
+	processed    atomic.Int64
+	failed       atomic.Int64
+	deadLettered atomic.Int64
+	inFlight     atomic.Int64
+	busy         atomic.Int64
+}
+
+func (pipeline *Pipeline) register(name string, workers int) *stageMetrics {
+	pipeline.mu.Lock()
+	defer pipeline.mu.Unlock()
+
+	metrics := &stageMetrics{name: name, workers: workers}
+	pipeline.metrics = append(pipeline.metrics, metrics)
+
+	return metrics
+}
+
+func (metrics *stageMetrics) snapshot() StageMetrics {
+	return StageMetrics{
+		Name:         metrics.name,
+		Workers:      metrics.workers,
+		Processed:    metrics.processed.Load(),
+		Failed:       metrics.failed.Load(),
+		DeadLettered: metrics.deadLettered.Load(),
+		InFlight:     metrics.inFlight.Load(),
+		Busy:         time.Duration(metrics.busy.Load()),
+	}
+}
@@ -0,0 +1,159 @@
+package twoface
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/smartystreets/goconvey/convey"
+)
+
+func TestPipeline(t *testing.T) {
+	convey.Convey("Pipeline", t, func() {
+		ctx := context.Background()
+		items := []int{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}
+
+		double := Stage[int, int]{Name: "double", Workers: 3, Buffer: 2, Fn: func(ctx context.Context, item int) (int, error) {
+			time.Sleep(time.Duration(10-item) * time.Millisecond)
+			return item * 2, nil
+		}}
+
+		format := Stage[int, string]{Name: "format", Workers: 2, Fn: func(ctx context.Context, item int) (string, error) {
+			return strconv.Itoa(item), nil
+		}}
+
+		failOdd := Stage[int, int]{Name: "even", Workers: 2, Fn: func(ctx context.Context, item int) (int, error) {
+			if item%2 == 1 {
+				return 0, fmt.Errorf("item %d: %w", item, errDummy)
+			}
+			return item, nil
+		}}
+
+		convey.Convey("Should keep the order of the inputs when ordered", func() {
+			pipeline := NewPipeline(ctx, PipelineConfig{Ordered: true})
+			results := Collect(AddStage(AddStage(From(pipeline, items), double), format))
+			pipeline.Wait()
+
+			convey.So(results, convey.ShouldHaveLength, len(items))
+			for idx, result := range results {
+				convey.So(result.Unwrap(), convey.ShouldEqual, strconv.Itoa(items[idx]*2))
+			}
+		})
+
+		convey.Convey("Should emit every output when unordered", func() {
+			pipeline := NewPipeline(ctx, PipelineConfig{})
+			results := Collect(AddStage(From(pipeline, items), double))
+			pipeline.Wait()
+
+			total := 0
+			for _, result := range results {
+				total += result.Unwrap()
+			}
+			convey.So(results, convey.ShouldHaveLength, len(items))
+			convey.So(total, convey.ShouldEqual, 110)
+		})
+
+		convey.Convey("Should pass failures downstream as Err values", func() {
+			pipeline := NewPipeline(ctx, PipelineConfig{Ordered: true})
+			results := Collect(AddStage(AddStage(From(pipeline, items), failOdd), format))
+			pipeline.Wait()
+
+			convey.So(results, convey.ShouldHaveLength, len(items))
+			convey.So(results[0].UnwrapErr().Error(), convey.ShouldEqual, "item 1: dummy error")
+			convey.So(results[1].Unwrap(), convey.ShouldEqual, "2")
+
+			metrics := pipeline.Metrics()
+			convey.So(metrics[0].Failed, convey.ShouldEqual, 5)
+			convey.So(metrics[1].Processed, convey.ShouldEqual, 5)
+		})
+
+		convey.Convey("Should send failures to the dead letter sink", func() {
+			sink := NewMemoryDeadLetters()
+			pipeline := NewPipeline(ctx, PipelineConfig{Ordered: true, DeadLetters: sink})
+			results := Collect(AddStage(AddStage(From(pipeline, items), failOdd), format))
+			pipeline.Wait()
+
+			convey.So(results, convey.ShouldHaveLength, 5)
+			for idx, result := range results {
+				convey.So(result.Unwrap(), convey.ShouldEqual, strconv.Itoa((idx+1)*2))
+			}
+			convey.So(pipeline.Metrics()[0].DeadLettered, convey.ShouldEqual, 5)
+
+			letters, err := sink.List()
+			convey.So(err, convey.ShouldBeNil)
+			convey.So(letters, convey.ShouldHaveLength, 5)
+
+			input := letters[0].Envelope.Job.(*StageInput)
+			convey.So(input.Stage, convey.ShouldEqual, failOdd.Name)
+			convey.So(input.Input.(int)%2, convey.ShouldEqual, 1)
+			convey.So(letters[0].Attempts[0].Err, convey.ShouldWrap, errDummy)
+		})
+
+		convey.Convey("Should drain once the source channel is closed", func() {
+			source := make(chan int)
+			pipeline := NewPipeline(ctx, PipelineConfig{})
+			results := AddStage(FromChannel(pipeline, source), double).Results()
+
+			go func() {
+				for _, item := range items[:3] {
+					source <- item
+				}
+				close(source)
+			}()
+
+			var outputs []Result[int, error]
+			for result := range results {
+				outputs = append(outputs, result)
+			}
+			pipeline.Wait()
+
+			convey.So(outputs, convey.ShouldHaveLength, 3)
+			convey.So(pipeline.Metrics()[0].InFlight, convey.ShouldEqual, 0)
+		})
+
+		convey.Convey("Should stop processing when stopped", func() {
+			pipeline := NewPipeline(ctx, PipelineConfig{})
+			blocked := AddStage(From(pipeline, items), Stage[int, int]{Name: "blocked", Fn: func(ctx context.Context, item int) (int, error) {
+				<-ctx.Done()
+				return 0, ctx.Err()
+			}})
+
+			results := blocked.Results()
+			pipeline.Stop()
+			for range results {
+			}
+			pipeline.Wait()
+
+			convey.So(pipeline.Metrics()[0].Processed, convey.ShouldEqual, 0)
+		})
+
+		convey.Convey("Should report metrics for every stage", func() {
+			pipeline := NewPipeline(ctx, PipelineConfig{})
+			Collect(AddStage(AddStage(From(pipeline, items), double), format))
+			pipeline.Wait()
+
+			metrics := pipeline.Metrics()
+			convey.So(metrics, convey.ShouldHaveLength, 2)
+			convey.So(metrics[0].Name, convey.ShouldEqual, "double")
+			convey.So(metrics[0].Workers, convey.ShouldEqual, 3)
+			convey.So(metrics[0].Processed, convey.ShouldEqual, len(items))
+			convey.So(metrics[0].Busy, convey.ShouldBeGreaterThan, 0)
+			convey.So(metrics[1].Processed, convey.ShouldEqual, len(items))
+		})
+	})
+}
+
+func BenchmarkPipeline(b *testing.B) {
+	ctx := context.Background()
+	items := make([]int, 100)
+
+	for i := 0; i < b.N; i++ {
+		pipeline := NewPipeline(ctx, PipelineConfig{Ordered: true})
+		Collect(AddStage(From(pipeline, items), Stage[int, int]{Workers: 4, Fn: func(ctx context.Context, item int) (int, error) {
+			return item, nil
+		}}))
+		pipeline.Wait()
+	}
+}
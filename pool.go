@@ -2,9 +2,17 @@ package twoface
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"sync"
 )
 
+/*
+ErrPoolShutdown is the error of a derived Future whose callbacks were sent to a Pool that
+was already shut down, so they could not run.
+*/
+var ErrPoolShutdown = errors.New("the pool was shut down")
+
 /*
 Pool is a set of Worker types, each running their own (pre-warmed) goroutine.
 Any object that implements the Job interface is able to schedule work on the
@@ -12,19 +20,48 @@ worker pool, which keeps the amount of goroutines in check, while still being
 able to benefit from high concurrency in all kinds of scenarios.
 */
 type Pool struct {
-	ctx        context.Context
-	cancel     context.CancelFunc
-	workerPool chan chan Job
-	jobQueue   chan Job
-	workers    []*Worker
-	wg         *sync.WaitGroup
+	ctx          context.Context
+	cancel       context.CancelFunc
+	workerPool   chan chan Job
+	jobQueue     chan Job
+	workers      []*Worker
+	wg           *sync.WaitGroup
+	keyed        bool
+	keys         map[string][]Job
+	finished     chan string
+	stopped      chan struct{}
+	schedule     *scheduler
+	queue        Queue
+	mu           sync.Mutex
+	submitted    map[uint64]struct{}
+	delivered    *sync.Cond
+	consuming    bool
+	consumed     chan struct{}
+	stopConsumer context.CancelFunc
+	deadLetters  DeadLetterSink
+	idempotency  *idempotency
+}
+
+/*
+PoolOption configures optional behavior of a Pool.
+*/
+type PoolOption func(*Pool)
+
+/*
+WithKeyedOrdering makes the pool run jobs that implement KeyedJob one at a time per key,
+in the order they were submitted, while jobs for different keys still run in parallel.
+*/
+func WithKeyedOrdering() PoolOption {
+	return func(pool *Pool) {
+		pool.keyed = true
+	}
 }
 
 /*
 NewPool instantiates a worker pool with a given number of workers, taking in a
 context for cleanly canceling all of the sub-processes it starts.
 */
-func NewPool(ctx context.Context, numWorkers int) *Pool {
+func NewPool(ctx context.Context, numWorkers int, options ...PoolOption) *Pool {
 	ctx, cancel := context.WithCancel(ctx)
 	wg := &sync.WaitGroup{}
 
@@ -35,19 +72,46 @@ func NewPool(ctx context.Context, numWorkers int) *Pool {
 		jobQueue:   make(chan Job),
 		workers:    make([]*Worker, 0, numWorkers),
 		wg:         wg,
+		keys:       make(map[string][]Job),
+		finished:   make(chan string),
+		stopped:    make(chan struct{}),
+	}
+
+	pool.schedule = newScheduler(pool)
+
+	for _, option := range options {
+		option(pool)
 	}
 
 	for i := 0; i < numWorkers; i++ {
-		worker := NewWorker(i, pool.workerPool, ctx)
-		worker.Start()
-		pool.workers = append(pool.workers, worker)
+		pool.workers = append(pool.workers, pool.newWorker(i).Start())
 	}
 
 	go pool.dispatch()
+	go pool.schedule.run()
+
+	if pool.queue != nil {
+		consumeCtx, stopConsumer := context.WithCancel(ctx)
+		pool.submitted = make(map[uint64]struct{})
+		pool.delivered = sync.NewCond(&pool.mu)
+		pool.consuming = true
+		pool.consumed = make(chan struct{})
+		pool.stopConsumer = stopConsumer
+		go pool.consume(consumeCtx)
+	}
 
 	return pool
 }
 
+/*
+newWorker creates a worker that takes its jobs from the pool, but does not start it yet.
+*/
+func (pool *Pool) newWorker(id int) *Worker {
+	worker := NewWorker(id, pool.workerPool, pool.ctx)
+	worker.deadLetters = pool.deadLetters
+	return worker
+}
+
 /*
 Size returns the current size of the pool by counting the currently active workers.
 */
@@ -57,32 +121,274 @@ func (pool *Pool) Size() int {
 
 /*
 Submit is the entry point for new jobs that want to be scheduled onto the worker pool.
+Jobs that are submitted after the pool has been shut down are dropped.
 */
 func (pool *Pool) Submit(job Job) {
+	if _, local := job.(localJob); pool.queue != nil && !local {
+		pool.persist(job)
+		return
+	}
+
 	pool.wg.Add(1)
-	pool.jobQueue <- job
+
+	select {
+	case pool.jobQueue <- job:
+	case <-pool.ctx.Done():
+		pool.drop(job)
+	}
 }
 
 /*
-Shutdown gracefully shuts down the pool and waits for all workers to complete.
+Execute runs a task on one of the workers, which makes a Pool usable as the Executor
+of a Future.
+*/
+func (pool *Pool) Execute(task func()) {
+	pool.Submit(taskJob(task))
+}
+
+/*
+executeOr runs a task like Execute, but calls refused when the pool drops the task, so the
+Future that waits for it can fail with ErrPoolShutdown.
+*/
+func (pool *Pool) executeOr(task func(), refused func(error)) {
+	pool.Submit(refusableTask{task: task, refused: refused})
+}
+
+/*
+Shutdown gracefully shuts down the pool. It waits for all jobs that were submitted before
+it returns, including the ones that are still waiting for a worker, and jobs that those
+jobs submit while they run. Only then does it stop the workers and the dispatcher.
+Scheduled jobs that are not yet due are dropped. With a Queue, jobs that were submitted
+are taken from it before the pool stops, but jobs that the queue held from before are left
+in it. Jobs that are submitted after Shutdown are dropped, and Futures that use the pool as
+their Executor fail with ErrPoolShutdown.
+
+Example:
+
+	pool := NewPool(ctx, 4)
+	pool.Execute(task)
+	pool.Shutdown() // task has run
 */
 func (pool *Pool) Shutdown() {
-	pool.cancel()
+	pool.schedule.close()
+	pool.stopConsuming()
 	pool.wg.Wait()
-	close(pool.jobQueue)
-	close(pool.workerPool)
+	pool.cancel()
+	<-pool.stopped
 }
 
+/*
+dispatch hands out jobs to workers in the order they were submitted. Jobs are kept in a
+backlog until a worker is available, so submitting a job never has to wait for one, not
+even from inside a running job.
+*/
 func (pool *Pool) dispatch() {
+	defer close(pool.stopped)
+
+	var backlog []Job
+
 	for {
+		var workers chan chan Job
+
+		backlog = pool.skipCancelled(backlog)
+
+		if len(backlog) > 0 {
+			workers = pool.workerPool
+		}
+
 		select {
 		case job := <-pool.jobQueue:
-			// A new job was received from the jobs queue, get the first available worker from the pool once ready.
-			jobChannel := <-pool.workerPool
+			backlog = pool.enqueue(backlog, job)
+		case key := <-pool.finished:
+			backlog = pool.release(backlog, key)
+		case jobChannel := <-workers:
+			job := backlog[0]
+			backlog = backlog[1:]
+
 			// Send the job to the worker for processing.
-			jobChannel <- job
+			select {
+			case jobChannel <- trackedJob{job: job, wg: pool.wg}:
+			case <-pool.ctx.Done():
+				pool.drop(job)
+			}
 		case <-pool.ctx.Done():
+			for _, job := range backlog {
+				pool.drop(job)
+			}
+			for _, pending := range pool.keys {
+				for _, job := range pending {
+					pool.drop(job)
+				}
+			}
 			return
 		}
 	}
 }
+
+/*
+skipCancelled removes the jobs at the front of the backlog that no longer need to run.
+*/
+func (pool *Pool) skipCancelled(backlog []Job) []Job {
+	for len(backlog) > 0 {
+		cancellable, ok := backlog[0].(CancellableJob)
+		if !ok || !cancellable.Cancelled() {
+			break
+		}
+
+		backlog = backlog[1:]
+		pool.wg.Done()
+
+		if keyed, ok := cancellable.(keyedJob); ok {
+			backlog = pool.release(backlog, keyed.key)
+		}
+	}
+
+	return backlog
+}
+
+/*
+enqueue adds a job to the backlog. With keyed ordering, a job whose key already has a job
+in the backlog or on a worker waits in the queue of that key instead.
+*/
+func (pool *Pool) enqueue(backlog []Job, job Job) []Job {
+	keyed, ok := job.(KeyedJob)
+	if delivered, isDelivered := job.(deliveredJob); isDelivered {
+		keyed, ok = delivered.delivery.Job.(KeyedJob)
+	}
+
+	if !pool.keyed || !ok {
+		return append(backlog, job)
+	}
+
+	key := keyed.Key()
+	wrapped := keyedJob{job: job, key: key, pool: pool}
+
+	if pending, busy := pool.keys[key]; busy {
+		pool.keys[key] = append(pending, wrapped)
+		return backlog
+	}
+
+	pool.keys[key] = nil
+	return append(backlog, wrapped)
+}
+
+/*
+release moves the next job of a key to the backlog, once the previous one has finished.
+Keys without any waiting jobs are forgotten, so idle keys do not take up any memory.
+*/
+func (pool *Pool) release(backlog []Job, key string) []Job {
+	pending := pool.keys[key]
+
+	if len(pending) == 0 {
+		delete(pool.keys, key)
+		return backlog
+	}
+
+	pool.keys[key] = pending[1:]
+	return append(backlog, pending[0])
+}
+
+/*
+drop accounts for a job that will never run, because the pool was shut down.
+*/
+func (pool *Pool) drop(job Job) {
+	discardJob(job, pool.ctx.Err())
+	pool.wg.Done()
+}
+
+/*
+CancellableJob is implemented by jobs whose outcome may no longer be needed by the time
+a worker becomes available for them. The pool skips these jobs when Cancelled returns true.
+*/
+type CancellableJob interface {
+	Job
+	Cancelled() bool
+}
+
+/*
+KeyedJob is implemented by jobs that belong to an entity, like a user or an account.
+In a pool created with WithKeyedOrdering, jobs with the same key never run at the same
+time, and run in the order they were submitted.
+*/
+type KeyedJob interface {
+	Job
+	Key() string
+}
+
+/*
+keyedJob lets the dispatcher know when a job with a key has finished, so the next job
+with that key can be scheduled.
+*/
+type keyedJob struct {
+	job  Job
+	key  string
+	pool *Pool
+}
+
+func (keyed keyedJob) Do() Result[any, error] {
+	defer func() {
+		select {
+		case keyed.pool.finished <- keyed.key:
+		case <-keyed.pool.ctx.Done():
+		}
+	}()
+
+	return keyed.job.Do()
+}
+
+func (keyed keyedJob) Cancelled() bool {
+	cancellable, ok := keyed.job.(CancellableJob)
+	return ok && cancellable.Cancelled()
+}
+
+func (keyed keyedJob) discard(err error) {
+	discardJob(keyed.job, err)
+}
+
+/*
+discardable is implemented by jobs that need to know when the pool drops them
+without running them, for instance to complete a Future.
+*/
+type discardable interface {
+	discard(err error)
+}
+
+/*
+trackedJob marks a job as completed in the wait group of its pool once it has run.
+*/
+type trackedJob struct {
+	job Job
+	wg  *sync.WaitGroup
+}
+
+func (tracked trackedJob) Do() Result[any, error] {
+	defer tracked.wg.Done()
+	return tracked.job.Do()
+}
+
+/*
+taskJob turns a plain function into a Job.
+*/
+type taskJob func()
+
+func (task taskJob) Do() Result[any, error] {
+	task()
+	return Ok[any, error](nil)
+}
+
+/*
+refusableTask is a task that reports it when the pool drops it.
+*/
+type refusableTask struct {
+	task    func()
+	refused func(error)
+}
+
+func (task refusableTask) Do() Result[any, error] {
+	task.task()
+	return Ok[any, error](nil)
+}
+
+func (task refusableTask) discard(err error) {
+	task.refused(fmt.Errorf("%w: %w", ErrPoolShutdown, err))
+}
@@ -0,0 +1,243 @@
+package twoface
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/smartystreets/goconvey/convey"
+)
+
+// orderedJob records the order in which the jobs of each key run.
+type orderedJob struct {
+	key     string
+	seq     int
+	log     *orderLog
+	latency time.Duration
+}
+
+func (job orderedJob) Key() string {
+	return job.key
+}
+
+func (job orderedJob) Do() Result[any, error] {
+	job.log.start(job.key)
+	time.Sleep(job.latency)
+	job.log.finish(job.key, job.seq)
+	return Ok[any, error](nil)
+}
+
+type orderLog struct {
+	mu        sync.Mutex
+	running   map[string]int
+	order     map[string][]int
+	overlaps  int
+	active    atomic.Int64
+	maxActive atomic.Int64
+}
+
+func newOrderLog() *orderLog {
+	return &orderLog{running: make(map[string]int), order: make(map[string][]int)}
+}
+
+func (log *orderLog) start(key string) {
+	active := log.active.Add(1)
+	for {
+		seen := log.maxActive.Load()
+		if active <= seen || log.maxActive.CompareAndSwap(seen, active) {
+			break
+		}
+	}
+
+	log.mu.Lock()
+	defer log.mu.Unlock()
+	log.running[key]++
+	if log.running[key] > 1 {
+		log.overlaps++
+	}
+}
+
+func (log *orderLog) finish(key string, seq int) {
+	log.active.Add(-1)
+
+	log.mu.Lock()
+	defer log.mu.Unlock()
+	log.running[key]--
+	log.order[key] = append(log.order[key], seq)
+}
+
+func TestPool(t *testing.T) {
+	convey.Convey("Pool", t, func() {
+		ctx := context.Background()
+
+		convey.Convey("Should run every submitted job before shutting down", func() {
+			pool := NewPool(ctx, 4)
+			var count atomic.Int64
+			for range 50 {
+				pool.Execute(func() { count.Add(1) })
+			}
+			pool.Shutdown()
+			convey.So(count.Load(), convey.ShouldEqual, 50)
+		})
+
+		convey.Convey("Should wait for the backlog, and for jobs that jobs submit, when shutting down", func() {
+			pool := NewPool(ctx, 1)
+			blocker := make(chan struct{})
+			var count atomic.Int64
+
+			pool.Execute(func() { <-blocker })
+			for range 10 {
+				pool.Execute(func() {
+					pool.Execute(func() { count.Add(1) })
+					count.Add(1)
+				})
+			}
+
+			go close(blocker)
+			pool.Shutdown()
+			convey.So(count.Load(), convey.ShouldEqual, 20)
+		})
+
+		convey.Convey("Should fail the futures that use it as their Executor after shutting down", func() {
+			pool := NewPool(ctx, 2)
+			pool.Shutdown()
+
+			_, err := AsyncOn(ctx, pool, func(ctx context.Context) (int, error) { return 42, nil }).Result()
+			convey.So(err, convey.ShouldEqual, context.Canceled)
+
+			p, f := NewPromise[int]()
+			called := false
+			f.WithExecutor(pool).Then(func(int) { called = true })
+			applied := ThenApply(f, func(v int) (int, error) { return v + 1, nil })
+			p.Set(41, nil)
+
+			_, err = applied.Result()
+			convey.So(errors.Is(err, ErrPoolShutdown), convey.ShouldBeTrue)
+			convey.So(called, convey.ShouldBeFalse)
+		})
+
+		convey.Convey("Should run the jobs of a key in order with keyed ordering", func() {
+			pool := NewPool(ctx, 8, WithKeyedOrdering())
+			log := newOrderLog()
+			keys := []string{"alice", "bob", "carol", "dave"}
+
+			var wg sync.WaitGroup
+			for _, key := range keys {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					for seq := range 25 {
+						pool.Submit(orderedJob{key: key, seq: seq, log: log, latency: time.Duration(seq%3) * time.Millisecond})
+					}
+				}()
+			}
+			wg.Wait()
+			pool.Shutdown()
+
+			expected := make([]int, 25)
+			for seq := range expected {
+				expected[seq] = seq
+			}
+
+			convey.So(log.overlaps, convey.ShouldEqual, 0)
+			for _, key := range keys {
+				convey.So(log.order[key], convey.ShouldResemble, expected)
+			}
+			convey.So(log.maxActive.Load(), convey.ShouldBeGreaterThan, 1)
+			convey.So(pool.keys, convey.ShouldBeEmpty)
+		})
+
+		convey.Convey("Should run jobs of different keys in parallel", func() {
+			pool := NewPool(ctx, 4, WithKeyedOrdering())
+			log := newOrderLog()
+			for idx := range 4 {
+				pool.Submit(orderedJob{key: fmt.Sprint(idx), log: log, latency: 20 * time.Millisecond})
+			}
+			pool.Shutdown()
+			convey.So(log.maxActive.Load(), convey.ShouldEqual, 4)
+		})
+
+		convey.Convey("Should still run jobs without a key in parallel", func() {
+			pool := NewPool(ctx, 4, WithKeyedOrdering())
+			var count atomic.Int64
+			for range 20 {
+				pool.Execute(func() { count.Add(1) })
+			}
+			pool.Submit(orderedJob{key: "alice", log: newOrderLog()})
+			pool.Shutdown()
+			convey.So(count.Load(), convey.ShouldEqual, 20)
+			convey.So(pool.keys, convey.ShouldBeEmpty)
+		})
+
+		convey.Convey("Should move on to the next job of a key when one is cancelled", func() {
+			pool := NewPool(ctx, 1, WithKeyedOrdering())
+			blocker := make(chan struct{})
+			pool.Execute(func() { <-blocker })
+
+			first := keyedAsync(ctx, pool, "alice", 1)
+			second := keyedAsync(ctx, pool, "alice", 2)
+			first.Cancel()
+			close(blocker)
+
+			result, err := second.Result()
+			convey.So(result, convey.ShouldEqual, 2)
+			convey.So(err, convey.ShouldBeNil)
+			pool.Shutdown()
+			convey.So(pool.keys, convey.ShouldBeEmpty)
+		})
+
+		convey.Convey("Should drop the waiting jobs of a key when the context is done", func() {
+			ctx, cancel := context.WithCancel(ctx)
+			pool := NewPool(ctx, 1, WithKeyedOrdering())
+			blocker := make(chan struct{})
+			first := keyedAsync(ctx, pool, "alice", 1, blocker)
+			second := keyedAsync(ctx, pool, "alice", 2)
+
+			cancel()
+			close(blocker)
+
+			_, err := second.Result()
+			convey.So(err, convey.ShouldEqual, context.Canceled)
+			first.Result()
+			pool.Shutdown()
+		})
+	})
+}
+
+// keyedFuture is an asyncJob that carries a key.
+type keyedFuture[T any] struct {
+	asyncJob[T]
+	key string
+}
+
+func (job keyedFuture[T]) Key() string {
+	return job.key
+}
+
+func keyedAsync(ctx context.Context, pool *Pool, key string, value int, wait ...chan struct{}) *Future[int] {
+	promise, future := NewPromise[int]()
+	pool.Submit(keyedFuture[int]{
+		asyncJob: asyncJob[int]{ctx: ctx, promise: promise, fn: func(ctx context.Context) (int, error) {
+			for _, ch := range wait {
+				<-ch
+			}
+			return value, nil
+		}},
+		key: key,
+	})
+	return future
+}
+
+func BenchmarkKeyedPool(b *testing.B) {
+	pool := NewPool(context.Background(), 4, WithKeyedOrdering())
+	defer pool.Shutdown()
+	log := newOrderLog()
+
+	for i := 0; i < b.N; i++ {
+		pool.Submit(orderedJob{key: fmt.Sprint(i % 16), seq: i, log: log})
+	}
+}
@@ -0,0 +1,360 @@
+package twoface
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+/*
+ErrQueueClosed is returned by a Queue that has been closed.
+*/
+var ErrQueueClosed = errors.New("the queue is closed")
+
+/*
+Queue holds the jobs of a Pool until a worker is ready for them. A job that was taken
+from the queue stays in it until it is acknowledged, so a durable queue can deliver it
+again after a crash, which makes delivery at-least-once.
+*/
+type Queue interface {
+	// Enqueue adds a job to the back of the queue, and returns its ID.
+	Enqueue(job Job) (uint64, error)
+	// Dequeue takes the job at the front of the queue, blocking until there is one.
+	Dequeue(ctx context.Context) (Delivery, error)
+	// Ack removes a delivered job from the queue for good.
+	Ack(id uint64) error
+	// Nack puts a delivered job back at the end of the queue.
+	Nack(id uint64) error
+	// Len returns the number of jobs in the queue, including the delivered ones that
+	// were not yet acknowledged.
+	Len() int
+	// Close releases the resources of the queue.
+	Close() error
+}
+
+/*
+Delivery is a job that was taken from a Queue.
+*/
+type Delivery struct {
+	ID  uint64
+	Job Job
+	// Attempts is the number of times the job was delivered before, and put back.
+	Attempts int
+}
+
+/*
+WithQueue makes the pool keep submitted jobs in the queue until a worker runs them,
+instead of in memory. A job is acknowledged once it has run, whatever its Result, and
+put back when the pool drops it. Jobs that a durable queue still holds from before a
+restart are run as well. Jobs that only make sense within the process, like the tasks
+given to Execute and the jobs behind a Future, bypass the queue.
+
+Example:
+
+	queue, err := OpenFileQueue(FileQueueConfig{Dir: "jobs", Registry: registry})
+	pool := NewPool(ctx, 4, WithQueue(queue))
+*/
+func WithQueue(queue Queue) PoolOption {
+	return func(pool *Pool) {
+		pool.queue = queue
+	}
+}
+
+/*
+localJob is implemented by jobs that cannot outlive the process, and so are never put in
+a Queue.
+*/
+type localJob interface {
+	local()
+}
+
+func (task taskJob) local() {}
+
+func (task refusableTask) local() {}
+
+func (job asyncJob[T]) local() {}
+
+func (run cronRun) local() {}
+
+/*
+persist adds the job to the queue of the pool. Jobs are tracked until they are taken
+from the queue, so Shutdown can wait for them.
+*/
+func (pool *Pool) persist(job Job) {
+	pool.mu.Lock()
+	defer pool.mu.Unlock()
+
+	if err := pool.ctx.Err(); err != nil {
+		discardJob(job, err)
+		return
+	}
+
+	id, err := pool.queue.Enqueue(job)
+	if err != nil {
+		discardJob(job, err)
+		return
+	}
+
+	// Once the pool stopped consuming, the job is left in the queue for next time.
+	if pool.consuming {
+		pool.wg.Add(1)
+		pool.submitted[id] = struct{}{}
+	}
+}
+
+/*
+consume moves jobs from the queue to the dispatcher, until the pool stops consuming.
+*/
+func (pool *Pool) consume(ctx context.Context) {
+	defer close(pool.consumed)
+	defer pool.forgetSubmitted()
+
+	for {
+		delivery, err := pool.queue.Dequeue(ctx)
+		if err != nil {
+			return
+		}
+
+		pool.mu.Lock()
+		if _, ok := pool.submitted[delivery.ID]; ok {
+			delete(pool.submitted, delivery.ID)
+			pool.delivered.Broadcast()
+		} else {
+			pool.wg.Add(1)
+		}
+		pool.mu.Unlock()
+
+		job := deliveredJob{delivery: delivery, queue: pool.queue}
+
+		select {
+		case pool.jobQueue <- job:
+		case <-pool.ctx.Done():
+			pool.drop(job)
+			return
+		}
+	}
+}
+
+/*
+forgetSubmitted stops tracking the jobs that were submitted but never taken from the
+queue, which stay in it for the next time the queue is used.
+*/
+func (pool *Pool) forgetSubmitted() {
+	pool.mu.Lock()
+	defer pool.mu.Unlock()
+
+	pool.consuming = false
+
+	for id := range pool.submitted {
+		delete(pool.submitted, id)
+		pool.wg.Done()
+	}
+
+	pool.delivered.Broadcast()
+}
+
+/*
+stopConsuming waits until every job that was submitted was taken from the queue, and
+then stops taking any more.
+*/
+func (pool *Pool) stopConsuming() {
+	if pool.queue == nil {
+		return
+	}
+
+	pool.mu.Lock()
+	for len(pool.submitted) > 0 && pool.ctx.Err() == nil {
+		pool.delivered.Wait()
+	}
+	pool.mu.Unlock()
+
+	pool.stopConsumer()
+	<-pool.consumed
+}
+
+/*
+deliveredJob runs a job from a Queue, and acknowledges it once it has run.
+*/
+type deliveredJob struct {
+	delivery Delivery
+	queue    Queue
+}
+
+func (delivered deliveredJob) Do() Result[any, error] {
+	result := delivered.delivery.Job.Do()
+
+	if err := delivered.queue.Ack(delivered.delivery.ID); err != nil {
+		return Err[any](fmt.Errorf("acknowledging job %d: %w", delivered.delivery.ID, err))
+	}
+
+	return result
+}
+
+func (delivered deliveredJob) discard(err error) {
+	discardJob(delivered.delivery.Job, err)
+	delivered.queue.Nack(delivered.delivery.ID)
+}
+
+/*
+brokenJob stands in for a job that a Queue could not decode, so it is reported by the
+worker that runs it, instead of blocking the queue.
+*/
+type brokenJob struct {
+	err error
+}
+
+func (broken brokenJob) Do() Result[any, error] {
+	return Err[any](broken.err)
+}
+
+/*
+MemoryQueue is a Queue that keeps its jobs in memory, so they do not survive a restart.
+
+Example:
+
+	pool := NewPool(ctx, 4, WithQueue(NewMemoryQueue()))
+*/
+type MemoryQueue struct {
+	mu       sync.Mutex
+	ready    []Delivery
+	inFlight map[uint64]Delivery
+	nextID   uint64
+	signal   chan struct{}
+	closed   bool
+}
+
+/*
+NewMemoryQueue creates an empty MemoryQueue.
+
+Example:
+
+	queue := NewMemoryQueue()
+*/
+func NewMemoryQueue() *MemoryQueue {
+	return &MemoryQueue{
+		inFlight: make(map[uint64]Delivery),
+		signal:   make(chan struct{}),
+	}
+}
+
+/*
+Enqueue adds a job to the back of the queue, and returns its ID, which increases with
+every job.
+*/
+func (queue *MemoryQueue) Enqueue(job Job) (uint64, error) {
+	queue.mu.Lock()
+	defer queue.mu.Unlock()
+
+	if queue.closed {
+		return 0, ErrQueueClosed
+	}
+
+	queue.nextID++
+	queue.ready = append(queue.ready, Delivery{ID: queue.nextID, Job: job})
+	queue.notify()
+
+	return queue.nextID, nil
+}
+
+/*
+Dequeue takes the job at the front of the queue, blocking until there is one, the queue
+is closed, or the context is done. The job stays in flight until it is acknowledged.
+*/
+func (queue *MemoryQueue) Dequeue(ctx context.Context) (Delivery, error) {
+	for {
+		queue.mu.Lock()
+
+		if queue.closed {
+			queue.mu.Unlock()
+			return Delivery{}, ErrQueueClosed
+		}
+
+		if len(queue.ready) > 0 {
+			delivery := queue.ready[0]
+			queue.ready = queue.ready[1:]
+			queue.inFlight[delivery.ID] = delivery
+			queue.mu.Unlock()
+			return stampDelivery(delivery), nil
+		}
+
+		signal := queue.signal
+		queue.mu.Unlock()
+
+		select {
+		case <-signal:
+		case <-ctx.Done():
+			return Delivery{}, ctx.Err()
+		}
+	}
+}
+
+/*
+Ack removes a job that is in flight from the queue for good.
+*/
+func (queue *MemoryQueue) Ack(id uint64) error {
+	queue.mu.Lock()
+	defer queue.mu.Unlock()
+
+	if _, ok := queue.inFlight[id]; !ok {
+		return fmt.Errorf("job %d is not in flight", id)
+	}
+
+	delete(queue.inFlight, id)
+	return nil
+}
+
+/*
+Nack puts a job that is in flight back at the end of the queue, counting the attempt.
+*/
+func (queue *MemoryQueue) Nack(id uint64) error {
+	queue.mu.Lock()
+	defer queue.mu.Unlock()
+
+	delivery, ok := queue.inFlight[id]
+	if !ok {
+		return fmt.Errorf("job %d is not in flight", id)
+	}
+
+	delete(queue.inFlight, id)
+	delivery.Attempts++
+	queue.ready = append(queue.ready, delivery)
+	queue.notify()
+
+	return nil
+}
+
+/*
+Len returns the number of jobs in the queue, including the ones that are in flight.
+*/
+func (queue *MemoryQueue) Len() int {
+	queue.mu.Lock()
+	defer queue.mu.Unlock()
+
+	return len(queue.ready) + len(queue.inFlight)
+}
+
+/*
+Close stops the queue, so Enqueue and Dequeue return ErrQueueClosed. The jobs in it are
+lost, as the queue only lives in memory.
+*/
+func (queue *MemoryQueue) Close() error {
+	queue.mu.Lock()
+	defer queue.mu.Unlock()
+
+	if !queue.closed {
+		queue.closed = true
+		queue.notify()
+	}
+
+	return nil
+}
+
+/*
+notify wakes up every Dequeue that is waiting for a job. It has to be called with the
+lock held.
+*/
+func (queue *MemoryQueue) notify() {
+	close(queue.signal)
+	queue.signal = make(chan struct{})
+}
@@ -0,0 +1,163 @@
+package twoface
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/smartystreets/goconvey/convey"
+)
+
+// recordedJob is a serializable job that records its name when it runs.
+type recordedJob struct {
+	Name string `json:"name"`
+}
+
+var jobRecorder struct {
+	mu    sync.Mutex
+	names []string
+}
+
+func (job *recordedJob) Do() Result[any, error] {
+	jobRecorder.mu.Lock()
+	defer jobRecorder.mu.Unlock()
+	jobRecorder.names = append(jobRecorder.names, job.Name)
+	return Ok[any, error](job.Name)
+}
+
+func recordedNames() []string {
+	jobRecorder.mu.Lock()
+	defer jobRecorder.mu.Unlock()
+	return append([]string{}, jobRecorder.names...)
+}
+
+func resetRecorder() {
+	jobRecorder.mu.Lock()
+	defer jobRecorder.mu.Unlock()
+	jobRecorder.names = nil
+}
+
+func TestQueue(t *testing.T) {
+	convey.Convey("Queue", t, func() {
+		ctx := context.Background()
+		resetRecorder()
+
+		convey.Convey("MemoryQueue should deliver jobs in order until they are acknowledged", func() {
+			queue := NewMemoryQueue()
+			first, _ := queue.Enqueue(&recordedJob{Name: "first"})
+			queue.Enqueue(&recordedJob{Name: "second"})
+			convey.So(queue.Len(), convey.ShouldEqual, 2)
+
+			delivery, err := queue.Dequeue(ctx)
+			convey.So(err, convey.ShouldBeNil)
+			convey.So(delivery.ID, convey.ShouldEqual, first)
+			convey.So(delivery.Job.(*recordedJob).Name, convey.ShouldEqual, "first")
+
+			convey.So(queue.Nack(delivery.ID), convey.ShouldBeNil)
+			second, _ := queue.Dequeue(ctx)
+			convey.So(second.Job.(*recordedJob).Name, convey.ShouldEqual, "second")
+
+			again, _ := queue.Dequeue(ctx)
+			convey.So(again.ID, convey.ShouldEqual, first)
+			convey.So(again.Attempts, convey.ShouldEqual, 1)
+
+			convey.So(queue.Ack(again.ID), convey.ShouldBeNil)
+			convey.So(queue.Ack(again.ID), convey.ShouldNotBeNil)
+			convey.So(queue.Len(), convey.ShouldEqual, 1)
+		})
+
+		convey.Convey("MemoryQueue should block until there is a job", func() {
+			queue := NewMemoryQueue()
+			timeout, cancel := context.WithTimeout(ctx, 5*time.Millisecond)
+			defer cancel()
+			_, err := queue.Dequeue(timeout)
+			convey.So(err, convey.ShouldEqual, context.DeadlineExceeded)
+
+			queue.Close()
+			_, err = queue.Dequeue(ctx)
+			convey.So(err, convey.ShouldEqual, ErrQueueClosed)
+			_, err = queue.Enqueue(&recordedJob{})
+			convey.So(err, convey.ShouldEqual, ErrQueueClosed)
+		})
+
+		convey.Convey("A Pool should run the jobs of its queue, and acknowledge them", func() {
+			queue := NewMemoryQueue()
+			pool := NewPool(ctx, 2, WithQueue(queue))
+			for _, name := range []string{"a", "b", "c"} {
+				pool.Submit(&recordedJob{Name: name})
+			}
+			pool.Shutdown()
+
+			convey.So(recordedNames(), convey.ShouldHaveLength, 3)
+			convey.So(queue.Len(), convey.ShouldEqual, 0)
+		})
+
+		convey.Convey("A Pool should run the jobs that were in its queue before it started", func() {
+			queue := NewMemoryQueue()
+			queue.Enqueue(&recordedJob{Name: "left over"})
+
+			pool := NewPool(ctx, 1, WithQueue(queue))
+			pool.Submit(&recordedJob{Name: "new"})
+			pool.Shutdown()
+
+			convey.So(recordedNames(), convey.ShouldResemble, []string{"left over", "new"})
+		})
+
+		convey.Convey("A Pool should keep Futures and tasks out of its queue", func() {
+			queue := NewMemoryQueue()
+			pool := NewPool(ctx, 1, WithQueue(queue))
+
+			var ran atomic.Bool
+			pool.Execute(func() { ran.Store(true) })
+			value, err := AsyncOn(ctx, pool, func(ctx context.Context) (int, error) { return 42, nil }).Result()
+			pool.Shutdown()
+
+			convey.So(value, convey.ShouldEqual, 42)
+			convey.So(err, convey.ShouldBeNil)
+			convey.So(ran.Load(), convey.ShouldBeTrue)
+			convey.So(queue.Len(), convey.ShouldEqual, 0)
+		})
+
+		convey.Convey("A Pool should put back the jobs it drops", func() {
+			queue := NewMemoryQueue()
+			queue.Enqueue(&recordedJob{})
+			delivery, _ := queue.Dequeue(ctx)
+			deliveredJob{delivery: delivery, queue: queue}.discard(context.Canceled)
+
+			redelivered, _ := queue.Dequeue(ctx)
+			convey.So(redelivered.ID, convey.ShouldEqual, delivery.ID)
+			convey.So(redelivered.Attempts, convey.ShouldEqual, 1)
+			convey.So(recordedNames(), convey.ShouldBeEmpty)
+		})
+
+		convey.Convey("A Pool should not wait for a job that its queue refuses", func() {
+			pool := NewPool(ctx, 1, WithQueue(&failingQueue{MemoryQueue: NewMemoryQueue()}))
+			pool.Submit(&recordedJob{Name: "refused"})
+			pool.Shutdown()
+			convey.So(recordedNames(), convey.ShouldBeEmpty)
+		})
+	})
+}
+
+// failingQueue refuses every job.
+type failingQueue struct {
+	*MemoryQueue
+}
+
+func (queue *failingQueue) Enqueue(job Job) (uint64, error) {
+	return 0, errors.New("disk full")
+}
+
+func BenchmarkMemoryQueue(b *testing.B) {
+	queue := NewMemoryQueue()
+	ctx := context.Background()
+
+	for i := 0; i < b.N; i++ {
+		queue.Enqueue(&recordedJob{})
+		delivery, _ := queue.Dequeue(ctx)
+		queue.Ack(delivery.ID)
+	}
+}
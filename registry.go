@@ -0,0 +1,311 @@
+package twoface
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sync"
+)
+
+/*
+JobRegistry maps names to the types of jobs, so a Job can be turned into bytes and back,
+for instance to store it in a FileQueue, or send it to another process. The payload of a
+job is written by a JobCodec, JSON unless configured otherwise, so its state has to be in
+exported fields.
+
+A type can be registered in several versions, as its payload changes over time. Jobs are
+always encoded with the version of their own type, and upgraded to the latest version
+when they are decoded.
+
+Example:
+
+	registry := NewJobRegistry().
+	    Register("email", func() Job { return &EmailJob{} })
+
+	data, err := registry.Marshal(&EmailJob{To: "someone@example.com"})
+	job, err := registry.Unmarshal(data)
+*/
+type JobRegistry struct {
+	mu        sync.RWMutex
+	codec     JobCodec
+	codecs    map[string]JobCodec
+	factories map[jobType]func() Job
+	upgrades  map[jobType]func(Job) (Job, error)
+	types     map[reflect.Type]jobType
+}
+
+/*
+jobType is a registered version of a type of job.
+*/
+type jobType struct {
+	name    string
+	version int
+}
+
+/*
+RegistryOption configures a JobRegistry.
+*/
+type RegistryOption func(*JobRegistry)
+
+/*
+WithJobCodec makes the registry encode payloads with the codec. Payloads that were
+written by the built-in codecs can always be decoded.
+
+Example:
+
+	registry := NewJobRegistry(WithJobCodec(GobCodec{}))
+*/
+func WithJobCodec(codec JobCodec) RegistryOption {
+	return func(registry *JobRegistry) {
+		registry.codec = codec
+		registry.codecs[codec.Name()] = codec
+	}
+}
+
+/*
+NewJobRegistry creates a JobRegistry without any types.
+
+Example:
+
+	registry := NewJobRegistry()
+*/
+func NewJobRegistry(options ...RegistryOption) *JobRegistry {
+	registry := &JobRegistry{
+		codec: JSONCodec{},
+		codecs: map[string]JobCodec{
+			JSONCodec{}.Name(): JSONCodec{},
+			GobCodec{}.Name():  GobCodec{},
+		},
+		factories: make(map[jobType]func() Job),
+		upgrades:  make(map[jobType]func(Job) (Job, error)),
+		types:     make(map[reflect.Type]jobType),
+	}
+
+	for _, option := range options {
+		option(registry)
+	}
+
+	return registry
+}
+
+/*
+Register adds a type of job under a name, as its first version. The factory returns an
+empty job to decode into, which should be a pointer, and is also used to recognize jobs
+of the type.
+
+Example:
+
+	registry.Register("email", func() Job { return &EmailJob{} })
+*/
+func (registry *JobRegistry) Register(name string, factory func() Job) *JobRegistry {
+	return registry.RegisterVersion(name, 1, factory)
+}
+
+/*
+RegisterVersion adds a version of a type of job. Each version needs its own Go type, and
+an Upgrade from the version before it, so payloads of older versions can still be read.
+
+Example:
+
+	registry.
+	    Register("email", func() Job { return &EmailJobV1{} }).
+	    RegisterVersion("email", 2, func() Job { return &EmailJob{} }).
+	    Upgrade("email", 1, func(job Job) (Job, error) {
+	        old := job.(*EmailJobV1)
+	        return &EmailJob{To: []string{old.To}}, nil
+	    })
+*/
+func (registry *JobRegistry) RegisterVersion(name string, version int, factory func() Job) *JobRegistry {
+	registry.mu.Lock()
+	defer registry.mu.Unlock()
+
+	key := jobType{name: name, version: version}
+	registry.factories[key] = factory
+	registry.types[reflect.TypeOf(factory())] = key
+	return registry
+}
+
+/*
+Upgrade adds the function that turns a decoded job of a version of a type into a job of
+the next version.
+
+Example:
+
+	registry.Upgrade("email", 1, func(job Job) (Job, error) {
+	    return &EmailJob{To: []string{job.(*EmailJobV1).To}}, nil
+	})
+*/
+func (registry *JobRegistry) Upgrade(name string, from int, upgrade func(Job) (Job, error)) *JobRegistry {
+	registry.mu.Lock()
+	defer registry.mu.Unlock()
+
+	registry.upgrades[jobType{name: name, version: from}] = upgrade
+	return registry
+}
+
+/*
+Name returns the name that the type of the job was registered under.
+
+Example:
+
+	name := registry.Name(&EmailJob{}).UnwrapOr("unknown")
+*/
+func (registry *JobRegistry) Name(job Job) Option[string] {
+	registry.mu.RLock()
+	defer registry.mu.RUnlock()
+
+	key, ok := registry.types[reflect.TypeOf(job)]
+	return FromComma(key.name, ok)
+}
+
+/*
+Marshal encodes the job, together with the name and version of its type. A job that is
+an Envelope keeps its headers and deadline.
+
+Example:
+
+	data, err := registry.Marshal(&EmailJob{To: "someone@example.com"})
+*/
+func (registry *JobRegistry) Marshal(job Job) ([]byte, error) {
+	if envelope, ok := job.(*Envelope); ok {
+		return registry.MarshalEnvelope(envelope)
+	}
+
+	return registry.MarshalEnvelope(&Envelope{Job: job})
+}
+
+/*
+Unmarshal decodes a job that was encoded with Marshal, upgraded to the latest version of
+its type. The envelope it came in is dropped; use UnmarshalEnvelope to keep it.
+
+Example:
+
+	job, err := registry.Unmarshal(data)
+*/
+func (registry *JobRegistry) Unmarshal(data []byte) (Job, error) {
+	envelope, err := registry.UnmarshalEnvelope(data)
+	if err != nil {
+		return nil, err
+	}
+
+	return envelope.Job, nil
+}
+
+/*
+MarshalEnvelope encodes the envelope, with the type and version of its job. The envelope
+itself is left as it is.
+
+Example:
+
+	data, err := registry.MarshalEnvelope(&Envelope{
+	    Job:     &EmailJob{To: "someone@example.com"},
+	    Headers: map[string]string{"tenant": "acme"},
+	})
+*/
+func (registry *JobRegistry) MarshalEnvelope(envelope *Envelope) ([]byte, error) {
+	registry.mu.RLock()
+	key, ok := registry.types[reflect.TypeOf(envelope.Job)]
+	codec := registry.codec
+	registry.mu.RUnlock()
+
+	if !ok {
+		return nil, fmt.Errorf("cannot marshal a job of unregistered type %T", envelope.Job)
+	}
+
+	payload, err := codec.Encode(envelope.Job)
+	if err != nil {
+		return nil, fmt.Errorf("cannot marshal a job of type %q: %w", key.name, err)
+	}
+
+	frame := envelopeFrame{
+		ID:       envelope.ID,
+		Type:     key.name,
+		Version:  key.version,
+		Codec:    codec.Name(),
+		Attempts: envelope.Attempts,
+		Headers:  envelope.Headers,
+		Payload:  payload,
+	}
+
+	if !envelope.Deadline.IsZero() {
+		frame.Deadline = &envelope.Deadline
+	}
+
+	return json.Marshal(frame)
+}
+
+/*
+UnmarshalEnvelope decodes an envelope that was encoded with MarshalEnvelope or Marshal,
+with its job upgraded to the latest version of its type.
+
+Example:
+
+	envelope, err := registry.UnmarshalEnvelope(data)
+	fmt.Println(envelope.Headers["tenant"])
+*/
+func (registry *JobRegistry) UnmarshalEnvelope(data []byte) (*Envelope, error) {
+	var frame envelopeFrame
+	if err := json.Unmarshal(data, &frame); err != nil {
+		return nil, fmt.Errorf("cannot unmarshal a job: %w", err)
+	}
+
+	registry.mu.RLock()
+	codec, hasCodec := registry.codecs[frame.Codec]
+	factory, ok := registry.factories[jobType{name: frame.Type, version: frame.Version}]
+	registry.mu.RUnlock()
+
+	if !hasCodec {
+		return nil, fmt.Errorf("cannot unmarshal a job with unknown codec %q", frame.Codec)
+	}
+
+	if !ok {
+		return nil, fmt.Errorf("cannot unmarshal a job of unregistered type %q version %d", frame.Type, frame.Version)
+	}
+
+	job := factory()
+	if err := codec.Decode(frame.Payload, job); err != nil {
+		return nil, fmt.Errorf("cannot unmarshal a job of type %q: %w", frame.Type, err)
+	}
+
+	envelope := &Envelope{
+		ID:       frame.ID,
+		Type:     frame.Type,
+		Version:  frame.Version,
+		Attempts: frame.Attempts,
+		Headers:  frame.Headers,
+		Job:      job,
+	}
+
+	if frame.Deadline != nil {
+		envelope.Deadline = *frame.Deadline
+	}
+
+	if err := registry.upgrade(envelope); err != nil {
+		return nil, err
+	}
+
+	return envelope, nil
+}
+
+/*
+upgrade replaces the job in the envelope with the latest version of its type.
+*/
+func (registry *JobRegistry) upgrade(envelope *Envelope) error {
+	for {
+		registry.mu.RLock()
+		upgrade, ok := registry.upgrades[jobType{name: envelope.Type, version: envelope.Version}]
+		registry.mu.RUnlock()
+
+		if !ok {
+			return nil
+		}
+
+		job, err := upgrade(envelope.Job)
+		if err != nil {
+			return fmt.Errorf("cannot upgrade a job of type %q from version %d: %w", envelope.Type, envelope.Version, err)
+		}
+
+		envelope.Job = job
+		envelope.Version++
+	}
+}
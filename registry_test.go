@@ -0,0 +1,161 @@
+package twoface
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/smartystreets/goconvey/convey"
+)
+
+// greetingV1 is the first version of greetingJob, which had a single name.
+type greetingV1 struct {
+	Name string
+}
+
+func (job *greetingV1) Do() Result[any, error] {
+	return Ok[any, error]("hello " + job.Name)
+}
+
+type greetingJob struct {
+	First string
+	Last  string
+}
+
+func (job *greetingJob) Do() Result[any, error] {
+	return Ok[any, error]("hello " + job.First + " " + job.Last)
+}
+
+func TestRegistry(t *testing.T) {
+	convey.Convey("JobRegistry", t, func() {
+		registry := NewJobRegistry().Register("recorded", func() Job { return &recordedJob{} })
+
+		convey.Convey("Should turn a job into bytes and back", func() {
+			data, err := registry.Marshal(&recordedJob{Name: "a"})
+			convey.So(err, convey.ShouldBeNil)
+
+			job, err := registry.Unmarshal(data)
+			convey.So(err, convey.ShouldBeNil)
+			convey.So(job, convey.ShouldResemble, &recordedJob{Name: "a"})
+			convey.So(registry.Name(job).UnwrapOrZero(), convey.ShouldEqual, "recorded")
+		})
+
+		convey.Convey("Should encode with the codec it was given, and decode any built-in one", func() {
+			gob := NewJobRegistry(WithJobCodec(GobCodec{})).Register("recorded", func() Job { return &recordedJob{} })
+
+			data, err := gob.Marshal(&recordedJob{Name: "gob"})
+			convey.So(err, convey.ShouldBeNil)
+			envelope, _ := registry.UnmarshalEnvelope(data)
+			convey.So(envelope.Job, convey.ShouldResemble, &recordedJob{Name: "gob"})
+
+			data, _ = registry.Marshal(&recordedJob{Name: "json"})
+			job, err := gob.Unmarshal(data)
+			convey.So(err, convey.ShouldBeNil)
+			convey.So(job, convey.ShouldResemble, &recordedJob{Name: "json"})
+		})
+
+		convey.Convey("Should keep the headers and deadline of an Envelope", func() {
+			deadline := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+			original := &Envelope{
+				ID:       7,
+				Attempts: 2,
+				Headers:  map[string]string{"tenant": "acme"},
+				Deadline: deadline,
+				Job:      &recordedJob{Name: "a"},
+			}
+			data, err := registry.MarshalEnvelope(original)
+			convey.So(err, convey.ShouldBeNil)
+			convey.So(original.Type, convey.ShouldBeEmpty)
+
+			envelope, err := registry.UnmarshalEnvelope(data)
+			convey.So(err, convey.ShouldBeNil)
+			convey.So(envelope.ID, convey.ShouldEqual, 7)
+			convey.So(envelope.Type, convey.ShouldEqual, "recorded")
+			convey.So(envelope.Version, convey.ShouldEqual, 1)
+			convey.So(envelope.Attempts, convey.ShouldEqual, 2)
+			convey.So(envelope.Headers, convey.ShouldResemble, map[string]string{"tenant": "acme"})
+			convey.So(envelope.Deadline.Equal(deadline), convey.ShouldBeTrue)
+		})
+
+		convey.Convey("Should upgrade older versions of a job", func() {
+			old := NewJobRegistry().Register("greeting", func() Job { return &greetingV1{} })
+			data, _ := old.Marshal(&greetingV1{Name: "Ada Lovelace"})
+
+			registry.
+				Register("greeting", func() Job { return &greetingV1{} }).
+				RegisterVersion("greeting", 2, func() Job { return &greetingJob{} }).
+				Upgrade("greeting", 1, func(job Job) (Job, error) {
+					var greeting greetingJob
+					_, err := fmt.Sscan(job.(*greetingV1).Name, &greeting.First, &greeting.Last)
+					return &greeting, err
+				})
+
+			envelope, err := registry.UnmarshalEnvelope(data)
+			convey.So(err, convey.ShouldBeNil)
+			convey.So(envelope.Version, convey.ShouldEqual, 2)
+			convey.So(envelope.Job, convey.ShouldResemble, &greetingJob{First: "Ada", Last: "Lovelace"})
+
+			data, _ = registry.Marshal(&greetingJob{First: "Alan", Last: "Turing"})
+			envelope, _ = registry.UnmarshalEnvelope(data)
+			convey.So(envelope.Version, convey.ShouldEqual, 2)
+
+			registry.Upgrade("greeting", 1, func(job Job) (Job, error) { return nil, errDummy })
+			data, _ = old.Marshal(&greetingV1{Name: "Grace Hopper"})
+			_, err = registry.Unmarshal(data)
+			convey.So(errors.Is(err, errDummy), convey.ShouldBeTrue)
+		})
+
+		convey.Convey("Should refuse types it does not know", func() {
+			_, err := registry.Marshal(DummyJob{})
+			convey.So(err, convey.ShouldNotBeNil)
+
+			data, _ := NewJobRegistry().Register("other", func() Job { return &recordedJob{} }).Marshal(&recordedJob{})
+			_, err = registry.Unmarshal(data)
+			convey.So(err, convey.ShouldNotBeNil)
+		})
+
+		convey.Convey("An Envelope should not run its job after its deadline", func() {
+			resetRecorder()
+			envelope := &Envelope{ID: 3, Deadline: time.Now().Add(-time.Second), Job: &recordedJob{Name: "late"}}
+
+			err := envelope.Do().UnwrapErr()
+			convey.So(errors.Is(err, context.DeadlineExceeded), convey.ShouldBeTrue)
+			convey.So(recordedNames(), convey.ShouldBeEmpty)
+
+			envelope.Deadline = time.Time{}
+			convey.So(envelope.Do().Unwrap(), convey.ShouldEqual, "late")
+		})
+
+		convey.Convey("A FileQueue should deliver an Envelope as one", func() {
+			queue, err := OpenFileQueue(FileQueueConfig{Dir: t.TempDir(), Registry: registry})
+			convey.So(err, convey.ShouldBeNil)
+			defer queue.Close()
+
+			queue.Enqueue(&Envelope{Headers: map[string]string{"trace": "abc"}, Job: &recordedJob{Name: "sealed"}})
+			queue.Enqueue(&recordedJob{Name: "bare"})
+
+			delivery, _ := queue.Dequeue(context.Background())
+			queue.Nack(delivery.ID)
+			delivery, _ = queue.Dequeue(context.Background())
+			convey.So(delivery.Job, convey.ShouldHaveSameTypeAs, &recordedJob{})
+
+			delivery, _ = queue.Dequeue(context.Background())
+			envelope := delivery.Job.(*Envelope)
+			convey.So(envelope.ID, convey.ShouldEqual, delivery.ID)
+			convey.So(envelope.Attempts, convey.ShouldEqual, 1)
+			convey.So(envelope.Headers["trace"], convey.ShouldEqual, "abc")
+			convey.So(envelope.Job, convey.ShouldResemble, &recordedJob{Name: "sealed"})
+		})
+	})
+}
+
+func BenchmarkRegistry(b *testing.B) {
+	registry := NewJobRegistry().Register("recorded", func() Job { return &recordedJob{} })
+
+	for i := 0; i < b.N; i++ {
+		data, _ := registry.Marshal(&recordedJob{Name: "benchmark"})
+		registry.Unmarshal(data)
+	}
+}
@@ -1,6 +1,11 @@
 package twoface
 
-import "fmt"
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+)
 
 /*
 Result represents a value that can be either Ok or Err.
@@ -138,3 +143,96 @@ func (r Result[T, E]) AndThen(f func(T) Result[T, E]) Result[T, E] {
 	}
 	return r
 }
+
+/*
+MarshalJSON encodes the Result as a tagged object, either {"ok":...} or
+{"err":...}. The Err value is encoded by the current ErrorCodec.
+
+Example:
+
+	data, _ := json.Marshal(Err[int, error](fmt.Errorf("an error")))
+	fmt.Println(string(data)) // {"err":{"message":"an error"}}
+*/
+func (r Result[T, E]) MarshalJSON() ([]byte, error) {
+	switch {
+	case r.IsOk():
+		return json.Marshal(map[string]T{"ok": *r.ok})
+	case r.IsErr():
+		if isNil(*r.err) {
+			return nil, errors.New("cannot marshal an `Err` holding a nil error")
+		}
+
+		data, err := CurrentErrorCodec().EncodeError(*r.err)
+		if err != nil {
+			return nil, err
+		}
+
+		return json.Marshal(map[string]json.RawMessage{"err": data})
+	}
+
+	return nil, errors.New("cannot marshal an empty `Result`")
+}
+
+/*
+isNil reports whether the value is nil, also when it is a nil pointer, or another nil
+value behind an interface, like a typed nil error.
+*/
+func isNil(value any) bool {
+	if value == nil {
+		return true
+	}
+
+	switch reflected := reflect.ValueOf(value); reflected.Kind() {
+	case reflect.Pointer, reflect.Map, reflect.Slice, reflect.Func, reflect.Chan, reflect.Interface:
+		return reflected.IsNil()
+	}
+
+	return false
+}
+
+/*
+UnmarshalJSON decodes a Result from a tagged object, as produced by MarshalJSON.
+The Err value is rebuilt by the current ErrorCodec, and must be assignable to E.
+
+Example:
+
+	var result Result[int, error]
+	_ = json.Unmarshal([]byte(`{"ok":42}`), &result)
+	fmt.Println(result.Unwrap()) // 42
+*/
+func (r *Result[T, E]) UnmarshalJSON(data []byte) error {
+	var tagged map[string]json.RawMessage
+
+	if err := json.Unmarshal(data, &tagged); err != nil {
+		return err
+	}
+
+	ok, isOk := tagged["ok"]
+	errData, isErr := tagged["err"]
+
+	if len(tagged) != 1 || isOk == isErr {
+		return fmt.Errorf("cannot unmarshal %s into a `Result`: expected exactly one of \"ok\" or \"err\"", data)
+	}
+
+	if isOk {
+		var value T
+		if err := json.Unmarshal(ok, &value); err != nil {
+			return err
+		}
+		*r = Ok[T, E](value)
+		return nil
+	}
+
+	decoded, err := CurrentErrorCodec().DecodeError(errData)
+	if err != nil {
+		return err
+	}
+
+	typed, assignable := decoded.(E)
+	if !assignable {
+		return fmt.Errorf("cannot unmarshal error of type %T into a `Result` with error type %v", decoded, reflect.TypeOf((*E)(nil)).Elem())
+	}
+
+	*r = Err[T](typed)
+	return nil
+}
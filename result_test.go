@@ -1,6 +1,8 @@
 package twoface
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
 	"testing"
 
@@ -52,9 +54,75 @@ func TestResult(t *testing.T) {
 			newR := r.AndThen(func(v int) Result[int, error] { return Ok[int, error](v * 2) })
 			convey.So(newR.Unwrap(), convey.ShouldEqual, 84)
 		})
+
+		convey.Convey("Should round-trip an Ok result through JSON", func() {
+			data, err := json.Marshal(Ok[int, error](42))
+			convey.So(err, convey.ShouldBeNil)
+			convey.So(string(data), convey.ShouldEqual, `{"ok":42}`)
+
+			var r Result[int, error]
+			convey.So(json.Unmarshal(data, &r), convey.ShouldBeNil)
+			convey.So(r.Unwrap(), convey.ShouldEqual, 42)
+		})
+
+		convey.Convey("Should round-trip an Err result through JSON", func() {
+			data, err := json.Marshal(Err[int, error](fmt.Errorf("an error")))
+			convey.So(err, convey.ShouldBeNil)
+			convey.So(string(data), convey.ShouldEqual, `{"err":{"message":"an error"}}`)
+
+			var r Result[int, error]
+			convey.So(json.Unmarshal(data, &r), convey.ShouldBeNil)
+			convey.So(r.UnwrapErr().Error(), convey.ShouldEqual, "an error")
+		})
+
+		convey.Convey("Should rebuild typed errors with a TypedErrorCodec", func() {
+			SetErrorCodec(NewTypedErrorCodec().Register("status", func() error { return &statusError{} }))
+			defer SetErrorCodec(MessageErrorCodec{})
+
+			data, err := json.Marshal(Err[int, error](&statusError{Code: 404}))
+			convey.So(err, convey.ShouldBeNil)
+
+			var r Result[int, error]
+			convey.So(json.Unmarshal(data, &r), convey.ShouldBeNil)
+
+			var status *statusError
+			convey.So(errors.As(r.UnwrapErr(), &status), convey.ShouldBeTrue)
+			convey.So(status.Code, convey.ShouldEqual, 404)
+
+			var typed Result[int, *statusError]
+			convey.So(json.Unmarshal(data, &typed), convey.ShouldBeNil)
+			convey.So(typed.UnwrapErr().Code, convey.ShouldEqual, 404)
+		})
+
+		convey.Convey("Should refuse to marshal an Err holding a nil error, also a typed one", func() {
+			var missing *statusError
+
+			_, err := json.Marshal(Err[int, *statusError](missing))
+			convey.So(err, convey.ShouldNotBeNil)
+
+			_, err = json.Marshal(Err[int, error](missing))
+			convey.So(err, convey.ShouldNotBeNil)
+
+			_, err = json.Marshal(Err[int, error](nil))
+			convey.So(err, convey.ShouldNotBeNil)
+		})
+
+		convey.Convey("Should reject errors that do not fit the error type", func() {
+			var r Result[int, *statusError]
+			convey.So(json.Unmarshal([]byte(`{"err":{"message":"an error"}}`), &r), convey.ShouldNotBeNil)
+			convey.So(json.Unmarshal([]byte(`{"ok":1,"err":{"message":"an error"}}`), &r), convey.ShouldNotBeNil)
+		})
 	})
 }
 
+type statusError struct {
+	Code int `json:"code"`
+}
+
+func (err *statusError) Error() string {
+	return fmt.Sprintf("status %d", err.Code)
+}
+
 func BenchmarkResult(b *testing.B) {
 	for i := 0; i < b.N; i++ {
 		r := Ok[int, error](42)
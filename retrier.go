@@ -1,6 +1,7 @@
 package twoface
 
 import (
+	"errors"
 	"fmt"
 	"math"
 	"time"
@@ -16,32 +17,83 @@ func NewRetrier(retrierType Retrier) Retrier {
 	return retrierType
 }
 
+// ErrMaxRetries is wrapped by the error of a job that failed every attempt of a Retrier.
+var ErrMaxRetries = errors.New("maximum retries reached")
+
+// Attempt is a single failed run of a job.
+type Attempt struct {
+	// Worker is the ID of the worker that ran the job, when it ran on a Pool.
+	Worker   int
+	Started  time.Time
+	Finished time.Time
+	Err      error
+}
+
+// RetryError is the error of a job that failed every attempt of a Retrier, with the errors of all of them.
+type RetryError struct {
+	Attempts []Attempt
+}
+
+// Error returns the error of the last attempt.
+func (err *RetryError) Error() string {
+	return fmt.Sprintf("%v after %d attempts: %v", ErrMaxRetries, len(err.Attempts), err.last())
+}
+
+// Unwrap returns ErrMaxRetries, and the error of the last attempt.
+func (err *RetryError) Unwrap() []error {
+	return []error{ErrMaxRetries, err.last()}
+}
+
+func (err *RetryError) last() error {
+	if len(err.Attempts) == 0 {
+		return nil
+	}
+	return err.Attempts[len(err.Attempts)-1].Err
+}
+
 // Fibonacci is a RetryStrategy that retries a function n times with a Fibonacci interval in seconds between retries.
 type Fibonacci struct {
-	max int
-	n   int
+	max  int
+	unit time.Duration
 }
 
 // NewFibonacci creates a new Fibonacci retrier.
 func NewFibonacci(max int) Retrier {
 	return NewRetrier(Fibonacci{
-		max: max,
-		n:   0,
+		max:  max,
+		unit: time.Second,
+	})
+}
+
+// NewFibonacciWithUnit creates a Fibonacci retrier whose intervals are counted in the unit, instead of in seconds.
+func NewFibonacciWithUnit(max int, unit time.Duration) Retrier {
+	return NewRetrier(Fibonacci{
+		max:  max,
+		unit: unit,
 	})
 }
 
 // Do retries the job with a Fibonacci backoff strategy.
 func (strategy Fibonacci) Do(fn Job) Result[any, error] {
-	if strategy.n > strategy.max {
-		return Err[any, error](fmt.Errorf("maximum retries reached"))
-	}
+	var attempts []Attempt
+
+	for n := 0; ; n++ {
+		started := time.Now()
+		result := fn.Do()
+		if result.IsOk() {
+			return result
+		}
 
-	result := fn.Do()
-	if result.IsOk() {
-		return result
+		attempts = append(attempts, Attempt{Started: started, Finished: time.Now(), Err: result.UnwrapErr()})
+		if n >= strategy.max {
+			return Err[any, error](&RetryError{Attempts: attempts})
+		}
+
+		time.Sleep(time.Duration(fibonacci(n+1)) * strategy.unit)
 	}
+}
 
-	strategy.n = int(math.Round((math.Pow(math.Phi, float64(strategy.n)) + math.Pow(math.Phi-1, float64(strategy.n))) / math.Sqrt(5)))
-	time.Sleep(time.Duration(strategy.n) * time.Second)
-	return strategy.Do(fn)
+// fibonacci returns the nth Fibonacci number.
+func fibonacci(n int) int {
+	return int(math.Round((math.Pow(math.Phi, float64(n)) - math.Pow(1-math.Phi, float64(n))) / math.Sqrt(5)))
 }
@@ -0,0 +1,260 @@
+package twoface
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+/*
+StepStatus is the outcome of a single step of a Saga.
+*/
+type StepStatus int
+
+const (
+	// StepPending is a step that did not run, because an earlier step failed.
+	StepPending StepStatus = iota
+	// StepCommitted is a step whose action succeeded, and that was not undone.
+	StepCommitted
+	// StepFailed is the step whose action failed, which stopped the Saga.
+	StepFailed
+	// StepCompensated is a step whose action was undone by its compensation.
+	StepCompensated
+	// StepCompensationFailed is a step whose compensation failed, so its action could
+	// not be undone.
+	StepCompensationFailed
+)
+
+func (status StepStatus) String() string {
+	switch status {
+	case StepCommitted:
+		return "committed"
+	case StepFailed:
+		return "failed"
+	case StepCompensated:
+		return "compensated"
+	case StepCompensationFailed:
+		return "compensation failed"
+	default:
+		return "pending"
+	}
+}
+
+/*
+Saga runs a sequence of steps that together form one operation, where each step has an
+action, and a compensation that undoes it. When the action of a step fails, the
+compensations of the steps before it run in reverse order, so the operation is either
+completed or rolled back as a whole. Compensations still run when the context of the Saga
+is cancelled.
+
+Example:
+
+	outcome := NewSaga().
+	    WithExecutor(pool).
+	    WithRetrier(NewFibonacci(3)).
+	    Step("reserve", ReserveStock{Order: id}, ReleaseStock{Order: id}).
+	    Step("charge", ChargeCard{Order: id}, RefundCard{Order: id}).
+	    Step("ship", ShipOrder{Order: id}, nil).
+	    Run(ctx)
+
+	if err := outcome.Err(); err != nil {
+	    log.Println(err)
+	}
+*/
+type Saga struct {
+	steps    []sagaStep
+	executor Executor
+	retrier  Retrier
+}
+
+type sagaStep struct {
+	name         string
+	action       Job
+	compensation Job
+}
+
+/*
+NewSaga creates a Saga without any steps, that runs its jobs on their own goroutine, and
+its compensations only once.
+
+Example:
+
+	saga := NewSaga()
+*/
+func NewSaga() *Saga {
+	return &Saga{executor: GoExecutor}
+}
+
+/*
+WithExecutor sets the Executor that the actions and compensations run on.
+
+Example:
+
+	saga := NewSaga().WithExecutor(pool)
+*/
+func (saga *Saga) WithExecutor(executor Executor) *Saga {
+	saga.executor = executor
+	return saga
+}
+
+/*
+WithRetrier sets the Retrier that compensations run with, so a rollback is not given up
+on after a single failure.
+
+Example:
+
+	saga := NewSaga().WithRetrier(NewFibonacci(3))
+*/
+func (saga *Saga) WithRetrier(retrier Retrier) *Saga {
+	saga.retrier = retrier
+	return saga
+}
+
+/*
+Step adds a step to the end of the Saga. The compensation can be nil, for a step that
+does not need to be undone, like the last one.
+
+Example:
+
+	saga.Step("charge", ChargeCard{Order: id}, RefundCard{Order: id})
+*/
+func (saga *Saga) Step(name string, action Job, compensation Job) *Saga {
+	saga.steps = append(saga.steps, sagaStep{name: name, action: action, compensation: compensation})
+	return saga
+}
+
+/*
+StepOutcome is what happened to a single step of a Saga.
+*/
+type StepOutcome struct {
+	Name   string
+	Status StepStatus
+	// Action is the Result of the action, when it ran.
+	Action Result[any, error]
+	// Compensation is the Result of the compensation, when it ran.
+	Compensation Result[any, error]
+	Started      time.Time
+	Finished     time.Time
+}
+
+/*
+SagaOutcome records what happened to every step of a Saga, in order.
+*/
+type SagaOutcome struct {
+	Steps []StepOutcome
+}
+
+/*
+Committed reports whether every step of the Saga succeeded.
+
+Example:
+
+	if outcome.Committed() {
+	    notifyCustomer()
+	}
+*/
+func (outcome SagaOutcome) Committed() bool {
+	for _, step := range outcome.Steps {
+		if step.Status != StepCommitted {
+			return false
+		}
+	}
+	return true
+}
+
+/*
+Err returns nil when the Saga committed. Otherwise it returns the error of the step that
+failed, joined with the errors of the compensations that failed.
+
+Example:
+
+	if err := outcome.Err(); err != nil {
+	    log.Println(err)
+	}
+*/
+func (outcome SagaOutcome) Err() error {
+	var errs []error
+
+	for _, step := range outcome.Steps {
+		switch step.Status {
+		case StepFailed:
+			errs = append(errs, fmt.Errorf("step %q: %w", step.Name, step.Action.UnwrapErr()))
+		case StepCompensationFailed:
+			errs = append(errs, fmt.Errorf("compensating step %q: %w", step.Name, step.Compensation.UnwrapErr()))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+/*
+Run runs the actions of the steps in order, until one fails, and then runs the
+compensations of the steps that committed before it, in reverse order.
+
+Example:
+
+	outcome := saga.Run(ctx)
+*/
+func (saga *Saga) Run(ctx context.Context) SagaOutcome {
+	outcome := SagaOutcome{Steps: make([]StepOutcome, len(saga.steps))}
+
+	for idx, step := range saga.steps {
+		outcome.Steps[idx].Name = step.name
+	}
+
+	for idx, step := range saga.steps {
+		current := &outcome.Steps[idx]
+		current.Started = time.Now()
+		current.Action = saga.run(ctx, step.action.Do)
+		current.Finished = time.Now()
+
+		if current.Action.IsOk() {
+			current.Status = StepCommitted
+			continue
+		}
+
+		current.Status = StepFailed
+		saga.compensate(context.WithoutCancel(ctx), outcome.Steps[:idx])
+		break
+	}
+
+	return outcome
+}
+
+/*
+compensate undoes the steps that committed, from the last one to the first.
+*/
+func (saga *Saga) compensate(ctx context.Context, committed []StepOutcome) {
+	for idx := len(committed) - 1; idx >= 0; idx-- {
+		compensation := saga.steps[idx].compensation
+		if compensation == nil {
+			continue
+		}
+
+		do := compensation.Do
+		if saga.retrier != nil {
+			do = func() Result[any, error] { return saga.retrier.Do(compensation) }
+		}
+
+		committed[idx].Compensation = saga.run(ctx, do)
+
+		if committed[idx].Compensation.IsOk() {
+			committed[idx].Status = StepCompensated
+		} else {
+			committed[idx].Status = StepCompensationFailed
+		}
+	}
+}
+
+/*
+run runs a job on the executor of the Saga, and waits for its Result.
+*/
+func (saga *Saga) run(ctx context.Context, do func() Result[any, error]) Result[any, error] {
+	future := AsyncOn(ctx, saga.executor, func(ctx context.Context) (any, error) {
+		return unpack(do())
+	})
+
+	<-future.Done()
+	return future.settled()
+}
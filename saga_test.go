@@ -0,0 +1,148 @@
+package twoface
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/smartystreets/goconvey/convey"
+)
+
+// sagaLog records the actions and compensations of a Saga, in the order they ran.
+type sagaLog struct {
+	mu      sync.Mutex
+	entries []string
+}
+
+func (log *sagaLog) job(entry string, err error) Job {
+	return JobFunc(func() Result[any, error] {
+		log.mu.Lock()
+		log.entries = append(log.entries, entry)
+		log.mu.Unlock()
+
+		if err != nil {
+			return Err[any](err)
+		}
+		return Ok[any, error](entry)
+	})
+}
+
+func (log *sagaLog) list() []string {
+	log.mu.Lock()
+	defer log.mu.Unlock()
+	return append([]string{}, log.entries...)
+}
+
+func TestSaga(t *testing.T) {
+	convey.Convey("Saga", t, func() {
+		ctx := context.Background()
+		pool := NewPool(ctx, 2)
+		log := &sagaLog{}
+
+		statuses := func(outcome SagaOutcome) []StepStatus {
+			var statuses []StepStatus
+			for _, step := range outcome.Steps {
+				statuses = append(statuses, step.Status)
+			}
+			return statuses
+		}
+
+		convey.Convey("Should commit every step when all actions succeed", func() {
+			outcome := NewSaga().
+				WithExecutor(pool).
+				Step("a", log.job("do a", nil), log.job("undo a", nil)).
+				Step("b", log.job("do b", nil), log.job("undo b", nil)).
+				Run(ctx)
+
+			convey.So(outcome.Committed(), convey.ShouldBeTrue)
+			convey.So(outcome.Err(), convey.ShouldBeNil)
+			convey.So(log.list(), convey.ShouldResemble, []string{"do a", "do b"})
+			convey.So(outcome.Steps[1].Action.Unwrap(), convey.ShouldEqual, "do b")
+		})
+
+		convey.Convey("Should compensate the committed steps in reverse when a step fails", func() {
+			outcome := NewSaga().
+				WithExecutor(pool).
+				Step("a", log.job("do a", nil), log.job("undo a", nil)).
+				Step("b", log.job("do b", nil), nil).
+				Step("c", log.job("do c", nil), log.job("undo c", nil)).
+				Step("d", log.job("do d", errDummy), log.job("undo d", nil)).
+				Step("e", log.job("do e", nil), log.job("undo e", nil)).
+				Run(ctx)
+
+			convey.So(log.list(), convey.ShouldResemble, []string{"do a", "do b", "do c", "do d", "undo c", "undo a"})
+			convey.So(statuses(outcome), convey.ShouldResemble, []StepStatus{
+				StepCompensated, StepCommitted, StepCompensated, StepFailed, StepPending,
+			})
+			convey.So(outcome.Committed(), convey.ShouldBeFalse)
+			convey.So(errors.Is(outcome.Err(), errDummy), convey.ShouldBeTrue)
+			convey.So(outcome.Steps[0].Compensation.Unwrap(), convey.ShouldEqual, "undo a")
+		})
+
+		convey.Convey("Should retry compensations with its Retrier", func() {
+			attempts := 0
+			flaky := JobFunc(func() Result[any, error] {
+				attempts++
+				if attempts < 3 {
+					return Err[any](errDummy)
+				}
+				return Ok[any, error]("undone")
+			})
+
+			outcome := NewSaga().
+				WithRetrier(NewFibonacciWithUnit(5, time.Millisecond)).
+				Step("a", log.job("do a", nil), flaky).
+				Step("b", log.job("do b", errDummy), nil).
+				Run(ctx)
+
+			convey.So(attempts, convey.ShouldEqual, 3)
+			convey.So(statuses(outcome), convey.ShouldResemble, []StepStatus{StepCompensated, StepFailed})
+		})
+
+		convey.Convey("Should report a compensation that keeps failing", func() {
+			outcome := NewSaga().
+				Step("a", log.job("do a", nil), log.job("undo a", errors.New("stuck"))).
+				Step("b", log.job("do b", errDummy), nil).
+				Run(ctx)
+
+			convey.So(statuses(outcome), convey.ShouldResemble, []StepStatus{StepCompensationFailed, StepFailed})
+			convey.So(outcome.Err().Error(), convey.ShouldContainSubstring, "stuck")
+			convey.So(errors.Is(outcome.Err(), errDummy), convey.ShouldBeTrue)
+		})
+
+		convey.Convey("Should still compensate when its context is cancelled", func() {
+			cancelled, cancel := context.WithCancel(ctx)
+
+			outcome := NewSaga().
+				WithExecutor(pool).
+				Step("a", log.job("do a", nil), log.job("undo a", nil)).
+				Step("cancel", JobFunc(func() Result[any, error] {
+					cancel()
+					return Err[any](context.Canceled)
+				}), nil).
+				Step("b", log.job("do b", nil), nil).
+				Run(cancelled)
+
+			convey.So(statuses(outcome), convey.ShouldResemble, []StepStatus{StepCompensated, StepFailed, StepPending})
+			convey.So(log.list(), convey.ShouldResemble, []string{"do a", "undo a"})
+		})
+
+		convey.Reset(func() {
+			pool.Shutdown()
+		})
+	})
+}
+
+func BenchmarkSaga(b *testing.B) {
+	ctx := context.Background()
+	log := &sagaLog{}
+
+	for i := 0; i < b.N; i++ {
+		NewSaga().
+			Step("a", log.job("do a", nil), log.job("undo a", nil)).
+			Step("b", log.job("do b", errDummy), nil).
+			Run(ctx)
+	}
+}
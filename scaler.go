@@ -107,8 +107,8 @@ func (scaler *Scaler) load() {
 func (scaler *Scaler) Grow() {
 	if !scaler.overload {
 		for i := 0; i < scaler.rate*scaler.level; i++ {
-			scaler.pool.workers = append(scaler.pool.workers, NewWorker(
-				len(scaler.pool.workers), scaler.pool.workerPool, scaler.pool.ctx,
+			scaler.pool.workers = append(scaler.pool.workers, scaler.pool.newWorker(
+				len(scaler.pool.workers),
 			).Start())
 		}
 	}
@@ -0,0 +1,357 @@
+package twoface
+
+import (
+	"container/heap"
+	"context"
+	"sort"
+	"sync"
+	"time"
+)
+
+/*
+ScheduledJob is a handle to a job that was submitted to a Pool for a later time.
+
+Example:
+
+	scheduled := pool.SubmitAfter(time.Hour, reminder)
+	fmt.Println(scheduled.At())
+	scheduled.Cancel()
+*/
+type ScheduledJob struct {
+	id        uint64
+	at        time.Time
+	job       Job
+	scheduler *scheduler
+	index     int
+}
+
+/*
+ID returns the identifier of the scheduled job, which is unique within its Pool.
+*/
+func (scheduled *ScheduledJob) ID() uint64 {
+	return scheduled.id
+}
+
+/*
+At returns the time at which the job is submitted to the Pool.
+*/
+func (scheduled *ScheduledJob) At() time.Time {
+	return scheduled.at
+}
+
+/*
+Job returns the job that was scheduled.
+*/
+func (scheduled *ScheduledJob) Job() Job {
+	return scheduled.job
+}
+
+/*
+Cancel removes the job from the schedule. It returns false when the job was already
+submitted, cancelled, or dropped.
+
+Example:
+
+	if scheduled.Cancel() {
+	    fmt.Println("reminder cancelled")
+	}
+*/
+func (scheduled *ScheduledJob) Cancel() bool {
+	if !scheduled.scheduler.remove(scheduled) {
+		return false
+	}
+
+	discardJob(scheduled.job, context.Canceled)
+	return true
+}
+
+func (scheduled *ScheduledJob) before(other *ScheduledJob) bool {
+	if scheduled.at.Equal(other.at) {
+		return scheduled.id < other.id
+	}
+
+	return scheduled.at.Before(other.at)
+}
+
+/*
+WithDroppedSchedules sets a function that is called for every scheduled job that is
+dropped, because the pool was shut down before it was due.
+*/
+func WithDroppedSchedules(fn func(*ScheduledJob)) PoolOption {
+	return func(pool *Pool) {
+		pool.schedule.dropped = fn
+	}
+}
+
+/*
+SubmitAt submits the job to the pool at the given time, or right away when that time
+has already passed. All scheduled jobs of a pool share a single timer.
+
+Example:
+
+	scheduled := pool.SubmitAt(expiresAt, expire)
+*/
+func (pool *Pool) SubmitAt(at time.Time, job Job) *ScheduledJob {
+	return pool.schedule.add(at, job)
+}
+
+/*
+SubmitAfter submits the job to the pool once the duration has passed.
+
+Example:
+
+	scheduled := pool.SubmitAfter(5*time.Minute, reminder)
+*/
+func (pool *Pool) SubmitAfter(delay time.Duration, job Job) *ScheduledJob {
+	return pool.SubmitAt(time.Now().Add(delay), job)
+}
+
+/*
+Scheduled returns the jobs that are waiting to be submitted, the earliest first.
+
+Example:
+
+	for _, scheduled := range pool.Scheduled() {
+	    fmt.Println(scheduled.ID(), scheduled.At())
+	}
+*/
+func (pool *Pool) Scheduled() []*ScheduledJob {
+	return pool.schedule.list()
+}
+
+/*
+scheduler keeps the scheduled jobs of a pool in a heap ordered by time, and submits them
+from a single goroutine once they are due.
+*/
+type scheduler struct {
+	pool    *Pool
+	mu      sync.Mutex
+	queue   scheduleQueue
+	nextID  uint64
+	closed  bool
+	wake    chan struct{}
+	stop    chan struct{}
+	stopped chan struct{}
+	once    sync.Once
+	dropped func(*ScheduledJob)
+}
+
+func newScheduler(pool *Pool) *scheduler {
+	return &scheduler{
+		pool:    pool,
+		wake:    make(chan struct{}, 1),
+		stop:    make(chan struct{}),
+		stopped: make(chan struct{}),
+	}
+}
+
+func (scheduler *scheduler) add(at time.Time, job Job) *ScheduledJob {
+	scheduler.mu.Lock()
+
+	scheduler.nextID++
+	scheduled := &ScheduledJob{id: scheduler.nextID, at: at, job: job, scheduler: scheduler, index: -1}
+
+	if scheduler.closed {
+		scheduler.mu.Unlock()
+		scheduler.drop(scheduled)
+		return scheduled
+	}
+
+	heap.Push(&scheduler.queue, scheduled)
+	earliest := scheduled.index == 0
+	scheduler.mu.Unlock()
+
+	if earliest {
+		select {
+		case scheduler.wake <- struct{}{}:
+		default:
+		}
+	}
+
+	return scheduled
+}
+
+func (scheduler *scheduler) remove(scheduled *ScheduledJob) bool {
+	scheduler.mu.Lock()
+	defer scheduler.mu.Unlock()
+
+	if scheduled.index < 0 {
+		return false
+	}
+
+	heap.Remove(&scheduler.queue, scheduled.index)
+	return true
+}
+
+func (scheduler *scheduler) list() []*ScheduledJob {
+	scheduler.mu.Lock()
+	defer scheduler.mu.Unlock()
+
+	scheduled := make([]*ScheduledJob, len(scheduler.queue))
+	copy(scheduled, scheduler.queue)
+	sort.Slice(scheduled, func(i, j int) bool { return scheduled[i].before(scheduled[j]) })
+
+	return scheduled
+}
+
+/*
+run submits the jobs that are due, and then sleeps until the next one is, or until an
+earlier job is added. It reuses a single timer for all of its waits.
+*/
+func (scheduler *scheduler) run() {
+	defer close(scheduler.stopped)
+	defer scheduler.drain()
+
+	var timer *time.Timer
+
+	defer func() {
+		if timer != nil {
+			timer.Stop()
+		}
+	}()
+
+	for {
+		due, wait := scheduler.due(time.Now())
+
+		for _, scheduled := range due {
+			scheduler.pool.Submit(scheduled.job)
+		}
+
+		var timeout <-chan time.Time
+
+		if wait >= 0 {
+			if timer == nil {
+				timer = time.NewTimer(wait)
+			} else {
+				timer.Reset(wait)
+			}
+			timeout = timer.C
+		}
+
+		select {
+		case <-timeout:
+			continue
+		case <-scheduler.wake:
+		case <-scheduler.stop:
+			return
+		case <-scheduler.pool.ctx.Done():
+			return
+		}
+
+		// The timer may have fired in the meantime, which would wake the next wait early.
+		if timer != nil && !timer.Stop() {
+			select {
+			case <-timer.C:
+			default:
+			}
+		}
+	}
+}
+
+/*
+due takes the jobs that are due from the heap, and returns how long it is until the
+next one is, or a negative duration when there is none.
+*/
+func (scheduler *scheduler) due(now time.Time) ([]*ScheduledJob, time.Duration) {
+	scheduler.mu.Lock()
+	defer scheduler.mu.Unlock()
+
+	var due []*ScheduledJob
+
+	for len(scheduler.queue) > 0 {
+		next := scheduler.queue[0]
+		if next.at.After(now) {
+			return due, next.at.Sub(now)
+		}
+
+		due = append(due, heap.Pop(&scheduler.queue).(*ScheduledJob))
+	}
+
+	return due, -1
+}
+
+/*
+close stops the scheduler, dropping the jobs that are not yet due, and waits until it
+no longer submits anything to the pool.
+*/
+func (scheduler *scheduler) close() {
+	scheduler.once.Do(func() {
+		close(scheduler.stop)
+	})
+
+	<-scheduler.stopped
+}
+
+func (scheduler *scheduler) drain() {
+	scheduler.mu.Lock()
+	scheduler.closed = true
+	remaining := scheduler.queue
+	scheduler.queue = nil
+	for _, scheduled := range remaining {
+		scheduled.index = -1
+	}
+	scheduler.mu.Unlock()
+
+	sort.Slice(remaining, func(i, j int) bool { return remaining[i].before(remaining[j]) })
+
+	for _, scheduled := range remaining {
+		scheduler.drop(scheduled)
+	}
+}
+
+func (scheduler *scheduler) drop(scheduled *ScheduledJob) {
+	err := scheduler.pool.ctx.Err()
+	if err == nil {
+		err = context.Canceled
+	}
+
+	discardJob(scheduled.job, err)
+
+	if scheduler.dropped != nil {
+		scheduler.dropped(scheduled)
+	}
+}
+
+/*
+discardJob lets a job that will never run know about it, if it wants to.
+*/
+func discardJob(job Job, err error) {
+	if discarded, ok := job.(discardable); ok {
+		discarded.discard(err)
+	}
+}
+
+/*
+scheduleQueue implements heap.Interface, ordering scheduled jobs by time, and by the
+order they were scheduled in when their times are equal.
+*/
+type scheduleQueue []*ScheduledJob
+
+func (queue scheduleQueue) Len() int {
+	return len(queue)
+}
+
+func (queue scheduleQueue) Less(i, j int) bool {
+	return queue[i].before(queue[j])
+}
+
+func (queue scheduleQueue) Swap(i, j int) {
+	queue[i], queue[j] = queue[j], queue[i]
+	queue[i].index = i
+	queue[j].index = j
+}
+
+func (queue *scheduleQueue) Push(value any) {
+	scheduled := value.(*ScheduledJob)
+	scheduled.index = len(*queue)
+	*queue = append(*queue, scheduled)
+}
+
+func (queue *scheduleQueue) Pop() any {
+	old := *queue
+	last := old[len(old)-1]
+	old[len(old)-1] = nil
+	last.index = -1
+	*queue = old[:len(old)-1]
+	return last
+}
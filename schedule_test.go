@@ -0,0 +1,147 @@
+package twoface
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/smartystreets/goconvey/convey"
+)
+
+func TestSchedule(t *testing.T) {
+	convey.Convey("Scheduled submission", t, func() {
+		ctx := context.Background()
+
+		var mu sync.Mutex
+		var order []int
+		record := func(value int) Job {
+			return JobFunc(func() Result[any, error] {
+				mu.Lock()
+				defer mu.Unlock()
+				order = append(order, value)
+				return Ok[any, error](value)
+			})
+		}
+
+		future := func(ctx context.Context, value int) (asyncJob[int], *Future[int]) {
+			promise, future := NewPromise[int]()
+			return asyncJob[int]{ctx: ctx, promise: promise, fn: func(ctx context.Context) (int, error) {
+				return value, nil
+			}}, future
+		}
+
+		convey.Convey("Should submit a job once its delay has passed", func() {
+			pool := NewPool(ctx, 1)
+			job, f := future(ctx, 42)
+			started := time.Now()
+			pool.SubmitAfter(20*time.Millisecond, job)
+
+			result, err := f.Result()
+			convey.So(result, convey.ShouldEqual, 42)
+			convey.So(err, convey.ShouldBeNil)
+			convey.So(time.Since(started), convey.ShouldBeGreaterThanOrEqualTo, 20*time.Millisecond)
+			pool.Shutdown()
+		})
+
+		convey.Convey("Should submit jobs in the order of their times", func() {
+			pool := NewPool(ctx, 1)
+			now := time.Now()
+			pool.SubmitAt(now.Add(30*time.Millisecond), record(3))
+			pool.SubmitAt(now.Add(10*time.Millisecond), record(1))
+			pool.SubmitAt(now.Add(20*time.Millisecond), record(2))
+			pool.SubmitAt(now.Add(-time.Second), record(0))
+
+			time.Sleep(60 * time.Millisecond)
+			pool.Shutdown()
+
+			mu.Lock()
+			defer mu.Unlock()
+			convey.So(order, convey.ShouldResemble, []int{0, 1, 2, 3})
+		})
+
+		convey.Convey("Should list the scheduled jobs, the earliest first", func() {
+			pool := NewPool(ctx, 1)
+			later := pool.SubmitAfter(time.Hour, record(2))
+			sooner := pool.SubmitAfter(time.Minute, record(1))
+
+			scheduled := pool.Scheduled()
+			convey.So(scheduled, convey.ShouldHaveLength, 2)
+			convey.So(scheduled[0].ID(), convey.ShouldEqual, sooner.ID())
+			convey.So(scheduled[1].ID(), convey.ShouldEqual, later.ID())
+			convey.So(sooner.At().Before(later.At()), convey.ShouldBeTrue)
+			pool.Shutdown()
+		})
+
+		convey.Convey("Should not submit a cancelled job", func() {
+			pool := NewPool(ctx, 1)
+			job, f := future(ctx, 42)
+			scheduled := pool.SubmitAfter(10*time.Millisecond, job)
+
+			convey.So(scheduled.Cancel(), convey.ShouldBeTrue)
+			convey.So(scheduled.Cancel(), convey.ShouldBeFalse)
+			convey.So(pool.Scheduled(), convey.ShouldBeEmpty)
+
+			_, err := f.Result()
+			convey.So(err, convey.ShouldEqual, context.Canceled)
+			pool.Shutdown()
+		})
+
+		convey.Convey("Should not cancel a job that was already submitted", func() {
+			pool := NewPool(ctx, 1)
+			job, f := future(ctx, 42)
+			scheduled := pool.SubmitAfter(time.Millisecond, job)
+			f.Result()
+			convey.So(scheduled.Cancel(), convey.ShouldBeFalse)
+			pool.Shutdown()
+		})
+
+		convey.Convey("Should drop and report the pending jobs on shutdown", func() {
+			var dropped []uint64
+			pool := NewPool(ctx, 1, WithDroppedSchedules(func(scheduled *ScheduledJob) {
+				dropped = append(dropped, scheduled.ID())
+			}))
+			job, f := future(ctx, 42)
+			first := pool.SubmitAfter(time.Hour, job)
+			second := pool.SubmitAfter(time.Minute, record(1))
+			pool.Shutdown()
+
+			_, err := f.Result()
+			convey.So(err, convey.ShouldEqual, context.Canceled)
+			convey.So(dropped, convey.ShouldResemble, []uint64{second.ID(), first.ID()})
+			convey.So(first.Cancel(), convey.ShouldBeFalse)
+
+			late := pool.SubmitAfter(time.Millisecond, record(2))
+			convey.So(dropped, convey.ShouldHaveLength, 3)
+			convey.So(dropped[2], convey.ShouldEqual, late.ID())
+		})
+
+		convey.Convey("Should handle many scheduled jobs with a single timer", func() {
+			pool := NewPool(ctx, 4)
+			var count atomic.Int64
+			now := time.Now()
+			for idx := range 1000 {
+				pool.SubmitAt(now.Add(time.Duration(idx%20)*time.Millisecond), JobFunc(func() Result[any, error] {
+					count.Add(1)
+					return Ok[any, error](nil)
+				}))
+			}
+
+			for count.Load() < 1000 {
+				time.Sleep(time.Millisecond)
+			}
+			convey.So(pool.Scheduled(), convey.ShouldBeEmpty)
+			pool.Shutdown()
+		})
+	})
+}
+
+func BenchmarkSubmitAfter(b *testing.B) {
+	pool := NewPool(context.Background(), 4)
+	defer pool.Shutdown()
+
+	for i := 0; i < b.N; i++ {
+		pool.SubmitAfter(time.Hour, JobFunc(func() Result[any, error] { return Ok[any, error](nil) })).Cancel()
+	}
+}
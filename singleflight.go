@@ -0,0 +1,123 @@
+package twoface
+
+import (
+	"context"
+	"sync"
+)
+
+/*
+Singleflight coalesces concurrent calls for the same key, so only one of them does the
+work, and every caller receives the same Future. Unlike a Memo, nothing is kept once
+the call completes, so the next call for the key does the work again. It is not
+called Group, like the type of golang.org/x/sync/singleflight, because Group already runs
+a collection of tasks in this package.
+
+Example:
+
+	flight := NewSingleflight[string, User](ctx)
+	user, err := flight.Do("alice", func(ctx context.Context) (User, error) {
+	    return fetchUser(ctx, "alice")
+	}).Result()
+*/
+type Singleflight[K comparable, T any] struct {
+	mu       sync.Mutex
+	ctx      context.Context
+	executor Executor
+	calls    map[K]*Future[T]
+}
+
+/*
+NewSingleflight creates a Singleflight whose calls receive a context derived from the
+given one.
+
+Example:
+
+	flight := NewSingleflight[string, User](ctx)
+*/
+func NewSingleflight[K comparable, T any](ctx context.Context) *Singleflight[K, T] {
+	return &Singleflight[K, T]{
+		ctx:      ctx,
+		executor: GoExecutor,
+		calls:    make(map[K]*Future[T]),
+	}
+}
+
+/*
+WithExecutor sets the Executor that the calls run on. When it is a Pool, the calls that
+remain after deduplication still respect the concurrency limit of the pool.
+
+Example:
+
+	flight := NewSingleflight[string, User](ctx).WithExecutor(pool)
+*/
+func (flight *Singleflight[K, T]) WithExecutor(executor Executor) *Singleflight[K, T] {
+	flight.mu.Lock()
+	defer flight.mu.Unlock()
+	flight.executor = executor
+	return flight
+}
+
+/*
+Do runs the function for the key, unless a call for the key is already in flight, in
+which case it returns the Future of that call. Because the Future is shared, cancelling
+it cancels the call for every caller.
+
+Example:
+
+	f := flight.Do("alice", fetchAlice)
+*/
+func (flight *Singleflight[K, T]) Do(key K, fn func(context.Context) (T, error)) *Future[T] {
+	future, _ := flight.DoShared(key, fn)
+	return future
+}
+
+/*
+DoShared is like Do, but also reports whether the caller joined a call that was already
+in flight, rather than starting a new one.
+
+Example:
+
+	f, shared := flight.DoShared("alice", fetchAlice)
+*/
+func (flight *Singleflight[K, T]) DoShared(key K, fn func(context.Context) (T, error)) (*Future[T], bool) {
+	flight.mu.Lock()
+	defer flight.mu.Unlock()
+
+	if future, ok := flight.calls[key]; ok && !future.IsDone() {
+		return future, true
+	}
+
+	future := AsyncOn(flight.ctx, flight.executor, fn)
+	flight.calls[key] = future
+
+	future.onComplete(func() { flight.forget(key, future) })
+
+	return future, false
+}
+
+/*
+Forget stops deduplicating the call that is in flight for the key, so the next call to
+Do starts a new one. Callers that already hold the Future of the old call still receive
+its outcome.
+
+Example:
+
+	flight.Forget("alice")
+*/
+func (flight *Singleflight[K, T]) Forget(key K) {
+	flight.mu.Lock()
+	defer flight.mu.Unlock()
+	delete(flight.calls, key)
+}
+
+/*
+forget removes the call for the key, but only if it is still the given one.
+*/
+func (flight *Singleflight[K, T]) forget(key K, future *Future[T]) {
+	flight.mu.Lock()
+	defer flight.mu.Unlock()
+
+	if flight.calls[key] == future {
+		delete(flight.calls, key)
+	}
+}
@@ -0,0 +1,93 @@
+package twoface
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/smartystreets/goconvey/convey"
+)
+
+func TestSingleflight(t *testing.T) {
+	convey.Convey("Singleflight", t, func() {
+		ctx := context.Background()
+		var calls atomic.Int64
+		release := make(chan struct{})
+
+		fetch := func(ctx context.Context) (int, error) {
+			calls.Add(1)
+			<-release
+			return 42, nil
+		}
+
+		convey.Convey("Should deduplicate calls that are in flight", func() {
+			flight := NewSingleflight[string, int](ctx)
+			first, shared := flight.DoShared("answer", fetch)
+			convey.So(shared, convey.ShouldBeFalse)
+
+			var wg sync.WaitGroup
+			futures := make([]*Future[int], 10)
+			sharing := make([]bool, 10)
+			for i := range futures {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					futures[i], sharing[i] = flight.DoShared("answer", fetch)
+				}()
+			}
+			wg.Wait()
+
+			for i := range futures {
+				convey.So(futures[i], convey.ShouldEqual, first)
+				convey.So(sharing[i], convey.ShouldBeTrue)
+			}
+
+			close(release)
+			result, err := first.Result()
+			convey.So(result, convey.ShouldEqual, 42)
+			convey.So(err, convey.ShouldBeNil)
+			convey.So(calls.Load(), convey.ShouldEqual, 1)
+		})
+
+		convey.Convey("Should call again once the call completed", func() {
+			close(release)
+			flight := NewSingleflight[string, int](ctx)
+			flight.Do("answer", fetch).Result()
+			flight.Do("answer", fetch).Result()
+			convey.So(calls.Load(), convey.ShouldEqual, 2)
+		})
+
+		convey.Convey("Should keep keys apart", func() {
+			close(release)
+			flight := NewSingleflight[string, int](ctx)
+			All(ctx, flight.Do("a", fetch), flight.Do("b", fetch)).Result()
+			convey.So(calls.Load(), convey.ShouldEqual, 2)
+		})
+
+		convey.Convey("Should Forget a call that is in flight", func() {
+			flight := NewSingleflight[string, int](ctx)
+			first := flight.Do("answer", fetch)
+			flight.Forget("answer")
+			second := flight.Do("answer", fetch)
+			convey.So(second == first, convey.ShouldBeFalse)
+
+			close(release)
+			All(ctx, first, second).Result()
+			convey.So(calls.Load(), convey.ShouldEqual, 2)
+		})
+
+		convey.Convey("Should run the calls on a Pool", func() {
+			pool := NewPool(ctx, 1)
+			flight := NewSingleflight[string, int](ctx).WithExecutor(pool)
+			futures := []*Future[int]{flight.Do("a", fetch), flight.Do("a", fetch), flight.Do("b", fetch)}
+			close(release)
+
+			values, err := All(ctx, futures...).Result()
+			convey.So(err, convey.ShouldBeNil)
+			convey.So(values, convey.ShouldResemble, []int{42, 42, 42})
+			convey.So(calls.Load(), convey.ShouldEqual, 2)
+			pool.Shutdown()
+		})
+	})
+}
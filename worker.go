@@ -2,6 +2,7 @@ package twoface
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"time"
 )
@@ -15,6 +16,7 @@ type Worker struct {
 	lastUse      time.Time
 	lastDuration int64
 	drain        bool
+	deadLetters  DeadLetterSink
 }
 
 // NewWorker creates a new worker.
@@ -33,15 +35,16 @@ func NewWorker(ID int, workerPool chan chan Job, ctx context.Context) *Worker {
 func (worker *Worker) Start() *Worker {
 	go func() {
 		for {
-			worker.WorkerPool <- worker.JobChannel
+			select {
+			case worker.WorkerPool <- worker.JobChannel:
+			case <-worker.ctx.Done():
+				return
+			}
 
 			select {
 			case job := <-worker.JobChannel:
 				worker.lastUse = time.Now()
-				result := job.Do()
-				if result.IsErr() {
-					fmt.Printf("Worker %d: Job failed with error: %v\n", worker.ID, result.UnwrapErr())
-				}
+				worker.do(job)
 				worker.lastDuration = time.Since(worker.lastUse).Nanoseconds()
 
 				if worker.drain {
@@ -55,6 +58,33 @@ func (worker *Worker) Start() *Worker {
 	return worker
 }
 
+// do runs the job, and dead-letters it when it failed every attempt of its Retrier.
+func (worker *Worker) do(job Job) {
+	if tracked, ok := job.(trackedJob); ok {
+		// The pool should only see the job as done once it was dead-lettered.
+		defer tracked.wg.Done()
+		job = tracked.job
+	}
+
+	result := job.Do()
+	if result.IsOk() {
+		return
+	}
+
+	err := result.UnwrapErr()
+
+	var exhausted *RetryError
+	if worker.deadLetters != nil && errors.As(err, &exhausted) {
+		_, putErr := worker.deadLetters.Put(newDeadLetter(job, exhausted, worker.ID))
+		if putErr == nil {
+			return
+		}
+		err = errors.Join(err, fmt.Errorf("cannot dead-letter the job: %w", putErr))
+	}
+
+	fmt.Printf("Worker %d: Job failed with error: %v\n", worker.ID, err)
+}
+
 // Drain the worker, which means it will finish its current job first before it will stop.
 func (worker *Worker) Drain() {
 	worker.drain = true
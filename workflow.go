@@ -0,0 +1,649 @@
+package twoface
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+/*
+ErrNondeterministic is the error of a workflow that, when it was resumed, did not take the
+same steps as it did before, so its checkpoints cannot be trusted.
+*/
+var ErrNondeterministic = errors.New("the workflow is not deterministic")
+
+const (
+	checkpointStep   = "step"
+	checkpointTimer  = "timer"
+	checkpointSignal = "signal"
+)
+
+/*
+WorkflowFunc is the code of a workflow. It runs from the start every time the workflow is
+resumed, where the steps, timers and signals that were checkpointed before return their
+recorded outcome straight away, instead of running again. So the function has to take the
+same steps in the same order every time, and do its work inside steps only.
+
+Example:
+
+	func Onboarding(wf *Workflow) Result[any, error] {
+	    var user User
+	    if err := wf.Input(&user); err != nil {
+	        return Err[any](err)
+	    }
+
+	    account := WorkflowStep(wf, "create account", func(ctx context.Context) (string, error) {
+	        return accounts.Create(ctx, user)
+	    })
+	    if account.IsErr() {
+	        return Err[any](account.UnwrapErr())
+	    }
+
+	    if err := wf.Sleep(24 * time.Hour); err != nil {
+	        return Err[any](err)
+	    }
+
+	    return Ok[any, error](account.Unwrap())
+	}
+*/
+type WorkflowFunc func(wf *Workflow) Result[any, error]
+
+/*
+WorkflowOption configures a WorkflowEngine.
+*/
+type WorkflowOption func(*WorkflowEngine)
+
+/*
+WithWorkflowExecutor sets the Executor that the steps of workflows run on. The workflow
+functions themselves run on their own goroutine, as they spend most of their time waiting.
+
+Example:
+
+	engine := NewWorkflowEngine(store, WithWorkflowExecutor(pool))
+*/
+func WithWorkflowExecutor(executor Executor) WorkflowOption {
+	return func(engine *WorkflowEngine) {
+		engine.executor = executor
+	}
+}
+
+/*
+WithWorkflowClock sets the Clock that timers of workflows follow. It defaults to
+SystemClock.
+
+Example:
+
+	engine := NewWorkflowEngine(store, WithWorkflowClock(clock))
+*/
+func WithWorkflowClock(clock Clock) WorkflowOption {
+	return func(engine *WorkflowEngine) {
+		engine.clock = clock
+	}
+}
+
+/*
+WorkflowEngine runs workflows whose progress is checkpointed to a CheckpointStore, so they
+can run for hours or days, and survive restarts. Workflows are registered by name, so
+Resume can find the code of the workflows that were interrupted.
+
+Example:
+
+	engine := NewWorkflowEngine(store, WithWorkflowExecutor(pool)).
+	    Register("onboarding", Onboarding)
+
+	// Pick up the workflows that were running before the restart.
+	engine.Resume(ctx)
+
+	future, err := engine.Start(ctx, "onboarding", "user-42", user)
+*/
+type WorkflowEngine struct {
+	store       CheckpointStore
+	executor    Executor
+	clock       Clock
+	mu          sync.Mutex
+	definitions map[string]WorkflowFunc
+	running     map[string]*Workflow
+}
+
+/*
+NewWorkflowEngine creates a WorkflowEngine that keeps its workflows in the store.
+
+Example:
+
+	engine := NewWorkflowEngine(NewMemoryCheckpointStore())
+*/
+func NewWorkflowEngine(store CheckpointStore, options ...WorkflowOption) *WorkflowEngine {
+	engine := &WorkflowEngine{
+		store:       store,
+		executor:    GoExecutor,
+		clock:       SystemClock,
+		definitions: make(map[string]WorkflowFunc),
+		running:     make(map[string]*Workflow),
+	}
+
+	for _, option := range options {
+		option(engine)
+	}
+
+	return engine
+}
+
+/*
+Register adds the code of a workflow under a name.
+
+Example:
+
+	engine.Register("onboarding", Onboarding)
+*/
+func (engine *WorkflowEngine) Register(name string, fn WorkflowFunc) *WorkflowEngine {
+	engine.mu.Lock()
+	defer engine.mu.Unlock()
+
+	engine.definitions[name] = fn
+	return engine
+}
+
+/*
+Start runs a new workflow with an ID that is unique in the store, and returns a Future for
+its value. The input is stored as JSON. When the context is cancelled, the workflow stops
+at its next step, timer or signal, and can be resumed later.
+
+Example:
+
+	future, err := engine.Start(ctx, "onboarding", "user-42", user)
+*/
+func (engine *WorkflowEngine) Start(ctx context.Context, name string, id string, input any) (*Future[any], error) {
+	data, err := json.Marshal(input)
+	if err != nil {
+		return nil, fmt.Errorf("cannot marshal the input of workflow %q: %w", id, err)
+	}
+
+	engine.mu.Lock()
+	defer engine.mu.Unlock()
+
+	fn, ok := engine.definitions[name]
+	if !ok {
+		return nil, fmt.Errorf("cannot start workflow %q of unregistered type %q", id, name)
+	}
+
+	if _, err := engine.store.Load(id); !errors.Is(err, ErrWorkflowNotFound) {
+		return nil, errors.Join(fmt.Errorf("cannot start workflow %q, because it already exists", id), err)
+	}
+
+	now := engine.clock.Now()
+	state := WorkflowState{ID: id, Name: name, Input: data, Status: WorkflowRunning, Created: now, Updated: now}
+
+	if err := engine.store.Save(state); err != nil {
+		return nil, err
+	}
+
+	return engine.launch(ctx, state, fn), nil
+}
+
+/*
+Resume runs every workflow in the store that has not finished, and is not running yet,
+from its last checkpoint. It returns a Future for each, by ID.
+
+Example:
+
+	futures, err := engine.Resume(ctx)
+*/
+func (engine *WorkflowEngine) Resume(ctx context.Context) (map[string]*Future[any], error) {
+	// A Signal that is stored between listing and launching would be overwritten by the
+	// first checkpoint of the workflow, so the states are listed with the lock held.
+	engine.mu.Lock()
+	defer engine.mu.Unlock()
+
+	states, err := engine.store.List()
+	if err != nil {
+		return nil, err
+	}
+
+	futures := make(map[string]*Future[any])
+	var errs []error
+
+	for _, state := range states {
+		if _, running := engine.running[state.ID]; running || state.Status != WorkflowRunning {
+			continue
+		}
+
+		fn, ok := engine.definitions[state.Name]
+		if !ok {
+			errs = append(errs, fmt.Errorf("cannot resume workflow %q of unregistered type %q", state.ID, state.Name))
+			continue
+		}
+
+		futures[state.ID] = engine.launch(ctx, state, fn)
+	}
+
+	return futures, errors.Join(errs...)
+}
+
+/*
+Signal sends a value to a workflow, which receives it with AwaitSignal. Signals are
+stored, so a workflow that is not waiting for it yet, or not even running, receives it
+later.
+
+Example:
+
+	err := engine.Signal("user-42", "approved", Approval{By: "admin"})
+*/
+func (engine *WorkflowEngine) Signal(id string, name string, value any) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Errorf("cannot marshal signal %q: %w", name, err)
+	}
+
+	signal := WorkflowSignal{Name: name, Value: data, At: engine.clock.Now()}
+
+	engine.mu.Lock()
+	defer engine.mu.Unlock()
+
+	if wf, running := engine.running[id]; running {
+		return wf.deliver(signal)
+	}
+
+	state, err := engine.store.Load(id)
+	if err != nil {
+		return err
+	}
+
+	if state.Status != WorkflowRunning {
+		return fmt.Errorf("cannot signal workflow %q, because it has %s", id, state.Status)
+	}
+
+	state.Signals = append(state.Signals, signal)
+	state.Updated = signal.At
+	return engine.store.Save(state)
+}
+
+/*
+State returns the state of a workflow, as it was last checkpointed.
+
+Example:
+
+	state, err := engine.State("user-42")
+	fmt.Println(state.Status, len(state.History))
+*/
+func (engine *WorkflowEngine) State(id string) (WorkflowState, error) {
+	return engine.store.Load(id)
+}
+
+/*
+launch runs the workflow on its own goroutine, and returns a Future for its value. It has
+to be called with the lock held.
+*/
+func (engine *WorkflowEngine) launch(ctx context.Context, state WorkflowState, fn WorkflowFunc) *Future[any] {
+	promise, future := NewPromiseWithContext[any](ctx)
+	wf := &Workflow{engine: engine, ctx: promise.Context(), state: state, signal: make(chan struct{})}
+	engine.running[state.ID] = wf
+
+	// Unlike Async, this always calls finish, even when the context is already done.
+	go func() {
+		value, err := call(wf.ctx, func(ctx context.Context) (any, error) {
+			return unpack(fn(wf))
+		})
+		promise.Set(engine.finish(wf, value, err))
+	}()
+
+	return future
+}
+
+/*
+finish records the outcome of a workflow, unless it was interrupted, in which case it is
+left to be resumed.
+*/
+func (engine *WorkflowEngine) finish(wf *Workflow, value any, err error) (any, error) {
+	engine.mu.Lock()
+	defer engine.mu.Unlock()
+	delete(engine.running, wf.state.ID)
+
+	wf.mu.Lock()
+	defer wf.mu.Unlock()
+
+	if err != nil && wf.ctx.Err() != nil {
+		return nil, err
+	}
+
+	if wf.failed != nil {
+		value, err = nil, wf.failed
+	} else if err == nil && wf.seq < len(wf.state.History) {
+		err = fmt.Errorf("%w: it finished after %d of %d checkpoints", ErrNondeterministic, wf.seq, len(wf.state.History))
+		value = nil
+	}
+
+	wf.state.Status = WorkflowCompleted
+	wf.state.Output, wf.state.Err = nil, nil
+
+	if err == nil {
+		wf.state.Output, err = json.Marshal(value)
+	}
+
+	if err != nil {
+		wf.state.Status = WorkflowFailed
+		wf.state.Err, _ = CurrentErrorCodec().EncodeError(err)
+	}
+
+	wf.state.Updated = engine.clock.Now()
+	return value, errors.Join(err, engine.store.Save(wf.state))
+}
+
+/*
+Workflow is a single run of a workflow, which its WorkflowFunc uses to take checkpointed
+steps, wait on timers and receive signals.
+*/
+type Workflow struct {
+	engine *WorkflowEngine
+	ctx    context.Context
+	mu     sync.Mutex
+	state  WorkflowState
+	seq    int
+	failed error
+	signal chan struct{}
+}
+
+/*
+ID returns the ID of the workflow.
+*/
+func (wf *Workflow) ID() string {
+	return wf.state.ID
+}
+
+/*
+Context returns the context of the workflow, which is cancelled when it is interrupted.
+*/
+func (wf *Workflow) Context() context.Context {
+	return wf.ctx
+}
+
+/*
+Input decodes the input that the workflow was started with.
+
+Example:
+
+	var user User
+	err := wf.Input(&user)
+*/
+func (wf *Workflow) Input(target any) error {
+	return json.Unmarshal(wf.state.Input, target)
+}
+
+/*
+Replaying reports whether the workflow is still taking steps that were checkpointed
+before, for instance to skip logging them twice.
+*/
+func (wf *Workflow) Replaying() bool {
+	wf.mu.Lock()
+	defer wf.mu.Unlock()
+	return wf.seq < len(wf.state.History)
+}
+
+/*
+WorkflowStep runs the function as a step of the workflow, on the Executor of the engine,
+and checkpoints its Result, including an error. When the workflow is resumed, the step
+returns the checkpointed Result, without running the function again. The value is stored
+as JSON, and the error with the current ErrorCodec, so they have to survive a round trip
+through them.
+
+Example:
+
+	total := WorkflowStep(wf, "charge", func(ctx context.Context) (int, error) {
+	    return payments.Charge(ctx, order)
+	})
+*/
+func WorkflowStep[T any](wf *Workflow, name string, fn func(context.Context) (T, error)) Result[T, error] {
+	checkpoint, replayed, err := wf.next(checkpointStep, name)
+	if err != nil {
+		return Err[T](err)
+	}
+
+	if replayed {
+		return decodeCheckpoint[T](checkpoint)
+	}
+
+	value, err := AsyncOn(wf.ctx, wf.engine.executor, fn).Result()
+
+	// A step that was interrupted is not checkpointed, so it runs again on resume.
+	if ctxErr := wf.ctx.Err(); ctxErr != nil {
+		return Err[T](ctxErr)
+	}
+
+	checkpoint, encodeErr := newCheckpoint(checkpointStep, name, value, err)
+	if encodeErr != nil {
+		return Err[T](encodeErr)
+	}
+
+	if recordErr := wf.record(checkpoint); recordErr != nil {
+		return Err[T](recordErr)
+	}
+
+	if err != nil {
+		return Err[T](err)
+	}
+	return Ok[T, error](value)
+}
+
+/*
+Do runs a Job as a step of the workflow, like WorkflowStep.
+
+Example:
+
+	result := wf.Do("send email", &EmailJob{To: "someone@example.com"})
+*/
+func (wf *Workflow) Do(name string, job Job) Result[any, error] {
+	return WorkflowStep(wf, name, func(ctx context.Context) (any, error) {
+		return unpack(job.Do())
+	})
+}
+
+/*
+Sleep waits for the duration, as a timer that is checkpointed when it starts. A workflow
+that is resumed only waits for what is left of it, or not at all when it already expired.
+
+Example:
+
+	if err := wf.Sleep(24 * time.Hour); err != nil {
+	    return Err[any](err)
+	}
+*/
+func (wf *Workflow) Sleep(d time.Duration) error {
+	checkpoint, replayed, err := wf.next(checkpointTimer, "sleep")
+	if err != nil {
+		return err
+	}
+
+	if !replayed {
+		checkpoint = Checkpoint{Kind: checkpointTimer, Name: "sleep", At: wf.engine.clock.Now().Add(d)}
+
+		if err := wf.record(checkpoint); err != nil {
+			return err
+		}
+	}
+
+	remaining := checkpoint.At.Sub(wf.engine.clock.Now())
+	if remaining <= 0 {
+		return nil
+	}
+
+	select {
+	case <-wf.engine.clock.After(remaining):
+		return nil
+	case <-wf.ctx.Done():
+		return wf.ctx.Err()
+	}
+}
+
+/*
+AwaitSignal waits until the workflow receives a signal with the name, and returns its
+value. Signals with the same name are received in the order they were sent.
+
+Example:
+
+	approval := AwaitSignal[Approval](wf, "approved")
+*/
+func AwaitSignal[T any](wf *Workflow, name string) Result[T, error] {
+	checkpoint, replayed, err := wf.next(checkpointSignal, name)
+	if err != nil {
+		return Err[T](err)
+	}
+
+	if !replayed {
+		if checkpoint, err = wf.receive(name); err != nil {
+			return Err[T](err)
+		}
+	}
+
+	return decodeCheckpoint[T](checkpoint)
+}
+
+/*
+next returns the checkpoint that the workflow recorded before for its next step, timer or
+signal, if there is one, and checks that it is of the same kind and name.
+*/
+func (wf *Workflow) next(kind string, name string) (Checkpoint, bool, error) {
+	wf.mu.Lock()
+	defer wf.mu.Unlock()
+
+	if wf.failed != nil {
+		return Checkpoint{}, false, wf.failed
+	}
+
+	if err := wf.ctx.Err(); err != nil {
+		return Checkpoint{}, false, err
+	}
+
+	if wf.seq >= len(wf.state.History) {
+		return Checkpoint{}, false, nil
+	}
+
+	checkpoint := wf.state.History[wf.seq]
+	if checkpoint.Kind != kind || checkpoint.Name != name {
+		wf.failed = fmt.Errorf(
+			"%w: checkpoint %d is %s %q, but the workflow took %s %q",
+			ErrNondeterministic, wf.seq, checkpoint.Kind, checkpoint.Name, kind, name,
+		)
+		return Checkpoint{}, false, wf.failed
+	}
+
+	wf.seq++
+	return checkpoint, true, nil
+}
+
+/*
+record adds a checkpoint to the history of the workflow, and saves it.
+*/
+func (wf *Workflow) record(checkpoint Checkpoint) error {
+	wf.mu.Lock()
+	defer wf.mu.Unlock()
+
+	return wf.recordLocked(checkpoint)
+}
+
+func (wf *Workflow) recordLocked(checkpoint Checkpoint) error {
+	wf.state.History = append(wf.state.History, checkpoint)
+	wf.state.Updated = wf.engine.clock.Now()
+	wf.seq++
+
+	// A workflow that cannot checkpoint cannot go on, or it would redo work on resume.
+	if err := wf.engine.store.Save(wf.state); err != nil {
+		wf.failed = fmt.Errorf("cannot checkpoint workflow %q: %w", wf.state.ID, err)
+		return wf.failed
+	}
+
+	return nil
+}
+
+/*
+receive waits for a signal with the name, and consumes it.
+*/
+func (wf *Workflow) receive(name string) (Checkpoint, error) {
+	for {
+		wf.mu.Lock()
+
+		for idx, signal := range wf.state.Signals {
+			if signal.Name != name {
+				continue
+			}
+
+			wf.state.Signals = append(wf.state.Signals[:idx:idx], wf.state.Signals[idx+1:]...)
+			checkpoint := Checkpoint{Kind: checkpointSignal, Name: name, Value: signal.Value, At: signal.At}
+			err := wf.recordLocked(checkpoint)
+			wf.mu.Unlock()
+			return checkpoint, err
+		}
+
+		waiting := wf.signal
+		wf.mu.Unlock()
+
+		select {
+		case <-waiting:
+		case <-wf.ctx.Done():
+			return Checkpoint{}, wf.ctx.Err()
+		}
+	}
+}
+
+/*
+deliver stores a signal for the running workflow, and wakes it up.
+*/
+func (wf *Workflow) deliver(signal WorkflowSignal) error {
+	wf.mu.Lock()
+	defer wf.mu.Unlock()
+
+	wf.state.Signals = append(wf.state.Signals, signal)
+	wf.state.Updated = signal.At
+
+	close(wf.signal)
+	wf.signal = make(chan struct{})
+
+	return wf.engine.store.Save(wf.state)
+}
+
+/*
+newCheckpoint records the outcome of a step.
+*/
+func newCheckpoint[T any](kind string, name string, value T, err error) (Checkpoint, error) {
+	checkpoint := Checkpoint{Kind: kind, Name: name}
+
+	if err != nil {
+		encoded, encodeErr := CurrentErrorCodec().EncodeError(err)
+		if encodeErr != nil {
+			return checkpoint, fmt.Errorf("cannot checkpoint the error of step %q: %w", name, encodeErr)
+		}
+		checkpoint.Err = encoded
+		return checkpoint, nil
+	}
+
+	encoded, encodeErr := json.Marshal(value)
+	if encodeErr != nil {
+		return checkpoint, fmt.Errorf("cannot checkpoint the value of step %q: %w", name, encodeErr)
+	}
+
+	checkpoint.Value = encoded
+	return checkpoint, nil
+}
+
+/*
+decodeCheckpoint turns a checkpoint back into the Result it recorded.
+*/
+func decodeCheckpoint[T any](checkpoint Checkpoint) Result[T, error] {
+	if len(checkpoint.Err) > 0 {
+		err, decodeErr := CurrentErrorCodec().DecodeError(checkpoint.Err)
+		if decodeErr != nil {
+			return Err[T](decodeErr)
+		}
+		return Err[T](err)
+	}
+
+	var value T
+	if len(checkpoint.Value) > 0 {
+		if err := json.Unmarshal(checkpoint.Value, &value); err != nil {
+			return Err[T](fmt.Errorf("cannot decode checkpoint %q: %w", checkpoint.Name, err))
+		}
+	}
+
+	return Ok[T, error](value)
+}
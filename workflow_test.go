@@ -0,0 +1,292 @@
+package twoface
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/smartystreets/goconvey/convey"
+)
+
+// checkpointed waits until the workflow has recorded at least the number of checkpoints.
+func checkpointed(engine *WorkflowEngine, id string, count int) bool {
+	deadline := time.Now().Add(5 * time.Second)
+
+	for time.Now().Before(deadline) {
+		if state, err := engine.State(id); err == nil && len(state.History) >= count {
+			return true
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	return false
+}
+
+// listingStore calls listed every time its states are listed.
+type listingStore struct {
+	CheckpointStore
+	listed func()
+}
+
+func (store listingStore) List() ([]WorkflowState, error) {
+	states, err := store.CheckpointStore.List()
+	store.listed()
+	return states, err
+}
+
+func TestWorkflow(t *testing.T) {
+	convey.Convey("Workflow", t, func() {
+		ctx := context.Background()
+		pool := NewPool(ctx, 2)
+		clock := &testClock{now: time.Now()}
+
+		var charges, emails atomic.Int32
+
+		// order charges a card, waits for a day, and sends an email once it is approved.
+		order := func(wf *Workflow) Result[any, error] {
+			var amount int
+			if err := wf.Input(&amount); err != nil {
+				return Err[any](err)
+			}
+
+			charged := WorkflowStep(wf, "charge", func(ctx context.Context) (int, error) {
+				charges.Add(1)
+				return amount * 2, nil
+			})
+			if charged.IsErr() {
+				return Err[any](charged.UnwrapErr())
+			}
+
+			if err := wf.Sleep(24 * time.Hour); err != nil {
+				return Err[any](err)
+			}
+
+			approver := AwaitSignal[string](wf, "approved")
+			if approver.IsErr() {
+				return Err[any](approver.UnwrapErr())
+			}
+
+			sent := WorkflowStep(wf, "email", func(ctx context.Context) (string, error) {
+				emails.Add(1)
+				return "sent to " + approver.Unwrap(), nil
+			})
+			if sent.IsErr() {
+				return Err[any](sent.UnwrapErr())
+			}
+
+			return Ok[any, error](charged.Unwrap())
+		}
+
+		stores := map[string]func() CheckpointStore{
+			"memory": func() CheckpointStore { return NewMemoryCheckpointStore() },
+			"file": func() CheckpointStore {
+				store, err := OpenFileCheckpointStore(t.TempDir())
+				convey.So(err, convey.ShouldBeNil)
+				return store
+			},
+		}
+
+		for kind, newStore := range stores {
+			convey.Convey("Should run to completion with a "+kind+" store", func() {
+				engine := NewWorkflowEngine(newStore(), WithWorkflowExecutor(pool), WithWorkflowClock(clock)).
+					Register("order", order)
+
+				future, err := engine.Start(ctx, "order", "order/1", 21)
+				convey.So(err, convey.ShouldBeNil)
+
+				convey.So(engine.Signal("order/1", "approved", "alice"), convey.ShouldBeNil)
+				convey.So(checkpointed(engine, "order/1", 2), convey.ShouldBeTrue)
+				clock.Advance(24 * time.Hour)
+
+				value, err := future.Result()
+				convey.So(err, convey.ShouldBeNil)
+				convey.So(value, convey.ShouldEqual, 42)
+
+				state, err := engine.State("order/1")
+				convey.So(err, convey.ShouldBeNil)
+				convey.So(state.Status, convey.ShouldEqual, WorkflowCompleted)
+				convey.So(state.Signals, convey.ShouldBeEmpty)
+				convey.So(len(state.History), convey.ShouldEqual, 4)
+				convey.So(string(state.History[3].Value), convey.ShouldEqual, `"sent to alice"`)
+
+				_, err = engine.Start(ctx, "order", "order/1", 21)
+				convey.So(err, convey.ShouldNotBeNil)
+				convey.So(engine.Signal("order/1", "approved", "bob"), convey.ShouldNotBeNil)
+			})
+
+			convey.Convey("Should resume from its last checkpoint with a "+kind+" store", func() {
+				store := newStore()
+				engine := NewWorkflowEngine(store, WithWorkflowExecutor(pool), WithWorkflowClock(clock)).
+					Register("order", order)
+
+				interrupted, cancel := context.WithCancel(ctx)
+				future, err := engine.Start(interrupted, "order", "order/2", 5)
+				convey.So(err, convey.ShouldBeNil)
+
+				// Interrupt it while it sleeps, after the charge was checkpointed.
+				convey.So(checkpointed(engine, "order/2", 2), convey.ShouldBeTrue)
+				cancel()
+
+				_, err = future.Result()
+				convey.So(errors.Is(err, context.Canceled), convey.ShouldBeTrue)
+
+				state, err := engine.State("order/2")
+				convey.So(err, convey.ShouldBeNil)
+				convey.So(state.Status, convey.ShouldEqual, WorkflowRunning)
+
+				// A signal for a workflow that is not running is kept for it.
+				convey.So(engine.Signal("order/2", "approved", "carol"), convey.ShouldBeNil)
+
+				restarted := NewWorkflowEngine(store, WithWorkflowExecutor(pool), WithWorkflowClock(clock)).
+					Register("order", order)
+
+				clock.Advance(24 * time.Hour)
+				futures, err := restarted.Resume(ctx)
+				convey.So(err, convey.ShouldBeNil)
+				convey.So(futures, convey.ShouldContainKey, "order/2")
+
+				value, err := futures["order/2"].Result()
+				convey.So(err, convey.ShouldBeNil)
+				convey.So(value, convey.ShouldEqual, 10)
+				convey.So(charges.Load(), convey.ShouldEqual, 1)
+				convey.So(emails.Load(), convey.ShouldEqual, 1)
+
+				futures, err = restarted.Resume(ctx)
+				convey.So(err, convey.ShouldBeNil)
+				convey.So(futures, convey.ShouldBeEmpty)
+			})
+		}
+
+		convey.Convey("Should replay the errors of steps", func() {
+			var attempts atomic.Int32
+			engine := NewWorkflowEngine(NewMemoryCheckpointStore(), WithWorkflowClock(clock)).
+				Register("flaky", func(wf *Workflow) Result[any, error] {
+					failed := WorkflowStep(wf, "fail", func(ctx context.Context) (int, error) {
+						attempts.Add(1)
+						return 0, errDummy
+					})
+					if failed.UnwrapErr().Error() != errDummy.Error() {
+						return Err[any](errors.New("lost the error of the step"))
+					}
+
+					return Ok[any, error](AwaitSignal[int](wf, "go").Unwrap())
+				})
+
+			interrupted, cancel := context.WithCancel(ctx)
+			future, err := engine.Start(interrupted, "flaky", "flaky", nil)
+			convey.So(err, convey.ShouldBeNil)
+			convey.So(checkpointed(engine, "flaky", 1), convey.ShouldBeTrue)
+			cancel()
+			future.Result()
+
+			futures, err := engine.Resume(ctx)
+			convey.So(err, convey.ShouldBeNil)
+			convey.So(engine.Signal("flaky", "go", 7), convey.ShouldBeNil)
+
+			value, err := futures["flaky"].Result()
+			convey.So(err, convey.ShouldBeNil)
+			convey.So(value, convey.ShouldEqual, 7)
+			convey.So(attempts.Load(), convey.ShouldEqual, 1)
+		})
+
+		convey.Convey("Should fail a workflow that does not replay the same steps", func() {
+			store := NewMemoryCheckpointStore()
+			now := clock.Now()
+			store.Save(WorkflowState{
+				ID: "changed", Name: "order", Input: []byte("1"), Status: WorkflowRunning,
+				History: []Checkpoint{{Kind: "step", Name: "refund", Value: []byte("2"), At: now}},
+				Created: now, Updated: now,
+			})
+
+			engine := NewWorkflowEngine(store, WithWorkflowClock(clock)).Register("order", order)
+			futures, err := engine.Resume(ctx)
+			convey.So(err, convey.ShouldBeNil)
+
+			_, err = futures["changed"].Result()
+			convey.So(errors.Is(err, ErrNondeterministic), convey.ShouldBeTrue)
+			convey.So(charges.Load(), convey.ShouldEqual, 0)
+
+			state, _ := engine.State("changed")
+			convey.So(state.Status, convey.ShouldEqual, WorkflowFailed)
+		})
+
+		convey.Convey("Should not lose a signal that is sent while it resumes", func() {
+			var engine *WorkflowEngine
+			now := clock.Now()
+
+			// The signal is sent right after the states are listed, and is given a moment to
+			// be stored before the workflows are launched.
+			store := listingStore{CheckpointStore: NewMemoryCheckpointStore(), listed: func() {
+				signalled := make(chan struct{})
+				go func() {
+					engine.Signal("waiting", "go", "now")
+					close(signalled)
+				}()
+
+				select {
+				case <-signalled:
+				case <-time.After(20 * time.Millisecond):
+				}
+			}}
+			store.Save(WorkflowState{ID: "waiting", Name: "waiter", Input: []byte("null"), Status: WorkflowRunning, Created: now, Updated: now})
+
+			engine = NewWorkflowEngine(store, WithWorkflowClock(clock)).
+				Register("waiter", func(wf *Workflow) Result[any, error] {
+					WorkflowStep(wf, "ready", func(ctx context.Context) (bool, error) { return true, nil })
+					signal := AwaitSignal[string](wf, "go")
+					if signal.IsErr() {
+						return Err[any](signal.UnwrapErr())
+					}
+					return Ok[any, error](signal.Unwrap())
+				})
+
+			futures, err := engine.Resume(ctx)
+			convey.So(err, convey.ShouldBeNil)
+
+			value, err := futures["waiting"].ResultTimeout(time.Second)
+			convey.So(err, convey.ShouldBeNil)
+			convey.So(value, convey.ShouldEqual, "now")
+		})
+
+		convey.Convey("Should fail a workflow that panics", func() {
+			engine := NewWorkflowEngine(NewMemoryCheckpointStore()).
+				Register("panics", func(wf *Workflow) Result[any, error] {
+					panic("boom")
+				})
+
+			future, err := engine.Start(ctx, "panics", "panics", nil)
+			convey.So(err, convey.ShouldBeNil)
+
+			_, err = future.Result()
+			var panicErr *PanicError
+			convey.So(errors.As(err, &panicErr), convey.ShouldBeTrue)
+
+			state, _ := engine.State("panics")
+			convey.So(state.Status, convey.ShouldEqual, WorkflowFailed)
+		})
+
+		convey.Reset(func() {
+			pool.Shutdown()
+		})
+	})
+}
+
+func BenchmarkWorkflow(b *testing.B) {
+	ctx := context.Background()
+	engine := NewWorkflowEngine(NewMemoryCheckpointStore()).
+		Register("steps", func(wf *Workflow) Result[any, error] {
+			for _, name := range []string{"a", "b", "c"} {
+				WorkflowStep(wf, name, func(ctx context.Context) (string, error) {
+					return name, nil
+				})
+			}
+			return Ok[any, error]("done")
+		})
+
+	for i := 0; i < b.N; i++ {
+		future, _ := engine.Start(ctx, "steps", time.Now().String(), i)
+		future.Result()
+	}
+}
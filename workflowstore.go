@@ -0,0 +1,316 @@
+package twoface
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+/*
+ErrWorkflowNotFound is returned by a CheckpointStore that holds no workflow with an ID.
+*/
+var ErrWorkflowNotFound = errors.New("workflow not found")
+
+/*
+WorkflowStatus is the state a workflow is in.
+*/
+type WorkflowStatus string
+
+const (
+	// WorkflowRunning is a workflow that has not finished yet, including one that was
+	// interrupted, and can be resumed.
+	WorkflowRunning WorkflowStatus = "running"
+	// WorkflowCompleted is a workflow that finished with a value.
+	WorkflowCompleted WorkflowStatus = "completed"
+	// WorkflowFailed is a workflow that finished with an error.
+	WorkflowFailed WorkflowStatus = "failed"
+)
+
+/*
+WorkflowState is everything a CheckpointStore keeps of a workflow: its input, the
+checkpoint of every step it completed, the signals it did not consume yet, and its outcome
+once it has finished.
+*/
+type WorkflowState struct {
+	ID      string           `json:"id"`
+	Name    string           `json:"name"`
+	Input   json.RawMessage  `json:"input,omitempty"`
+	Status  WorkflowStatus   `json:"status"`
+	History []Checkpoint     `json:"history,omitempty"`
+	Signals []WorkflowSignal `json:"signals,omitempty"`
+	Output  json.RawMessage  `json:"output,omitempty"`
+	Err     json.RawMessage  `json:"error,omitempty"`
+	Created time.Time        `json:"created"`
+	Updated time.Time        `json:"updated"`
+}
+
+/*
+Checkpoint is the recorded outcome of a step, timer or signal of a workflow. The value and
+error are JSON, with the error written by the current ErrorCodec.
+*/
+type Checkpoint struct {
+	Kind  string          `json:"kind"`
+	Name  string          `json:"name"`
+	Value json.RawMessage `json:"value,omitempty"`
+	Err   json.RawMessage `json:"error,omitempty"`
+	At    time.Time       `json:"at"`
+}
+
+/*
+WorkflowSignal is a value that was sent to a workflow from outside.
+*/
+type WorkflowSignal struct {
+	Name  string          `json:"name"`
+	Value json.RawMessage `json:"value,omitempty"`
+	At    time.Time       `json:"at"`
+}
+
+/*
+clone copies the state, so the copy does not share its history and signals.
+*/
+func (state WorkflowState) clone() WorkflowState {
+	state.History = append([]Checkpoint(nil), state.History...)
+	state.Signals = append([]WorkflowSignal(nil), state.Signals...)
+	return state
+}
+
+/*
+CheckpointStore keeps the state of workflows, so they can be resumed after a restart. A
+state is saved as a whole every time a workflow reaches a checkpoint.
+
+Example:
+
+	store, err := OpenFileCheckpointStore("workflows")
+	engine := NewWorkflowEngine(store)
+*/
+type CheckpointStore interface {
+	// Save replaces the state of the workflow with the ID of the state.
+	Save(state WorkflowState) error
+	// Load returns the state of a workflow, or ErrWorkflowNotFound.
+	Load(id string) (WorkflowState, error)
+	// List returns the states of all workflows.
+	List() ([]WorkflowState, error)
+	// Delete forgets a workflow.
+	Delete(id string) error
+}
+
+/*
+MemoryCheckpointStore is a CheckpointStore that keeps workflows in memory, so they only
+survive a restart of the WorkflowEngine, and not of the process.
+
+Example:
+
+	engine := NewWorkflowEngine(NewMemoryCheckpointStore())
+*/
+type MemoryCheckpointStore struct {
+	mu     sync.Mutex
+	states map[string]WorkflowState
+}
+
+/*
+NewMemoryCheckpointStore creates an empty MemoryCheckpointStore.
+
+Example:
+
+	store := NewMemoryCheckpointStore()
+*/
+func NewMemoryCheckpointStore() *MemoryCheckpointStore {
+	return &MemoryCheckpointStore{states: make(map[string]WorkflowState)}
+}
+
+/*
+Save keeps a copy of the state, replacing the one of the workflow with the same ID.
+*/
+func (store *MemoryCheckpointStore) Save(state WorkflowState) error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	store.states[state.ID] = state.clone()
+	return nil
+}
+
+/*
+Load returns a copy of the state of the workflow, or ErrWorkflowNotFound.
+*/
+func (store *MemoryCheckpointStore) Load(id string) (WorkflowState, error) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	state, ok := store.states[id]
+	if !ok {
+		return WorkflowState{}, fmt.Errorf("%w: %q", ErrWorkflowNotFound, id)
+	}
+
+	return state.clone(), nil
+}
+
+/*
+List returns a copy of the state of every workflow, the oldest first.
+*/
+func (store *MemoryCheckpointStore) List() ([]WorkflowState, error) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	states := make([]WorkflowState, 0, len(store.states))
+	for _, state := range store.states {
+		states = append(states, state.clone())
+	}
+
+	sort.Slice(states, func(i, j int) bool { return states[i].Created.Before(states[j].Created) })
+	return states, nil
+}
+
+/*
+Delete forgets the workflow. Deleting one that does not exist does nothing.
+*/
+func (store *MemoryCheckpointStore) Delete(id string) error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	delete(store.states, id)
+	return nil
+}
+
+/*
+FileCheckpointStore is a CheckpointStore that keeps every workflow in a JSON file of its
+own. A state is written to a temporary file first, and then renamed, so a crash never
+leaves a checkpoint half written.
+
+Example:
+
+	store, err := OpenFileCheckpointStore("workflows")
+*/
+type FileCheckpointStore struct {
+	dir string
+	mu  sync.Mutex
+}
+
+const workflowExtension = ".json"
+
+/*
+OpenFileCheckpointStore opens the store in the directory, creating it when needed.
+
+Example:
+
+	store, err := OpenFileCheckpointStore("workflows")
+*/
+func OpenFileCheckpointStore(dir string) (*FileCheckpointStore, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+
+	return &FileCheckpointStore{dir: dir}, nil
+}
+
+/*
+Save writes the state to the file of the workflow, and syncs it before it renames the
+temporary file, so the checkpoint survives a crash once Save returns.
+*/
+func (store *FileCheckpointStore) Save(state WorkflowState) error {
+	data, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	path := store.path(state.ID)
+	temp := path + ".tmp"
+
+	file, err := os.Create(temp)
+	if err != nil {
+		return err
+	}
+
+	_, err = file.Write(data)
+	if err = errors.Join(err, file.Sync(), file.Close()); err != nil {
+		os.Remove(temp)
+		return err
+	}
+
+	if err := os.Rename(temp, path); err != nil {
+		os.Remove(temp)
+		return err
+	}
+
+	return nil
+}
+
+/*
+Load reads the state of the workflow from its file, or returns ErrWorkflowNotFound.
+*/
+func (store *FileCheckpointStore) Load(id string) (WorkflowState, error) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	state, err := store.read(store.path(id))
+	if errors.Is(err, os.ErrNotExist) {
+		return WorkflowState{}, fmt.Errorf("%w: %q", ErrWorkflowNotFound, id)
+	}
+
+	return state, err
+}
+
+/*
+List reads the state of every workflow in the directory, the oldest first.
+*/
+func (store *FileCheckpointStore) List() ([]WorkflowState, error) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	paths, err := filepath.Glob(filepath.Join(store.dir, "*"+workflowExtension))
+	if err != nil {
+		return nil, err
+	}
+
+	states := make([]WorkflowState, 0, len(paths))
+	for _, path := range paths {
+		state, err := store.read(path)
+		if err != nil {
+			return nil, err
+		}
+		states = append(states, state)
+	}
+
+	sort.Slice(states, func(i, j int) bool { return states[i].Created.Before(states[j].Created) })
+	return states, nil
+}
+
+/*
+Delete removes the file of the workflow. Deleting one that does not exist does nothing.
+*/
+func (store *FileCheckpointStore) Delete(id string) error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	if err := os.Remove(store.path(id)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+
+	return nil
+}
+
+func (store *FileCheckpointStore) read(path string) (WorkflowState, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return WorkflowState{}, err
+	}
+
+	var state WorkflowState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return WorkflowState{}, fmt.Errorf("cannot read workflow %s: %w", path, err)
+	}
+
+	return state, nil
+}
+
+func (store *FileCheckpointStore) path(id string) string {
+	return filepath.Join(store.dir, url.PathEscape(id)+workflowExtension)
+}